@@ -0,0 +1,188 @@
+package diameter
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"time"
+)
+
+// AdmitCER is admission policy function that is called with received CER
+// before HandleCER. Returned error rejects the peer, and Result-Code of CEA
+// is Code of AdmissionDenied or DIAMETER_UNKNOWN_PEER for other error.
+// It is called in state machine lock, so it must not wait other Conn.
+var AdmitCER func(r CER, c *Conn) error
+
+// Policy is admission policy of peers.
+// Empty list and zero value is not checked.
+type Policy struct {
+	// AllowHost and DenyHost are Origin-Host patterns of path.Match,
+	// like "*.example.com". Comparison is case insensitive.
+	AllowHost, DenyHost []string
+	// AllowRealm and DenyRealm are Origin-Realm
+	AllowRealm, DenyRealm []Identity
+	// AllowNet and DenyNet are source address of transport connection
+	AllowNet, DenyNet []*net.IPNet
+
+	// MaxConn is limit of concurrent Conns with same peer
+	MaxConn int
+	// RequiredApps are application IDs that peer must support
+	RequiredApps []uint32
+}
+
+// ParseCIDRs returns IPNet list of CIDR strings
+func ParseCIDRs(s ...string) ([]*net.IPNet, error) {
+	r := make([]*net.IPNet, 0, len(s))
+	for _, v := range s {
+		_, n, e := net.ParseCIDR(v)
+		if e != nil {
+			return nil, e
+		}
+		r = append(r, n)
+	}
+	return r, nil
+}
+
+// Admit checks CER by the policy, it can be set to AdmitCER
+func (p Policy) Admit(r CER, c *Conn) error {
+	host := strings.ToLower(string(r.OriginHost))
+	for _, v := range p.DenyHost {
+		if ok, _ := path.Match(strings.ToLower(v), host); ok {
+			return AdmissionDenied{DiameterUnknownPeer, "host is denied"}
+		}
+	}
+	for _, v := range p.DenyRealm {
+		if CompareIdentity(v, r.OriginRealm) == 0 {
+			return AdmissionDenied{DiameterUnknownPeer, "realm is denied"}
+		}
+	}
+	ips := addrIPs(c.con.RemoteAddr())
+	if containIP(p.DenyNet, ips) {
+		return AdmissionDenied{DiameterUnknownPeer, "address is denied"}
+	}
+
+	if len(p.AllowHost) != 0 {
+		ok := false
+		for _, v := range p.AllowHost {
+			if ok, _ = path.Match(strings.ToLower(v), host); ok {
+				break
+			}
+		}
+		if !ok {
+			return AdmissionDenied{DiameterUnknownPeer, "host is not allowed"}
+		}
+	}
+	if len(p.AllowRealm) != 0 {
+		ok := false
+		for _, v := range p.AllowRealm {
+			if ok = CompareIdentity(v, r.OriginRealm) == 0; ok {
+				break
+			}
+		}
+		if !ok {
+			return AdmissionDenied{DiameterUnknownPeer, "realm is not allowed"}
+		}
+	}
+	if len(p.AllowNet) != 0 && !containIP(p.AllowNet, ips) {
+		return AdmissionDenied{DiameterUnknownPeer, "address is not allowed"}
+	}
+
+	if p.MaxConn > 0 && peerConns(r.OriginHost, c) >= p.MaxConn {
+		return AdmissionDenied{DiameterUnableToComply,
+			fmt.Sprintf("number of connection exceeds %d", p.MaxConn)}
+	}
+
+	for _, a := range p.RequiredApps {
+		ok := false
+		for _, ids := range r.ApplicationID {
+			for _, id := range ids {
+				if id == a || id == 0xffffffff {
+					ok = true
+				}
+			}
+		}
+		if !ok {
+			return AdmissionDenied{DiameterNoCommonApplication,
+				fmt.Sprintf("application %d is not supported", a)}
+		}
+	}
+	return nil
+}
+
+func containIP(l []*net.IPNet, ips []net.IP) bool {
+	for _, n := range l {
+		for _, ip := range ips {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// peerConns returns number of Conns with host except c, fsmLock must be held
+func peerConns(host Identity, c *Conn) int {
+	n := 0
+	for k, l := range fsmPeers {
+		if CompareIdentity(k, host) != 0 {
+			continue
+		}
+		for _, v := range l {
+			if v != c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// admit evaluates AdmitCER and publish the result
+func admit(r CER, c *Conn) (uint32, error) {
+	if AdmitCER == nil {
+		return DiameterSuccess, nil
+	}
+	e := AdmitCER(r, c)
+	publish(AdmissionEvent{
+		Peer:    r.OriginHost,
+		Realm:   r.OriginRealm,
+		Addr:    c.con.RemoteAddr(),
+		Time:    c.clock.Now(),
+		Allowed: e == nil,
+		Err:     e})
+
+	if e == nil {
+		return DiameterSuccess, nil
+	}
+	if d, ok := e.(AdmissionDenied); ok {
+		return d.Code, e
+	}
+	return DiameterUnknownPeer, e
+}
+
+// AdmissionEvent notify result of admission policy for CER
+type AdmissionEvent struct {
+	Peer    Identity
+	Realm   Identity
+	Addr    net.Addr
+	Time    time.Time
+	Allowed bool
+	Err     error
+}
+
+// Type returns AdmissionNotice
+func (e AdmissionEvent) Type() NoticeType {
+	return AdmissionNotice
+}
+
+// PeerHost returns Host of the peer
+func (e AdmissionEvent) PeerHost() Identity {
+	return e.Peer
+}
+
+func (e AdmissionEvent) String() string {
+	if e.Allowed {
+		return fmt.Sprintf("Admission: Peer %s (%s): Allowed", e.Peer, e.Addr)
+	}
+	return fmt.Sprintf("Admission: Peer %s (%s): Denied: %s", e.Peer, e.Addr, e.Err)
+}
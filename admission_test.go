@@ -0,0 +1,70 @@
+package diameter_test
+
+import (
+	"net"
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+)
+
+func TestPolicyAdmit(t *testing.T) {
+	nets := func(s ...string) []*net.IPNet {
+		l, e := dia.ParseCIDRs(s...)
+		if e != nil {
+			t.Fatal(e)
+		}
+		return l
+	}
+	r := dia.CER{
+		OriginHost:    "Peer.Example.com",
+		OriginRealm:   "example.com",
+		ApplicationID: map[uint32][]uint32{0: {4}}}
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 3868}
+
+	tests := []struct {
+		name string
+		p    dia.Policy
+		code uint32 // zero is admitted
+	}{
+		{"empty", dia.Policy{}, 0},
+		{"deny host", dia.Policy{DenyHost: []string{"*.EXAMPLE.COM"}}, dia.DiameterUnknownPeer},
+		{"deny other host", dia.Policy{DenyHost: []string{"other.*"}}, 0},
+		{"allow host", dia.Policy{AllowHost: []string{"peer.*"}}, 0},
+		{"not allowed host", dia.Policy{AllowHost: []string{"other.*"}}, dia.DiameterUnknownPeer},
+		{"deny realm", dia.Policy{DenyRealm: []dia.Identity{"EXAMPLE.COM"}}, dia.DiameterUnknownPeer},
+		{"allow realm", dia.Policy{AllowRealm: []dia.Identity{"example.com"}}, 0},
+		{"not allowed realm", dia.Policy{AllowRealm: []dia.Identity{"example.net"}}, dia.DiameterUnknownPeer},
+		{"deny net", dia.Policy{DenyNet: nets("192.0.2.0/24")}, dia.DiameterUnknownPeer},
+		{"allow net", dia.Policy{AllowNet: nets("192.0.2.0/24")}, 0},
+		{"not allowed net", dia.Policy{AllowNet: nets("198.51.100.0/24")}, dia.DiameterUnknownPeer},
+		{"deny precedes allow", dia.Policy{
+			AllowHost: []string{"peer.*"}, DenyNet: nets("192.0.2.1/32")}, dia.DiameterUnknownPeer},
+		{"max conn", dia.Policy{MaxConn: 1}, 0},
+		{"required app", dia.Policy{RequiredApps: []uint32{4}}, 0},
+		{"missing app", dia.Policy{RequiredApps: []uint32{4, 16777312}}, dia.DiameterNoCommonApplication},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := dia.AdmitFrom(tt.p, r, addr)
+			if tt.code == 0 {
+				if e != nil {
+					t.Fatalf("denied: %v", e)
+				}
+				return
+			}
+			d, ok := e.(dia.AdmissionDenied)
+			if !ok {
+				t.Fatalf("error is %v, want AdmissionDenied", e)
+			}
+			if d.Code != tt.code {
+				t.Fatalf("code is %d, want %d", d.Code, tt.code)
+			}
+		})
+	}
+
+	relay := r
+	relay.ApplicationID = map[uint32][]uint32{0: {0xffffffff}}
+	if e := dia.AdmitFrom(dia.Policy{RequiredApps: []uint32{4}}, relay, addr); e != nil {
+		t.Fatalf("relay is denied: %v", e)
+	}
+}
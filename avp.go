@@ -72,7 +72,9 @@ func (a RawAVP) WriteTo(w io.Writer) (n int64, e error) {
 	return int64(i), e
 }
 
-// ReadFrom read binary data from io.Reader
+// ReadFrom read binary data from io.Reader.
+// AVP length that is shorter than AVP header or longer than
+// remaining data of r is InvalidAVP with DIAMETER_INVALID_AVP_LENGTH.
 func (a *RawAVP) ReadFrom(r io.Reader) (n int64, e error) {
 	buf, i, e := subread(r, 8)
 	n += int64(i)
@@ -90,6 +92,14 @@ func (a *RawAVP) ReadFrom(r io.Reader) (n int64, e error) {
 	buf[4] = 0x00
 	var lng uint32
 	binary.Read(bytes.NewBuffer(buf[4:8]), binary.BigEndian, &lng)
+	if lng < 8 || (a.FlgV && lng < 12) {
+		e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: *a}
+		return
+	}
+	if b, ok := r.(interface{ Len() int }); ok && int(lng)-8 > b.Len() {
+		e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: *a}
+		return
+	}
 	l := lng - 8
 
 	if a.FlgV {
@@ -178,29 +188,33 @@ func (a RawAVP) Decode(d interface{}) (e error) {
 		} else if len(a.data) == 18 && a.data[0] == 0x00 && a.data[1] == 0x02 {
 			*d = net.IP(a.data[2:18])
 		} else {
-			e = fmt.Errorf("invalid address family")
+			e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
 		}
 	case *time.Time:
 		if len(a.data) != 8 {
-			e = io.EOF
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 		} else {
 			buf := bytes.NewReader(a.data)
 			var t uint64
-			if e = binary.Read(buf, binary.BigEndian, &t); e != nil {
+			if e = binary.Read(buf, binary.BigEndian, &t); e == nil {
 				*d = time.Unix(int64(t-2208988800), int64(0))
 			}
 		}
 	case *Identity:
-		*d, e = ParseIdentity(string(a.data))
+		if *d, e = ParseIdentity(string(a.data)); e != nil {
+			e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
+		}
 	case *URI:
-		*d, e = ParseURI(string(a.data))
+		if *d, e = ParseURI(string(a.data)); e != nil {
+			e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
+		}
 	case *Enumerated:
 		if len(a.data) != 4 {
-			e = io.EOF
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 		} else {
 			buf := bytes.NewReader(a.data)
 			var t int32
-			if e = binary.Read(buf, binary.BigEndian, &t); e != nil {
+			if e = binary.Read(buf, binary.BigEndian, &t); e == nil {
 				*d = Enumerated(t)
 			}
 		}
@@ -213,6 +227,7 @@ func (a RawAVP) Decode(d interface{}) (e error) {
 		for buf := bytes.NewReader(a.data); buf.Len() != 0; {
 			avp := RawAVP{}
 			if _, e = avp.ReadFrom(buf); e != nil {
+				e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 				break
 			}
 			*d = append(*d, avp)
@@ -223,14 +238,14 @@ func (a RawAVP) Decode(d interface{}) (e error) {
 		*d = b
 	case *int32, *uint32, *float32:
 		if len(a.data) != 4 {
-			e = io.EOF
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 		} else {
 			buf := bytes.NewReader(a.data)
 			e = binary.Read(buf, binary.BigEndian, d)
 		}
 	case *int64, *uint64, *float64:
 		if len(a.data) != 8 {
-			e = io.EOF
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 		} else {
 			buf := bytes.NewReader(a.data)
 			e = binary.Read(buf, binary.BigEndian, d)
@@ -12,7 +12,7 @@ func setHostIPAddress(v net.IP) (a RawAVP) {
 
 func getHostIPAddress(a RawAVP) (v net.IP, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -27,7 +27,7 @@ func setAuthAppID(v uint32) (a RawAVP) {
 
 func getAuthAppID(a RawAVP) (v uint32, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -42,7 +42,7 @@ func setVendorID(v uint32) (a RawAVP) {
 
 func getVendorID(a RawAVP) (v uint32, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -57,7 +57,7 @@ func setFirmwareRevision(v uint32) (a RawAVP) {
 
 func getFirmwareRevision(a RawAVP) (v uint32, e error) {
 	if a.FlgV || a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -72,7 +72,7 @@ func setProductName(v string) (a RawAVP) {
 
 func getProductName(a RawAVP) (v string, e error) {
 	if a.FlgV || a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -96,12 +96,12 @@ func setDisconnectCause(v Enumerated) (a RawAVP) {
 
 func getDisconnectCause(a RawAVP) (v Enumerated, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
 	if v < 0 || v > 2 {
-		e = InvalidAVP(DiameterInvalidAvpValue)
+		e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
 	}
 	return
 }
@@ -114,7 +114,7 @@ func setOriginStateID(v uint32) (a RawAVP) {
 
 func getOriginStateID(a RawAVP) (v uint32, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -129,7 +129,7 @@ func setFailedAVP(v []RawAVP) (a RawAVP) {
 
 func getFailedAVP(a RawAVP) (v []RawAVP, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -144,7 +144,7 @@ func setSupportedVendorID(v uint32) (a RawAVP) {
 
 func getSupportedVendorID(a RawAVP) (v uint32, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -158,8 +158,23 @@ func setErrorMessage(v string) (a RawAVP) {
 }
 
 func getErrorMessage(a RawAVP) (v string, e error) {
-	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+	if a.FlgV || a.FlgM || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
+		e = a.Decode(&v)
+	}
+	return
+}
+
+func setErrorReportingHost(v Identity) (a RawAVP) {
+	a = RawAVP{Code: 294, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	a.Encode(v)
+	return
+}
+
+func getErrorReportingHost(a RawAVP) (v Identity, e error) {
+	if a.FlgV || a.FlgM || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -11,7 +11,7 @@ func SetVendorSpecAppID(vi, ai uint32) (a RawAVP) {
 func GetVendorSpecAppID(a RawAVP) (vi, ai uint32, e error) {
 	o := []RawAVP{}
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&o)
 	}
@@ -27,7 +27,7 @@ func GetVendorSpecAppID(a RawAVP) (vi, ai uint32, e error) {
 		}
 	}
 	if vi == 0 || ai == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: a}
 	}
 	return
 }
@@ -42,7 +42,7 @@ func SetSessionID(v string) (a RawAVP) {
 // GetSessionID read Session-ID AVP
 func GetSessionID(a RawAVP) (v string, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -59,7 +59,7 @@ func SetOriginHost(v Identity) (a RawAVP) {
 // GetOriginHost read Origin-Host AVP
 func GetOriginHost(a RawAVP) (v Identity, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -68,55 +68,86 @@ func GetOriginHost(a RawAVP) (v Identity, e error) {
 
 // SetResultCode make Result-Code AVP
 func SetResultCode(c uint32) (a RawAVP) {
-	if c < 10000 {
-		a = RawAVP{Code: 268, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
-		a.Encode(c)
-		return
-	}
-	a = RawAVP{Code: 297, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
-	v := []RawAVP{
-		RawAVP{Code: 266, VenID: 0, FlgV: false, FlgM: true, FlgP: false},
-		RawAVP{Code: 298, VenID: 0, FlgV: false, FlgM: true, FlgP: false}}
-	v[0].Encode(c / 10000)
-	v[1].Encode(c % 10000)
-	a.Encode(v)
+	a = RawAVP{Code: 268, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
+	a.Encode(c)
 	return
 }
 
 // GetResultCode read Result-Code AVP
 func GetResultCode(a RawAVP) (c uint32, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
-	} else if a.Code == 268 {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
 		e = a.Decode(&c)
-		return
-	} else if a.Code == 297 {
-		o := []RawAVP{}
+	}
+	return
+}
+
+// SetExperimentalResult make Experimental-Result AVP
+func SetExperimentalResult(v, c uint32) (a RawAVP) {
+	a = RawAVP{Code: 297, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
+	t := []RawAVP{
+		setVendorID(v),
+		RawAVP{Code: 298, VenID: 0, FlgV: false, FlgM: true, FlgP: false}}
+	t[1].Encode(c)
+	a.Encode(t)
+	return
+}
+
+// GetExperimentalResult read Experimental-Result AVP
+func GetExperimentalResult(a RawAVP) (v, c uint32, e error) {
+	o := []RawAVP{}
+	if a.FlgV || !a.FlgM || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
 		e = a.Decode(&o)
-		for _, a := range o {
-			switch a.Code {
-			case 266:
-				if a.FlgV || !a.FlgM || a.FlgP {
-					e = InvalidAVP(DiameterInvalidAvpBits)
-				} else {
-					var i uint32
-					e = a.Decode(&i)
-					c += i * 10000
-				}
-			case 298:
-				if a.FlgV || !a.FlgM || a.FlgP {
-					e = InvalidAVP(DiameterInvalidAvpBits)
-				} else {
-					var r uint32
-					e = a.Decode(&r)
-					c += r
-				}
-			}
+	}
+	vok, cok := false, false
+	for _, a := range o {
+		if e != nil {
+			break
 		}
-		if c < 10000 || c%10000 == 0 {
-			e = InvalidAVP(DiameterMissingAvp)
+		if a.VenID != 0 {
+			continue
+		}
+		switch a.Code {
+		case 266:
+			v, e = getVendorID(a)
+			vok = true
+		case 298:
+			if a.FlgV || !a.FlgM || a.FlgP {
+				e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+			} else {
+				e = a.Decode(&c)
+			}
+			cok = true
 		}
 	}
+	if e == nil && (!vok || !cok) {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: a}
+	}
+	return
+}
+
+// SetResult make Result-Code or Experimental-Result AVP
+func SetResult(r Result) RawAVP {
+	if r.IsExperimental {
+		return SetExperimentalResult(r.VendorID, r.Code)
+	}
+	return SetResultCode(r.Code)
+}
+
+// GetResult read Result-Code or Experimental-Result AVP
+func GetResult(a RawAVP) (r Result, e error) {
+	switch a.Code {
+	case 268:
+		r.Code, e = GetResultCode(a)
+	case 297:
+		r.IsExperimental = true
+		r.VendorID, r.Code, e = GetExperimentalResult(a)
+	default:
+		e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
+	}
 	return
 }
 
@@ -137,7 +168,7 @@ func SetAuthSessionState(v bool) (a RawAVP) {
 func GetAuthSessionState(a RawAVP) (v bool, e error) {
 	s := new(Enumerated)
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e != nil {
 		switch *s {
 		case 0:
@@ -145,7 +176,7 @@ func GetAuthSessionState(a RawAVP) (v bool, e error) {
 		case 1:
 			v = false
 		default:
-			e = InvalidAVP(DiameterInvalidAvpValue)
+			e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -161,7 +192,7 @@ func SetFailedAVP(v []RawAVP) (a RawAVP) {
 // GetFailedAVP read Failed-AVP AVP
 func GetFailedAVP(a RawAVP) (v []RawAVP, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -178,7 +209,7 @@ func SetRouteRecord(v Identity) (a RawAVP) {
 // GetRouteRecord read Route-Record AVP
 func GetRouteRecord(a RawAVP) (v Identity, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -195,7 +226,7 @@ func SetDestinationRealm(v Identity) (a RawAVP) {
 // GetDestinationRealm read Destination-Realm AVP
 func GetDestinationRealm(a RawAVP) (v Identity, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -212,7 +243,7 @@ func SetDestinationHost(v Identity) (a RawAVP) {
 // GetDestinationHost read Destination-Host AVP
 func GetDestinationHost(a RawAVP) (v Identity, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -229,106 +260,50 @@ func SetOriginRealm(v Identity) (a RawAVP) {
 // GetOriginRealm read Origin-Realm AVP
 func GetOriginRealm(a RawAVP) (v Identity, e error) {
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = InvalidAVP(DiameterInvalidAvpBits)
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
 	return
 }
 
-/*
-// ProxyHost AVP
-type ProxyHost diameter.Identity
-
-// ToRaw return AVP struct of this value
-func (v *ProxyHost) ToRaw() diameter.RawAVP {
-	a := diameter.RawAVP{Code: 280, VenID: 0,
-		FlgV: false, FlgM: true, FlgP: false}
-	if v != nil {
-		a.Encode(diameter.Identity(*v))
-	}
-	return a
-}
-
-// FromRaw get AVP value
-func (v *ProxyHost) FromRaw(a diameter.RawAVP) (e error) {
-	if e = a.Validate(0, 280, false, true, false); e != nil {
-		return
-	}
-	s := new(diameter.Identity)
-	if e = a.Decode(s); e != nil {
-		return
-	}
-	*v = ProxyHost(*s)
+// SetProxyInfo make Proxy-Info AVP
+func SetProxyInfo(h Identity, s []byte) (a RawAVP) {
+	a = RawAVP{Code: 284, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
+	ph := RawAVP{Code: 280, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
+	ph.Encode(h)
+	ps := RawAVP{Code: 33, VenID: 0, FlgV: false, FlgM: true, FlgP: false}
+	ps.Encode(s)
+	a.Encode([]RawAVP{ph, ps})
 	return
 }
 
-// ProxyState AVP
-type ProxyState []byte
-
-// ToRaw return AVP struct of this value
-func (v *ProxyState) ToRaw() diameter.RawAVP {
-	a := diameter.RawAVP{Code: 33, VenID: 0,
-		FlgV: false, FlgM: true, FlgP: false}
-	if v != nil {
-		a.Encode([]byte(*v))
-	}
-	return a
-}
-
-// FromRaw get AVP value
-func (v *ProxyState) FromRaw(a diameter.RawAVP) (e error) {
-	if e = a.Validate(0, 33, false, true, false); e != nil {
-		return
-	}
-	s := new([]byte)
-	if e = a.Decode(s); e != nil {
-		return
-	}
-	*v = ProxyState(*s)
-	return
-}
-
-// ProxyInfo AVP
-type ProxyInfo struct {
-	ProxyHost
-	ProxyState
-}
-
-// ToRaw return AVP struct of this value
-func (v *ProxyInfo) ToRaw() diameter.RawAVP {
-	a := diameter.RawAVP{Code: 284, VenID: 0,
-		FlgV: false, FlgM: true, FlgP: false}
-	if v != nil {
-		t := []diameter.RawAVP{
-			v.ProxyHost.ToRaw(),
-			v.ProxyState.ToRaw()}
-		a.Encode(diameter.GroupedAVP(t))
-	}
-	return a
-}
-
-// FromRaw get AVP value
-func (v *ProxyInfo) FromRaw(a diameter.RawAVP) (e error) {
-	if e = a.Validate(0, 284, false, true, false); e != nil {
+// GetProxyInfo read Proxy-Info AVP
+func GetProxyInfo(a RawAVP) (h Identity, s []byte, e error) {
+	o := []RawAVP{}
+	if a.FlgV || !a.FlgM || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
 		return
 	}
-	o := diameter.GroupedAVP{}
 	if e = a.Decode(&o); e != nil {
 		return
 	}
-	*v = ProxyInfo{}
-	for _, a := range o {
-		if a.VenID != 0 {
+	for _, v := range o {
+		if v.VenID != 0 {
 			continue
 		}
-		switch a.Code {
+		switch v.Code {
 		case 280:
-			e = v.ProxyHost.FromRaw(a)
+			e = v.Decode(&h)
 		case 33:
-			e = v.ProxyState.FromRaw(a)
+			e = v.Decode(&s)
 		}
+		if e != nil {
+			return
+		}
+	}
+	if len(h) == 0 || s == nil {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: a}
 	}
 	return
 }
-*/
@@ -9,6 +9,7 @@ import (
 /*
 CER is Capabilities-Exchange-Request message
  <CER> ::= < Diameter Header: 257, REQ >
+		   [ DRMP ]
 		   { Origin-Host }
 		   { Origin-Realm }
 		1* { Host-IP-Address }
@@ -24,6 +25,8 @@ CER is Capabilities-Exchange-Request message
 		 * [ AVP ]
 */
 type CER struct {
+	DRMP DRMP
+
 	OriginHost    Identity
 	OriginRealm   Identity
 	HostIPAddress []net.IP
@@ -68,6 +71,9 @@ func (v CER) ToRaw(s string) RawMsg {
 		Code: 257, AppID: 0,
 		AVP: make([]RawAVP, 0, 20)}
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
 	for _, ip := range v.HostIPAddress {
@@ -109,6 +115,8 @@ func (CER) FromRaw(m RawMsg) (Request, string, error) {
 
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 264:
 			v.OriginHost, e = GetOriginHost(a)
 		case 296:
@@ -156,20 +164,25 @@ func (CER) FromRaw(m RawMsg) (Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 ||
-		len(v.OriginRealm) == 0 ||
-		len(v.HostIPAddress) == 0 ||
-		v.VendorID == 0 ||
-		len(v.ProductName) == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginHost("")}
+	} else if len(v.OriginRealm) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginRealm("")}
+	} else if len(v.HostIPAddress) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: setHostIPAddress(net.IPv4zero)}
+	} else if v.VendorID == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: setVendorID(0)}
+	} else if len(v.ProductName) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: setProductName("")}
 	}
 	return v, "", e
 }
 
 // Failed make error message for timeout
-func (v CER) Failed(c uint32) Answer {
+func (v CER) Failed(r Result) Answer {
 	return CEA{
-		ResultCode:    c,
+		DRMP:          v.DRMP,
+		ResultCode:    r.Code,
 		OriginHost:    Host,
 		OriginRealm:   Realm,
 		HostIPAddress: []net.IP{net.IPv4zero},
@@ -180,6 +193,7 @@ func (v CER) Failed(c uint32) Answer {
 /*
 CEA is Capabilities-Exchange-Answer message
  <CEA> ::= < Diameter Header: 257 >
+		   [ DRMP ]
 		   { Result-Code }
 		   { Origin-Host }
 		   { Origin-Realm }
@@ -198,6 +212,8 @@ CEA is Capabilities-Exchange-Answer message
 		 * [ AVP ]
 */
 type CEA struct {
+	DRMP DRMP
+
 	ResultCode    uint32
 	OriginHost    Identity
 	OriginRealm   Identity
@@ -250,6 +266,9 @@ func (v CEA) ToRaw(s string) RawMsg {
 		AVP: make([]RawAVP, 0, 20)}
 	m.FlgE = v.ResultCode != DiameterSuccess
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetResultCode(v.ResultCode))
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
@@ -298,6 +317,8 @@ func (CEA) FromRaw(m RawMsg) (Answer, string, error) {
 
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 268:
 			v.ResultCode, e = GetResultCode(a)
 		case 264:
@@ -356,26 +377,29 @@ func (CEA) FromRaw(m RawMsg) (Answer, string, error) {
 		len(v.HostIPAddress) == 0 ||
 		v.VendorID == 0 ||
 		len(v.ProductName) == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+		e = InvalidAVP{Code: DiameterMissingAvp}
 	}
 
 	return v, "", e
 }
 
 // Result returns result-code
-func (v CEA) Result() uint32 {
-	return v.ResultCode
+func (v CEA) Result() Result {
+	return ResultCode(v.ResultCode)
 }
 
 /*
 DPR is Disconnect-Peer-Request message
  <DPR>  ::= < Diameter Header: 282, REQ >
+			[ DRMP ]
 			{ Origin-Host }
 			{ Origin-Realm }
 			{ Disconnect-Cause }
 		  * [ AVP ]
 */
 type DPR struct {
+	DRMP DRMP
+
 	OriginHost      Identity
 	OriginRealm     Identity
 	DisconnectCause Enumerated
@@ -399,6 +423,9 @@ func (v DPR) ToRaw(s string) RawMsg {
 		Code: 282, AppID: 0,
 		AVP: make([]RawAVP, 0, 3)}
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
 	m.AVP = append(m.AVP, setDisconnectCause(v.DisconnectCause))
@@ -416,6 +443,8 @@ func (DPR) FromRaw(m RawMsg) (Request, string, error) {
 		DisconnectCause: -1}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 264:
 			v.OriginHost, e = GetOriginHost(a)
 		case 296:
@@ -429,18 +458,21 @@ func (DPR) FromRaw(m RawMsg) (Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 ||
-		len(v.OriginRealm) == 0 ||
-		v.DisconnectCause < 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginHost("")}
+	} else if len(v.OriginRealm) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginRealm("")}
+	} else if v.DisconnectCause < 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: setDisconnectCause(0)}
 	}
 	return v, "", e
 }
 
 // Failed make error message for timeout
-func (v DPR) Failed(c uint32) Answer {
+func (v DPR) Failed(r Result) Answer {
 	return DPA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r.Code,
 		OriginHost:  Host,
 		OriginRealm: Realm}
 }
@@ -448,6 +480,7 @@ func (v DPR) Failed(c uint32) Answer {
 /*
 DPA is Disconnect-Peer-Answer message
  <DPA>  ::= < Diameter Header: 282 >
+			[ DRMP ]
 			{ Result-Code }
 			{ Origin-Host }
 			{ Origin-Realm }
@@ -456,6 +489,8 @@ DPA is Disconnect-Peer-Answer message
 		  * [ AVP ]
 */
 type DPA struct {
+	DRMP DRMP
+
 	ResultCode   uint32
 	OriginHost   Identity
 	OriginRealm  Identity
@@ -486,6 +521,9 @@ func (v DPA) ToRaw(s string) RawMsg {
 		AVP: make([]RawAVP, 0, 5)}
 	m.FlgE = v.ResultCode != DiameterSuccess
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetResultCode(v.ResultCode))
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
@@ -508,6 +546,8 @@ func (DPA) FromRaw(m RawMsg) (Answer, string, error) {
 	v := DPA{}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 268:
 			v.ResultCode, e = GetResultCode(a)
 		case 264:
@@ -527,25 +567,28 @@ func (DPA) FromRaw(m RawMsg) (Answer, string, error) {
 	if v.ResultCode == 0 ||
 		len(v.OriginHost) == 0 ||
 		len(v.OriginRealm) == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+		e = InvalidAVP{Code: DiameterMissingAvp}
 	}
 	return v, "", e
 }
 
 // Result returns result-code
-func (v DPA) Result() uint32 {
-	return v.ResultCode
+func (v DPA) Result() Result {
+	return ResultCode(v.ResultCode)
 }
 
 /*
 DWR is DeviceWatchdogRequest message
  <DWR>  ::= < Diameter Header: 280, REQ >
+			[ DRMP ]
 			{ Origin-Host }
 			{ Origin-Realm }
 			[ Origin-State-Id ]
 		  * [ AVP ]
 */
 type DWR struct {
+	DRMP DRMP
+
 	OriginHost    Identity
 	OriginRealm   Identity
 	OriginStateID uint32
@@ -569,6 +612,9 @@ func (v DWR) ToRaw(s string) RawMsg {
 		Code: 280, AppID: 0,
 		AVP: make([]RawAVP, 0, 3)}
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
 	if v.OriginStateID != 0 {
@@ -587,6 +633,8 @@ func (DWR) FromRaw(m RawMsg) (Request, string, error) {
 	v := DWR{}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 264:
 			v.OriginHost, e = GetOriginHost(a)
 		case 296:
@@ -599,17 +647,19 @@ func (DWR) FromRaw(m RawMsg) (Request, string, error) {
 			return nil, "", e
 		}
 	}
-	if len(v.OriginHost) == 0 ||
-		len(v.OriginRealm) == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginHost("")}
+	} else if len(v.OriginRealm) == 0 {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: SetOriginRealm("")}
 	}
 	return v, "", e
 }
 
 // Failed make error message for timeout
-func (v DWR) Failed(c uint32) Answer {
+func (v DWR) Failed(r Result) Answer {
 	return DWA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r.Code,
 		OriginHost:  Host,
 		OriginRealm: Realm}
 }
@@ -617,6 +667,7 @@ func (v DWR) Failed(c uint32) Answer {
 /*
 DWA Device-Watchdo-gAnswer message
  <DWA>  ::= < Diameter Header: 280 >
+			[ DRMP ]
 			{ Result-Code }
 			{ Origin-Host }
 			{ Origin-Realm }
@@ -626,6 +677,8 @@ DWA Device-Watchdo-gAnswer message
 		  * [ AVP ]
 */
 type DWA struct {
+	DRMP DRMP
+
 	ResultCode    uint32
 	OriginHost    Identity
 	OriginRealm   Identity
@@ -658,6 +711,9 @@ func (v DWA) ToRaw(s string) RawMsg {
 		AVP: make([]RawAVP, 0, 6)}
 	m.FlgE = v.ResultCode != DiameterSuccess
 
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetResultCode(v.ResultCode))
 	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
@@ -683,6 +739,8 @@ func (DWA) FromRaw(m RawMsg) (Answer, string, error) {
 	v := DWA{}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 268:
 			v.ResultCode, e = GetResultCode(a)
 		case 264:
@@ -704,12 +762,12 @@ func (DWA) FromRaw(m RawMsg) (Answer, string, error) {
 	if v.ResultCode == 0 ||
 		len(v.OriginHost) == 0 ||
 		len(v.OriginRealm) == 0 {
-		e = InvalidAVP(DiameterMissingAvp)
+		e = InvalidAVP{Code: DiameterMissingAvp}
 	}
 	return v, "", e
 }
 
 // Result returns result-code
-func (v DWA) Result() uint32 {
-	return v.ResultCode
+func (v DWA) Result() Result {
+	return ResultCode(v.ResultCode)
 }
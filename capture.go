@@ -0,0 +1,359 @@
+package diameter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapture is Capture that is set to new Conn
+var DefaultCapture *Capture
+
+// CaptureFormat is file format of Capture
+type CaptureFormat int
+
+const (
+	// PcapFormat is libpcap file format
+	PcapFormat CaptureFormat = iota
+	// PcapngFormat is pcapng file format
+	PcapngFormat
+)
+
+const (
+	linkTypeRaw    = 101
+	captureSnapLen = 0x40000
+	captureSegment = 0xffff - 60 - 20
+)
+
+// Capture write Diameter messages to pcap or pcapng file.
+// Each message is wrapped in synthetic IP and TCP or SCTP header.
+type Capture struct {
+	// Path is file path. When rotation is enabled,
+	// file number and time is added to the name.
+	Path   string
+	Format CaptureFormat
+	// SCTP use SCTP DATA chunk instead of TCP segment
+	SCTP bool
+	// MaxSize is file size in bytes that triggers rotation
+	MaxSize int64
+	// MaxAge is file age that triggers rotation
+	MaxAge time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	size   int64
+	opened time.Time
+	count  int
+	flows  map[*Conn]*captureFlow
+}
+
+type captureFlow struct {
+	seq [2]uint32 // TCP sequence number or SCTP TSN
+	ssn [2]uint16 // SCTP stream sequence number
+}
+
+// NewCapture open new capture file
+func NewCapture(path string, f CaptureFormat) (*Capture, error) {
+	c := &Capture{Path: path, Format: f}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.open(DefaultClock.Now()); e != nil {
+		return nil, e
+	}
+	return c, nil
+}
+
+// Close flush and close capture file
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+// Flush write buffered data to capture file
+func (c *Capture) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buf == nil {
+		return nil
+	}
+	return c.buf.Flush()
+}
+
+func (c *Capture) filename(t time.Time) string {
+	if c.MaxSize <= 0 && c.MaxAge <= 0 {
+		return c.Path
+	}
+	ext := filepath.Ext(c.Path)
+	return fmt.Sprintf("%s_%05d_%s%s",
+		strings.TrimSuffix(c.Path, ext), c.count,
+		t.Format("20060102150405"), ext)
+}
+
+func (c *Capture) open(t time.Time) (e error) {
+	c.count++
+	if c.file, e = os.Create(c.filename(t)); e != nil {
+		return
+	}
+	c.buf = bufio.NewWriter(c.file)
+	c.opened = t
+	c.size = 0
+
+	w := new(bytes.Buffer)
+	if c.Format == PcapngFormat {
+		// Section Header Block
+		binary.Write(w, binary.LittleEndian, []uint32{
+			0x0a0d0d0a, 28, 0x1a2b3c4d, 0x00000001,
+			0xffffffff, 0xffffffff, 28})
+		// Interface Description Block
+		binary.Write(w, binary.LittleEndian, []uint32{
+			0x00000001, 20, linkTypeRaw, captureSnapLen, 20})
+	} else {
+		binary.Write(w, binary.LittleEndian, []uint32{
+			0xa1b2c3d4, 0x00040002, 0, 0, captureSnapLen, linkTypeRaw})
+	}
+	return c.write(w.Bytes())
+}
+
+func (c *Capture) close() (e error) {
+	if c.file == nil {
+		return nil
+	}
+	e = c.buf.Flush()
+	if e2 := c.file.Close(); e == nil {
+		e = e2
+	}
+	c.file = nil
+	c.buf = nil
+	return
+}
+
+func (c *Capture) write(b []byte) error {
+	n, e := c.buf.Write(b)
+	c.size += int64(n)
+	return e
+}
+
+func (c *Capture) rotate(t time.Time) error {
+	if (c.MaxSize <= 0 || c.size < c.MaxSize) &&
+		(c.MaxAge <= 0 || t.Sub(c.opened) < c.MaxAge) {
+		return nil
+	}
+	if e := c.close(); e != nil {
+		return e
+	}
+	return c.open(t)
+}
+
+func (c *Capture) forget(con *Conn) {
+	c.mu.Lock()
+	delete(c.flows, con)
+	c.mu.Unlock()
+}
+
+func (c *Capture) capture(con *Conn, d Direction, m RawMsg) error {
+	data := new(bytes.Buffer)
+	if _, e := m.WriteTo(data); e != nil {
+		return e
+	}
+	src, dst := captureAddr(con.LocalAddr(), 1), captureAddr(con.PeerAddr(), 2)
+	i := 0
+	if d == Rx {
+		src, dst = dst, src
+		i = 1
+	}
+	t := con.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return os.ErrClosed
+	}
+	if c.flows == nil {
+		c.flows = make(map[*Conn]*captureFlow)
+	}
+	f, ok := c.flows[con]
+	if !ok {
+		f = &captureFlow{seq: [2]uint32{1, 1}}
+		c.flows[con] = f
+	}
+
+	b := data.Bytes()
+	for first := true; first || len(b) != 0; first = false {
+		l := len(b)
+		if l > captureSegment {
+			l = captureSegment
+		}
+		var p []byte
+		if c.SCTP {
+			var flg byte
+			if first {
+				flg |= 0x02
+			}
+			if l == len(b) {
+				flg |= 0x01
+			}
+			p = sctpPacket(src, dst, f.seq[i], f.ssn[i], flg, b[:l])
+			f.seq[i]++
+		} else {
+			p = tcpPacket(src, dst, f.seq[i], f.seq[1-i], b[:l])
+			f.seq[i] += uint32(l)
+		}
+		b = b[l:]
+		if e := c.record(t, p); e != nil {
+			return e
+		}
+	}
+	if c.SCTP {
+		f.ssn[i]++
+	}
+	return c.rotate(t)
+}
+
+func (c *Capture) record(t time.Time, p []byte) error {
+	w := new(bytes.Buffer)
+	us := uint64(t.UnixNano() / 1000)
+	if c.Format == PcapngFormat {
+		pad := (4 - len(p)%4) % 4
+		l := uint32(32 + len(p) + pad)
+		// Enhanced Packet Block
+		binary.Write(w, binary.LittleEndian, []uint32{
+			0x00000006, l, 0, uint32(us >> 32), uint32(us),
+			uint32(len(p)), uint32(len(p))})
+		w.Write(p)
+		w.Write(make([]byte, pad))
+		binary.Write(w, binary.LittleEndian, l)
+	} else {
+		binary.Write(w, binary.LittleEndian, []uint32{
+			uint32(us / 1000000), uint32(us % 1000000),
+			uint32(len(p)), uint32(len(p))})
+		w.Write(p)
+	}
+	return c.write(w.Bytes())
+}
+
+type captureEndpoint struct {
+	ip   net.IP
+	port uint16
+}
+
+// captureAddr returns IP and port of a.
+// Address that is not IP (ex. net.Pipe) is mapped to 127.0.0.n:3868.
+func captureAddr(a net.Addr, n byte) captureEndpoint {
+	r := captureEndpoint{ip: net.IPv4(127, 0, 0, n), port: 3868}
+	if a == nil {
+		return r
+	}
+	h, p, e := net.SplitHostPort(a.String())
+	if e != nil {
+		return r
+	}
+	// SCTP multi-homed address is "ip1/ip2:port"
+	if i := strings.Index(h, "/"); i >= 0 {
+		h = h[:i]
+	}
+	if ip := net.ParseIP(h); ip != nil {
+		r.ip = ip
+	}
+	if v, e := strconv.ParseUint(p, 10, 16); e == nil {
+		r.port = uint16(v)
+	}
+	return r
+}
+
+func ipPacket(src, dst captureEndpoint, proto byte, payload []byte) []byte {
+	w := new(bytes.Buffer)
+	s4, d4 := src.ip.To4(), dst.ip.To4()
+	if s4 != nil && d4 != nil {
+		h := make([]byte, 20)
+		h[0] = 0x45
+		binary.BigEndian.PutUint16(h[2:], uint16(20+len(payload)))
+		h[6] = 0x40 // don't fragment
+		h[8] = 64
+		h[9] = proto
+		copy(h[12:], s4)
+		copy(h[16:], d4)
+		binary.BigEndian.PutUint16(h[10:], ^uint16(checksum(0, h)))
+		w.Write(h)
+	} else {
+		h := make([]byte, 40)
+		h[0] = 0x60
+		binary.BigEndian.PutUint16(h[4:], uint16(len(payload)))
+		h[6] = proto
+		h[7] = 64
+		copy(h[8:], src.ip.To16())
+		copy(h[24:], dst.ip.To16())
+		w.Write(h)
+	}
+	w.Write(payload)
+	return w.Bytes()
+}
+
+func tcpPacket(src, dst captureEndpoint, seq, ack uint32, data []byte) []byte {
+	h := make([]byte, 20, 20+len(data))
+	binary.BigEndian.PutUint16(h[0:], src.port)
+	binary.BigEndian.PutUint16(h[2:], dst.port)
+	binary.BigEndian.PutUint32(h[4:], seq)
+	binary.BigEndian.PutUint32(h[8:], ack)
+	h[12] = 0x50
+	h[13] = 0x18 // PSH, ACK
+	binary.BigEndian.PutUint16(h[14:], 0xffff)
+	h = append(h, data...)
+
+	// pseudo header checksum
+	var s uint32
+	if s4, d4 := src.ip.To4(), dst.ip.To4(); s4 != nil && d4 != nil {
+		s = checksum(s, s4)
+		s = checksum(s, d4)
+	} else {
+		s = checksum(s, src.ip.To16())
+		s = checksum(s, dst.ip.To16())
+	}
+	s += 6 + uint32(len(h))
+	binary.BigEndian.PutUint16(h[16:], ^uint16(checksum(s, h)))
+	return ipPacket(src, dst, 6, h)
+}
+
+func sctpPacket(src, dst captureEndpoint, tsn uint32, ssn uint16, flg byte, data []byte) []byte {
+	pad := (4 - len(data)%4) % 4
+	h := make([]byte, 28, 28+len(data)+pad)
+	binary.BigEndian.PutUint16(h[0:], src.port)
+	binary.BigEndian.PutUint16(h[2:], dst.port)
+	binary.BigEndian.PutUint32(h[4:], 1) // verification tag
+	// DATA chunk
+	h[12] = 0
+	h[13] = flg
+	binary.BigEndian.PutUint16(h[14:], uint16(16+len(data)))
+	binary.BigEndian.PutUint32(h[16:], tsn)
+	binary.BigEndian.PutUint16(h[20:], 0) // stream ID
+	binary.BigEndian.PutUint16(h[22:], ssn)
+	binary.BigEndian.PutUint32(h[24:], 46) // PPID of Diameter
+	h = append(h, data...)
+	h = append(h, make([]byte, pad)...)
+	binary.LittleEndian.PutUint32(h[8:],
+		crc32.Checksum(h, crc32.MakeTable(crc32.Castagnoli)))
+	return ipPacket(src, dst, 132, h)
+}
+
+func checksum(s uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	for s>>16 != 0 {
+		s = s&0xffff + s>>16
+	}
+	return s
+}
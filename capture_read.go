@@ -0,0 +1,461 @@
+package diameter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// captureMaxLen is maximum length of packet record or block in capture file
+const captureMaxLen = 256 * 1024
+
+// CapturedMsg is Diameter message read from capture file
+type CapturedMsg struct {
+	Time time.Time
+	Src  netip.AddrPort
+	Dst  netip.AddrPort
+	SCTP bool
+	RawMsg
+}
+
+// CaptureReader read Diameter messages from pcap or pcapng file.
+// TCP streams and fragmented SCTP DATA chunks are reassembled.
+type CaptureReader struct {
+	r     *bufio.Reader
+	ng    bool
+	order binary.ByteOrder
+
+	// pcap
+	link    uint32
+	nsec    bool
+	snaplen uint32
+	ifaces  []captureIface
+
+	flows map[captureFlowKey]*captureStream
+	out   []CapturedMsg
+}
+
+type captureIface struct {
+	link uint32
+	res  time.Duration // timestamp resolution
+}
+
+type captureFlowKey struct {
+	src, dst netip.AddrPort
+	sctp     bool
+	stream   uint16
+}
+
+type captureStream struct {
+	init    bool
+	next    uint32
+	buf     []byte
+	pending map[uint32][]byte
+}
+
+// NewCaptureReader returns CaptureReader that read from r
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	c := &CaptureReader{
+		r:     bufio.NewReader(r),
+		flows: make(map[captureFlowKey]*captureStream)}
+	b, e := c.r.Peek(4)
+	if e != nil {
+		return nil, e
+	}
+	switch {
+	case bytes.Equal(b, []byte{0x0a, 0x0d, 0x0d, 0x0a}):
+		c.ng = true
+		return c, nil
+	case bytes.Equal(b, []byte{0xd4, 0xc3, 0xb2, 0xa1}):
+		c.order = binary.LittleEndian
+	case bytes.Equal(b, []byte{0x4d, 0x3c, 0xb2, 0xa1}):
+		c.order, c.nsec = binary.LittleEndian, true
+	case bytes.Equal(b, []byte{0xa1, 0xb2, 0xc3, 0xd4}):
+		c.order = binary.BigEndian
+	case bytes.Equal(b, []byte{0xa1, 0xb2, 0x3c, 0x4d}):
+		c.order, c.nsec = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("unknown capture file format")
+	}
+	h := make([]byte, 24)
+	if _, e = io.ReadFull(c.r, h); e != nil {
+		return nil, e
+	}
+	c.link = c.order.Uint32(h[20:]) & 0xffff
+	c.snaplen = c.order.Uint32(h[16:])
+	if c.snaplen == 0 || c.snaplen > captureMaxLen {
+		c.snaplen = captureMaxLen
+	}
+	return c, nil
+}
+
+// ReadCaptureFile read all Diameter messages in pcap or pcapng file
+func ReadCaptureFile(path string) ([]CapturedMsg, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	r, e := NewCaptureReader(f)
+	if e != nil {
+		return nil, e
+	}
+	var l []CapturedMsg
+	for {
+		m, e := r.Read()
+		if e == io.EOF {
+			return l, nil
+		} else if e != nil {
+			return l, e
+		}
+		l = append(l, m)
+	}
+}
+
+// Read returns next Diameter message. It returns io.EOF at end of file.
+func (c *CaptureReader) Read() (CapturedMsg, error) {
+	for len(c.out) == 0 {
+		var e error
+		if c.ng {
+			e = c.readBlock()
+		} else {
+			e = c.readRecord()
+		}
+		if e == io.ErrUnexpectedEOF {
+			e = io.EOF
+		}
+		if e != nil {
+			return CapturedMsg{}, e
+		}
+	}
+	m := c.out[0]
+	c.out = c.out[1:]
+	return m, nil
+}
+
+func (c *CaptureReader) readRecord() error {
+	h := make([]byte, 16)
+	if _, e := io.ReadFull(c.r, h); e != nil {
+		return e
+	}
+	l := c.order.Uint32(h[8:])
+	if l > c.snaplen {
+		return fmt.Errorf("invalid pcap record length %d", l)
+	}
+	p := make([]byte, l)
+	if _, e := io.ReadFull(c.r, p); e != nil {
+		return e
+	}
+	t := time.Unix(int64(c.order.Uint32(h)), 0)
+	if c.nsec {
+		t = t.Add(time.Duration(c.order.Uint32(h[4:])))
+	} else {
+		t = t.Add(time.Duration(c.order.Uint32(h[4:])) * time.Microsecond)
+	}
+	c.packet(t, c.link, p)
+	return nil
+}
+
+func (c *CaptureReader) readBlock() error {
+	h := make([]byte, 12)
+	if _, e := io.ReadFull(c.r, h[:8]); e != nil {
+		return e
+	}
+	if bytes.Equal(h[:4], []byte{0x0a, 0x0d, 0x0d, 0x0a}) {
+		if _, e := io.ReadFull(c.r, h[8:12]); e != nil {
+			return e
+		}
+		switch {
+		case bytes.Equal(h[8:12], []byte{0x4d, 0x3c, 0x2b, 0x1a}):
+			c.order = binary.LittleEndian
+		case bytes.Equal(h[8:12], []byte{0x1a, 0x2b, 0x3c, 0x4d}):
+			c.order = binary.BigEndian
+		default:
+			return fmt.Errorf("invalid pcapng byte-order magic")
+		}
+		c.ifaces = c.ifaces[:0]
+		l := int(c.order.Uint32(h[4:]))
+		if l < 28 || l%4 != 0 {
+			return fmt.Errorf("invalid pcapng block length %d", l)
+		}
+		_, e := c.r.Discard(l - 12)
+		return e
+	}
+	if c.order == nil {
+		return fmt.Errorf("pcapng section header not found")
+	}
+	l := int(c.order.Uint32(h[4:]))
+	if l < 12 || l%4 != 0 || l > captureMaxLen {
+		return fmt.Errorf("invalid pcapng block length %d", l)
+	}
+	b := make([]byte, l-8)
+	if _, e := io.ReadFull(c.r, b); e != nil {
+		return e
+	}
+	b = b[:len(b)-4]
+
+	switch c.order.Uint32(h) {
+	case 1: // Interface Description Block
+		if len(b) < 8 {
+			return fmt.Errorf("invalid interface description block")
+		}
+		i := captureIface{
+			link: uint32(c.order.Uint16(b)),
+			res:  time.Microsecond}
+		for o := b[8:]; len(o) >= 4; {
+			code, ol := c.order.Uint16(o), int(c.order.Uint16(o[2:]))
+			if code == 0 || len(o) < 4+ol {
+				break
+			}
+			if code == 9 && ol >= 1 {
+				v := o[4]
+				if v&0x80 == 0 {
+					i.res = time.Second
+					for n := byte(0); n < v; n++ {
+						i.res /= 10
+					}
+				} else {
+					i.res = time.Second >> (v & 0x7f)
+				}
+				if i.res == 0 {
+					i.res = time.Nanosecond
+				}
+			}
+			o = o[4+ol+(4-ol%4)%4:]
+		}
+		c.ifaces = append(c.ifaces, i)
+	case 6: // Enhanced Packet Block
+		if len(b) < 20 {
+			return fmt.Errorf("invalid enhanced packet block")
+		}
+		id := int(c.order.Uint32(b))
+		if id >= len(c.ifaces) {
+			return fmt.Errorf("unknown interface %d", id)
+		}
+		ts := uint64(c.order.Uint32(b[4:]))<<32 | uint64(c.order.Uint32(b[8:]))
+		cl := int(c.order.Uint32(b[12:]))
+		if 20+cl > len(b) {
+			return fmt.Errorf("invalid enhanced packet block")
+		}
+		c.packet(time.Unix(0, 0).Add(time.Duration(ts)*c.ifaces[id].res),
+			c.ifaces[id].link, b[20:20+cl])
+	case 3: // Simple Packet Block
+		if len(c.ifaces) == 0 || len(b) < 4 {
+			return fmt.Errorf("invalid simple packet block")
+		}
+		cl := int(c.order.Uint32(b))
+		if 4+cl > len(b) {
+			cl = len(b) - 4
+		}
+		c.packet(time.Time{}, c.ifaces[0].link, b[4:4+cl])
+	}
+	return nil
+}
+
+// packet decode link layer and IP header
+func (c *CaptureReader) packet(t time.Time, link uint32, p []byte) {
+	var ethType uint16
+	switch link {
+	case 0: // BSD loopback
+		if len(p) < 4 {
+			return
+		}
+		p = p[4:]
+	case 1: // Ethernet
+		if len(p) < 14 {
+			return
+		}
+		ethType, p = binary.BigEndian.Uint16(p[12:]), p[14:]
+		for (ethType == 0x8100 || ethType == 0x88a8) && len(p) >= 4 {
+			ethType, p = binary.BigEndian.Uint16(p[2:]), p[4:]
+		}
+		if ethType != 0x0800 && ethType != 0x86dd {
+			return
+		}
+	case 101, 228, 229: // Raw IP, IPv4, IPv6
+	case 113: // Linux cooked capture
+		if len(p) < 16 {
+			return
+		}
+		p = p[16:]
+	case 276: // Linux cooked capture v2
+		if len(p) < 20 {
+			return
+		}
+		p = p[20:]
+	default:
+		return
+	}
+	if len(p) < 1 {
+		return
+	}
+
+	var src, dst netip.Addr
+	var proto byte
+	switch p[0] >> 4 {
+	case 4:
+		if len(p) < 20 {
+			return
+		}
+		hl, tl := int(p[0]&0x0f)*4, int(binary.BigEndian.Uint16(p[2:]))
+		if hl < 20 || tl < hl || len(p) < tl {
+			return
+		}
+		// fragmented packet is not supported
+		if binary.BigEndian.Uint16(p[6:])&0x3fff != 0 {
+			return
+		}
+		proto = p[9]
+		src, _ = netip.AddrFromSlice(p[12:16])
+		dst, _ = netip.AddrFromSlice(p[16:20])
+		p = p[hl:tl]
+	case 6:
+		if len(p) < 40 {
+			return
+		}
+		pl := int(binary.BigEndian.Uint16(p[4:]))
+		if len(p) < 40+pl {
+			return
+		}
+		proto = p[6]
+		src, _ = netip.AddrFromSlice(p[8:24])
+		dst, _ = netip.AddrFromSlice(p[24:40])
+		p = p[40 : 40+pl]
+		// skip Hop-by-Hop, Routing and Destination options
+		for (proto == 0 || proto == 43 || proto == 60) && len(p) >= 8 {
+			l := int(p[1])*8 + 8
+			if len(p) < l {
+				return
+			}
+			proto, p = p[0], p[l:]
+		}
+	default:
+		return
+	}
+
+	switch proto {
+	case 6:
+		c.tcp(t, src, dst, p)
+	case 132:
+		c.sctp(t, src, dst, p)
+	}
+}
+
+func (c *CaptureReader) tcp(t time.Time, sa, da netip.Addr, p []byte) {
+	if len(p) < 20 {
+		return
+	}
+	k := captureFlowKey{
+		src: netip.AddrPortFrom(sa, binary.BigEndian.Uint16(p)),
+		dst: netip.AddrPortFrom(da, binary.BigEndian.Uint16(p[2:]))}
+	seq := binary.BigEndian.Uint32(p[4:])
+	hl := int(p[12]>>4) * 4
+	flg := p[13]
+	if hl < 20 || len(p) < hl {
+		return
+	}
+	p = p[hl:]
+
+	s, ok := c.flows[k]
+	if !ok {
+		s = &captureStream{pending: make(map[uint32][]byte)}
+		c.flows[k] = s
+	}
+	if flg&0x02 != 0 { // SYN
+		s.init, s.next, s.buf = true, seq+1, nil
+		return
+	}
+	if !s.init {
+		s.init, s.next = true, seq
+	}
+
+	if d := int32(seq - s.next); d > 0 {
+		if len(p) != 0 {
+			s.pending[seq] = append([]byte{}, p...)
+		}
+	} else if -d < int32(len(p)) {
+		s.buf = append(s.buf, p[-d:]...)
+		s.next += uint32(len(p)) - uint32(-d)
+		for {
+			b, ok := s.pending[s.next]
+			if !ok {
+				break
+			}
+			delete(s.pending, s.next)
+			s.buf = append(s.buf, b...)
+			s.next += uint32(len(b))
+		}
+	}
+	s.buf = c.parse(t, k, s.buf)
+
+	if flg&0x05 != 0 { // FIN, RST
+		delete(c.flows, k)
+	}
+}
+
+func (c *CaptureReader) sctp(t time.Time, sa, da netip.Addr, p []byte) {
+	if len(p) < 12 {
+		return
+	}
+	src := netip.AddrPortFrom(sa, binary.BigEndian.Uint16(p))
+	dst := netip.AddrPortFrom(da, binary.BigEndian.Uint16(p[2:]))
+	for p = p[12:]; len(p) >= 4; {
+		typ, flg := p[0], p[1]
+		l := int(binary.BigEndian.Uint16(p[2:]))
+		if l < 4 || len(p) < l {
+			return
+		}
+		if typ == 0 && l >= 16 {
+			k := captureFlowKey{src: src, dst: dst, sctp: true,
+				stream: binary.BigEndian.Uint16(p[8:])}
+			d := p[16:l]
+			switch flg & 0x03 {
+			case 0x03: // unfragmented
+				c.parse(t, k, d)
+			case 0x02: // first fragment
+				c.flows[k] = &captureStream{buf: append([]byte{}, d...)}
+			default:
+				if s, ok := c.flows[k]; ok {
+					s.buf = append(s.buf, d...)
+					if flg&0x01 != 0 {
+						c.parse(t, k, s.buf)
+						delete(c.flows, k)
+					}
+				}
+			}
+		}
+		l += (4 - l%4) % 4
+		if l > len(p) {
+			return
+		}
+		p = p[l:]
+	}
+}
+
+// parse Diameter messages in b and returns remaining bytes
+func (c *CaptureReader) parse(t time.Time, k captureFlowKey, b []byte) []byte {
+	for len(b) >= 20 {
+		l := int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+		if b[0] != DiaVer || l < 20 || l%4 != 0 {
+			// not Diameter or lost synchronization
+			return nil
+		}
+		if len(b) < l {
+			break
+		}
+		m := CapturedMsg{Time: t, Src: k.src, Dst: k.dst, SCTP: k.sctp}
+		if _, e := m.RawMsg.ReadFrom(bytes.NewReader(b[:l])); e == nil {
+			c.out = append(c.out, m)
+		}
+		b = b[l:]
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
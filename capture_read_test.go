@@ -0,0 +1,143 @@
+package diameter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+)
+
+func testMsg(t *testing.T, hbh uint32) []byte {
+	m := dia.RawMsg{Ver: dia.DiaVer, FlgR: true, Code: 280, HbHID: hbh,
+		AVP: []dia.RawAVP{dia.SetOriginHost("host.example")}}
+	b := new(bytes.Buffer)
+	if _, e := m.WriteTo(b); e != nil {
+		t.Fatal(e)
+	}
+	return b.Bytes()
+}
+
+// tcpSegment returns raw IPv4 packet of TCP segment
+func tcpSegment(seq uint32, flg byte, p []byte) []byte {
+	b := make([]byte, 40, 40+len(p))
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:], uint16(40+len(p)))
+	b[9] = 6
+	copy(b[12:], []byte{192, 0, 2, 1, 192, 0, 2, 2})
+	binary.BigEndian.PutUint16(b[20:], 3868)
+	binary.BigEndian.PutUint16(b[22:], 50000)
+	binary.BigEndian.PutUint32(b[24:], seq)
+	b[32] = 5 << 4
+	b[33] = flg
+	return append(b, p...)
+}
+
+// pcapFile returns pcap file of raw IP packets
+func pcapFile(snaplen uint32, l ...[]byte) []byte {
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.LittleEndian, []uint32{0xa1b2c3d4, 0x00040002, 0, 0, snaplen, 101})
+	for i, p := range l {
+		binary.Write(b, binary.LittleEndian, []uint32{uint32(i), 0, uint32(len(p)), uint32(len(p))})
+		b.Write(p)
+	}
+	return b.Bytes()
+}
+
+// pcapngFile returns pcapng file of raw IP packets
+func pcapngFile(l ...[]byte) []byte {
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.LittleEndian, []uint32{0x0a0d0d0a, 28, 0x1a2b3c4d, 1, 0xffffffff, 0xffffffff, 28})
+	binary.Write(b, binary.LittleEndian, []uint32{1, 20, 101, 0, 20})
+	for _, p := range l {
+		pad := (4 - len(p)%4) % 4
+		bl := uint32(32 + len(p) + pad)
+		binary.Write(b, binary.LittleEndian, []uint32{6, bl, 0, 0, 0, uint32(len(p)), uint32(len(p))})
+		b.Write(p)
+		b.Write(make([]byte, pad))
+		binary.Write(b, binary.LittleEndian, bl)
+	}
+	return b.Bytes()
+}
+
+func TestCaptureReader(t *testing.T) {
+	m1, m2 := testMsg(t, 1), testMsg(t, 2)
+	long := make([]byte, 300*1024)
+	binary.LittleEndian.PutUint32(long, 6)
+	binary.LittleEndian.PutUint32(long[4:], uint32(len(long)))
+
+	tests := []struct {
+		name string
+		file []byte
+		want []uint32
+		err  bool
+	}{
+		{"single", pcapFile(0,
+			tcpSegment(1000, 0x18, m1)), []uint32{1}, false},
+		{"batched", pcapFile(0,
+			tcpSegment(1000, 0x18, append(append([]byte{}, m1...), m2...))), []uint32{1, 2}, false},
+		{"split", pcapFile(0,
+			tcpSegment(1000, 0x18, m1[:10]),
+			tcpSegment(1010, 0x18, m1[10:])), []uint32{1}, false},
+		{"reordered", pcapFile(0,
+			tcpSegment(1000, 0x18, m1[:10]),
+			tcpSegment(1010+uint32(len(m1)-10), 0x18, m2),
+			tcpSegment(1010, 0x18, m1[10:])), []uint32{1, 2}, false},
+		{"retransmitted", pcapFile(0,
+			tcpSegment(1000, 0x18, m1),
+			tcpSegment(1000, 0x18, m1)), []uint32{1}, false},
+		{"after syn", pcapFile(0,
+			tcpSegment(999, 0x02, nil),
+			tcpSegment(1000, 0x18, m1)), []uint32{1}, false},
+		{"not diameter", pcapFile(0,
+			tcpSegment(1000, 0x18, make([]byte, 40))), nil, false},
+		{"over snaplen", pcapFile(64,
+			tcpSegment(1000, 0x18, m1)), nil, true},
+		{"pcapng", pcapngFile(
+			tcpSegment(1000, 0x18, m1),
+			tcpSegment(1000+uint32(len(m1)), 0x18, m2)), []uint32{1, 2}, false},
+		{"pcapng too long", append(pcapngFile(), long...), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, e := dia.NewCaptureReader(bytes.NewReader(tt.file))
+			if e != nil {
+				t.Fatal(e)
+			}
+			var got []uint32
+			for {
+				m, e := r.Read()
+				if e == io.EOF {
+					break
+				} else if e != nil {
+					if !tt.err {
+						t.Fatal(e)
+					}
+					return
+				}
+				if m.Src.Port() != 3868 || m.Dst.Port() != 50000 || m.SCTP {
+					t.Errorf("flow is %v->%v", m.Src, m.Dst)
+				}
+				got = append(got, m.HbHID)
+			}
+			if tt.err {
+				t.Fatal("no error")
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("messages are %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("messages are %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCaptureReaderFormat(t *testing.T) {
+	if _, e := dia.NewCaptureReader(bytes.NewReader([]byte{0, 1, 2, 3})); e == nil {
+		t.Fatal("unknown format is accepted")
+	}
+}
@@ -0,0 +1,30 @@
+package diameter
+
+import "time"
+
+// Clock is time source of Conn.
+// Watchdog, DPA wait, answer timeout and other timers are made by Clock.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is timer that is made by Clock
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// DefaultClock is Clock of new Conn.
+// It can be replaced by fake clock for test.
+var DefaultClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
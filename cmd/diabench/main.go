@@ -0,0 +1,247 @@
+/*
+diabench send Diameter requests to a peer at fixed rate and report latency.
+
+	diabench -peer aaa://hss.example.com:3868 -host bench.example.com \
+		-f srr.json -c 4 -r 1000 -d 30s -imsi 440100000000000 -n 10000
+
+Request is made from JSON template that is expanded by text/template with
+.Host, .Realm, .PeerHost, .PeerRealm, .Seq and .IMSI.
+.IMSI is -imsi value plus .Seq, and .Seq is 0 to n-1.
+Session-Id is set to unique value for each request.
+
+Requests are sent by open-loop schedule, so slow answers don't delay
+following requests, and latency is measured from scheduled send time.
+Connections are closed by DPR after all answers are received.
+More than one connection (-c) requires the peer that accepts
+multiple connections from the same Origin-Host.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+	_ "github.com/fkgi/diameter/ts29338"
+)
+
+func main() {
+	uri := flag.String("peer", "", "peer URI")
+	host := flag.String("host", "", "local Origin-Host")
+	realm := flag.String("realm", "", "local Origin-Realm")
+	file := flag.String("f", "", "JSON template file")
+	conns := flag.Int("c", 1, "number of connections")
+	rate := flag.Float64("r", 100, "requests per second")
+	duration := flag.Duration("d", time.Second*10, "test duration")
+	timeout := flag.Duration("t", time.Second*5, "answer timeout")
+	imsi := flag.String("imsi", "001010000000000", "first IMSI")
+	vary := flag.Int("n", 1000, "number of request variations")
+	flag.Parse()
+
+	if len(*uri) == 0 || len(*host) == 0 || len(*file) == 0 ||
+		*conns <= 0 || *rate <= 0 || *vary <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var e error
+	if dia.Host, e = dia.ParseIdentity(*host); e != nil {
+		log.Fatalln("invalid host:", e)
+	}
+	if len(*realm) != 0 {
+		dia.Realm, e = dia.ParseIdentity(*realm)
+	} else if i := strings.Index(*host, "."); i >= 0 {
+		dia.Realm, e = dia.ParseIdentity((*host)[i+1:])
+	}
+	if e != nil {
+		log.Fatalln("invalid realm:", e)
+	}
+	u, e := dia.ParseURI(*uri)
+	if e != nil {
+		log.Fatalln("invalid peer URI:", e)
+	}
+	if len(u.Transport) != 0 && u.Transport != "tcp" {
+		log.Fatalln("transport", u.Transport, "is not supported")
+	}
+	if u.Port == 0 {
+		u.Port = 3868
+	}
+	peer := dia.Peer{Host: u.Fqdn, MultiConn: *conns > 1}
+	if i := strings.Index(string(u.Fqdn), "."); i >= 0 {
+		peer.Realm = u.Fqdn[i+1:]
+	}
+
+	reqs, e := loadTemplate(*file, peer, *imsi, *vary)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	cons := make([]*dia.Conn, *conns)
+	for i := range cons {
+		c, e := net.Dial("tcp", net.JoinHostPort(string(u.Fqdn), strconv.Itoa(u.Port)))
+		if e != nil {
+			log.Fatalln("failed to connect:", e)
+		}
+		if cons[i], e = dia.Dial(peer, c, *timeout); e != nil {
+			log.Fatalln("capability exchange failed:", e)
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	r := run(cons, reqs, *rate, *duration, *timeout, sig)
+
+	for _, c := range cons {
+		c.Close(*timeout)
+	}
+	r.print(os.Stdout)
+}
+
+// loadTemplate returns n requests expanded from template file
+func loadTemplate(file string, peer dia.Peer, imsi string, n int) ([]dia.RawReq, error) {
+	b, e := os.ReadFile(file)
+	if e != nil {
+		return nil, fmt.Errorf("failed to read template: %v", e)
+	}
+	t, e := texttemplate.New(file).Parse(string(b))
+	if e != nil {
+		return nil, fmt.Errorf("invalid template: %v", e)
+	}
+	first, ok := new(big.Int).SetString(imsi, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid IMSI %s", imsi)
+	}
+
+	reqs := make([]dia.RawReq, n)
+	buf := new(bytes.Buffer)
+	for i := range reqs {
+		id := new(big.Int).Add(first, big.NewInt(int64(i))).String()
+		if len(id) < len(imsi) {
+			id = strings.Repeat("0", len(imsi)-len(id)) + id
+		}
+		buf.Reset()
+		if e = t.Execute(buf, map[string]interface{}{
+			"Host": dia.Host, "Realm": dia.Realm,
+			"PeerHost": peer.Host, "PeerRealm": peer.Realm,
+			"Seq": i, "IMSI": id}); e != nil {
+			return nil, fmt.Errorf("invalid template: %v", e)
+		}
+		if e = json.Unmarshal(buf.Bytes(), &reqs[i].RawMsg); e != nil {
+			return nil, fmt.Errorf("invalid JSON message: %v", e)
+		}
+	}
+
+	var vid uint32
+	for _, a := range reqs[0].AVP {
+		if a.Code == 260 && a.VenID == 0 {
+			vid, _, _ = dia.GetVendorSpecAppID(a)
+		}
+	}
+	dia.AddSupportedMessage(vid, reqs[0].AppID, reqs[0].Code, dia.RawReq{}, dia.RawAns{})
+	return reqs, nil
+}
+
+type report struct {
+	sync.Mutex
+	start, end time.Time
+	sent       int
+	latency    []time.Duration
+	results    map[dia.Result]int
+}
+
+// run sends requests until d is passed or stop is signaled.
+// Request i is scheduled at start + i/rate regardless of answers.
+func run(cons []*dia.Conn, reqs []dia.RawReq, rate float64,
+	d, timeout time.Duration, stop <-chan os.Signal) *report {
+	r := &report{results: make(map[dia.Result]int)}
+	var wg sync.WaitGroup
+	interval := time.Duration(float64(time.Second) / rate)
+	r.start = time.Now()
+	end := r.start.Add(d)
+
+	tm := time.NewTimer(0)
+	defer tm.Stop()
+	for i := 0; ; i++ {
+		at := r.start.Add(interval * time.Duration(i))
+		if !at.Before(end) {
+			break
+		}
+		tm.Reset(time.Until(at))
+		select {
+		case <-stop:
+			end = time.Now()
+		case <-tm.C:
+		}
+		if !at.Before(end) {
+			break
+		}
+
+		r.sent++
+		wg.Add(1)
+		go func(c *dia.Conn, req dia.RawReq, at time.Time) {
+			defer wg.Done()
+			res := c.Send(req, timeout).Result()
+			l := time.Since(at)
+			r.Lock()
+			r.latency = append(r.latency, l)
+			r.results[res]++
+			r.Unlock()
+		}(cons[i%len(cons)], reqs[i%len(reqs)], at)
+	}
+	wg.Wait()
+	r.end = time.Now()
+	return r
+}
+
+func (r *report) print(w io.Writer) {
+	elapsed := r.end.Sub(r.start)
+	fmt.Fprintf(w, "Duration:   %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Requests:   %d\n", r.sent)
+	fmt.Fprintf(w, "Throughput: %.1f answers/s\n",
+		float64(len(r.latency))/elapsed.Seconds())
+
+	if len(r.latency) != 0 {
+		sort.Slice(r.latency, func(i, j int) bool {
+			return r.latency[i] < r.latency[j]
+		})
+		p := func(q float64) time.Duration {
+			i := int(q * float64(len(r.latency)))
+			if i >= len(r.latency) {
+				i = len(r.latency) - 1
+			}
+			return r.latency[i]
+		}
+		fmt.Fprintln(w, "Latency:")
+		fmt.Fprintf(w, "  min   %s\n", r.latency[0])
+		fmt.Fprintf(w, "  p50   %s\n", p(0.5))
+		fmt.Fprintf(w, "  p99   %s\n", p(0.99))
+		fmt.Fprintf(w, "  p999  %s\n", p(0.999))
+		fmt.Fprintf(w, "  max   %s\n", r.latency[len(r.latency)-1])
+	}
+
+	res := make([]dia.Result, 0, len(r.results))
+	for k := range r.results {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return r.results[res[i]] > r.results[res[j]]
+	})
+	fmt.Fprintln(w, "Results:")
+	for _, k := range res {
+		fmt.Fprintf(w, "  %-40s %d\n", k, r.results[k])
+	}
+}
@@ -0,0 +1,256 @@
+/*
+diactl send a Diameter request to a peer and print the answer.
+
+	diactl -peer aaa://hss.example.com:3868 -host diactl.example.com <command> [flags]
+
+Commands are
+
+	send -f template.json          send request from JSON template
+	srr  -msisdn N -sc N           Send-Routing-Info-for-SM-Request
+	alr  -msisdn N -imsi N -sc N   Alert-Service-Centre-Request
+	rdr  -msisdn N -imsi N -sc N   Report-SM-Delivery-Status-Request
+	tfr  -imsi N -sc N -pdu HEX    MT-Forward-Short-Message-Request
+	ofr  -msisdn N -sc N -pdu HEX  MO-Forward-Short-Message-Request
+
+JSON template is expanded by text/template with
+.Host, .Realm, .PeerHost and .PeerRealm before decoding.
+Exit status is 0 for 2xxx result, 3, 4 or 5 for 3xxx, 4xxx or 5xxx result,
+1 for other failure and 2 for invalid argument.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+	"github.com/fkgi/diameter/ts29338"
+	"github.com/fkgi/teldata"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [flags] send|srr|alr|rdr|tfr|ofr [command flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	uri := flag.String("peer", "", "peer URI")
+	host := flag.String("host", "", "local Origin-Host")
+	realm := flag.String("realm", "", "local Origin-Realm")
+	dhost := flag.String("dest-host", "", "Destination-Host (default peer host)")
+	drealm := flag.String("dest-realm", "", "Destination-Realm (default peer realm)")
+	timeout := flag.Duration("t", time.Second*5, "answer timeout")
+	flag.Parse()
+
+	if len(*uri) == 0 || len(*host) == 0 || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var e error
+	if dia.Host, e = dia.ParseIdentity(*host); e != nil {
+		usage("invalid host:", e)
+	}
+	if len(*realm) != 0 {
+		dia.Realm, e = dia.ParseIdentity(*realm)
+	} else if i := strings.Index(*host, "."); i >= 0 {
+		dia.Realm, e = dia.ParseIdentity((*host)[i+1:])
+	}
+	if e != nil {
+		usage("invalid realm:", e)
+	}
+	u, e := dia.ParseURI(*uri)
+	if e != nil {
+		usage("invalid peer URI:", e)
+	}
+	if len(u.Transport) != 0 && u.Transport != "tcp" {
+		usage("transport", u.Transport, "is not supported")
+	}
+	if u.Port == 0 {
+		u.Port = 3868
+	}
+
+	peer := dia.Peer{Host: u.Fqdn}
+	if len(*dhost) != 0 {
+		if peer.Host, e = dia.ParseIdentity(*dhost); e != nil {
+			usage("invalid destination host:", e)
+		}
+	}
+	if len(*drealm) != 0 {
+		if peer.Realm, e = dia.ParseIdentity(*drealm); e != nil {
+			usage("invalid destination realm:", e)
+		}
+	} else if i := strings.Index(string(peer.Host), "."); i >= 0 {
+		peer.Realm = peer.Host[i+1:]
+	}
+
+	req := build(flag.Arg(0), flag.Args()[1:], peer)
+
+	c, e := net.Dial("tcp", net.JoinHostPort(string(u.Fqdn), strconv.Itoa(u.Port)))
+	if e != nil {
+		log.Println("failed to connect:", e)
+		os.Exit(1)
+	}
+	con, e := dia.Dial(dia.Peer{Host: u.Fqdn}, c, *timeout)
+	if e != nil {
+		log.Println("capability exchange failed:", e)
+		os.Exit(1)
+	}
+
+	ans := con.Send(req, *timeout)
+	con.Close(*timeout)
+
+	fmt.Print(ans)
+	r := ans.Result()
+	fmt.Println("Result:", r)
+	switch r.Class() {
+	case dia.Success:
+		os.Exit(0)
+	case dia.ProtocolError:
+		os.Exit(3)
+	case dia.TransientFailure:
+		os.Exit(4)
+	case dia.PermanentFailure:
+		os.Exit(5)
+	}
+	os.Exit(1)
+}
+
+func usage(v ...interface{}) {
+	log.Println(v...)
+	os.Exit(2)
+}
+
+func build(cmd string, args []string, peer dia.Peer) dia.Request {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	file := fs.String("f", "", "JSON template file, - for stdin")
+	msisdn := fs.String("msisdn", "", "MSISDN")
+	imsi := fs.String("imsi", "", "IMSI")
+	sc := fs.String("sc", "", "SC-Address")
+	pdu := fs.String("pdu", "", "hex encoded SMS TPDU")
+	fs.Parse(args)
+
+	e164 := func(s string) teldata.E164 {
+		if len(s) == 0 {
+			return nil
+		}
+		v, e := teldata.ParseE164(s)
+		if e != nil {
+			usage("invalid E.164 number", s, ":", e)
+		}
+		return v
+	}
+	id := func() teldata.IMSI {
+		if len(*imsi) == 0 {
+			return teldata.IMSI("")
+		}
+		v, e := teldata.ParseIMSI(*imsi)
+		if e != nil {
+			usage("invalid IMSI", *imsi, ":", e)
+		}
+		return v
+	}
+	tpdu := func() []byte {
+		b, e := hex.DecodeString(*pdu)
+		if e != nil || len(b) == 0 {
+			usage("invalid TPDU", *pdu)
+		}
+		return b
+	}
+
+	switch cmd {
+	case "send":
+		return fromTemplate(*file, peer)
+	case "srr":
+		dia.AddSupportedMessage(10415, 16777312, 8388647, ts29338.SRR{}, ts29338.SRA{})
+		return ts29338.SRR{
+			OriginHost: dia.Host, OriginRealm: dia.Realm,
+			DestinationHost: peer.Host, DestinationRealm: peer.Realm,
+			MSISDN: e164(*msisdn), IMSI: id(), SCAddress: e164(*sc)}
+	case "alr":
+		dia.AddSupportedMessage(10415, 16777312, 8388648, ts29338.ALR{}, ts29338.ALA{})
+		return ts29338.ALR{
+			OriginHost: dia.Host, OriginRealm: dia.Realm,
+			DestinationHost: peer.Host, DestinationRealm: peer.Realm,
+			MSISDN: e164(*msisdn), IMSI: id(), SCAddress: e164(*sc)}
+	case "rdr":
+		dia.AddSupportedMessage(10415, 16777312, 8388649, ts29338.RDR{}, ts29338.RDA{})
+		return ts29338.RDR{
+			OriginHost: dia.Host, OriginRealm: dia.Realm,
+			DestinationHost: peer.Host, DestinationRealm: peer.Realm,
+			MSISDN: e164(*msisdn), IMSI: id(), SCAddress: e164(*sc)}
+	case "tfr":
+		dia.AddSupportedMessage(10415, 16777313, 8388646, ts29338.TFR{}, ts29338.TFA{})
+		v := ts29338.TFR{
+			OriginHost: dia.Host, OriginRealm: dia.Realm,
+			DestinationHost: peer.Host, DestinationRealm: peer.Realm,
+			IMSI: id(), SCAddress: e164(*sc)}
+		if e := v.SMSPDU.Decode(tpdu()); e != nil {
+			usage("invalid SMS-DELIVER:", e)
+		}
+		return v
+	case "ofr":
+		dia.AddSupportedMessage(10415, 16777313, 8388645, ts29338.OFR{}, ts29338.OFA{})
+		v := ts29338.OFR{
+			OriginHost: dia.Host, OriginRealm: dia.Realm,
+			DestinationHost: peer.Host, DestinationRealm: peer.Realm,
+			MSISDN: e164(*msisdn), IMSI: id(), SCAddress: e164(*sc)}
+		if e := v.SMSPDU.Decode(tpdu()); e != nil {
+			usage("invalid SMS-SUBMIT:", e)
+		}
+		return v
+	}
+	usage("unknown command", cmd)
+	return nil
+}
+
+func fromTemplate(file string, peer dia.Peer) dia.Request {
+	var b []byte
+	var e error
+	switch file {
+	case "":
+		usage("template file is required")
+	case "-":
+		b, e = io.ReadAll(os.Stdin)
+	default:
+		b, e = os.ReadFile(file)
+	}
+	if e != nil {
+		usage("failed to read template:", e)
+	}
+
+	t, e := texttemplate.New(file).Parse(string(b))
+	if e != nil {
+		usage("invalid template:", e)
+	}
+	buf := new(bytes.Buffer)
+	if e = t.Execute(buf, map[string]dia.Identity{
+		"Host": dia.Host, "Realm": dia.Realm,
+		"PeerHost": peer.Host, "PeerRealm": peer.Realm}); e != nil {
+		usage("invalid template:", e)
+	}
+
+	var m dia.RawMsg
+	if e = json.Unmarshal(buf.Bytes(), &m); e != nil {
+		usage("invalid JSON message:", e)
+	}
+	var vid uint32
+	for _, a := range m.AVP {
+		if a.Code == 260 {
+			vid, _, _ = dia.GetVendorSpecAppID(a)
+		}
+	}
+	dia.AddSupportedMessage(vid, m.AppID, m.Code, dia.RawReq{}, dia.RawAns{})
+	return dia.RawReq{RawMsg: m}
+}
@@ -0,0 +1,160 @@
+/*
+diareplay replay Diameter requests in pcap or pcapng file to a peer,
+and compare received answers with captured answers.
+
+	diareplay -r capture.pcap -peer aaa://hss.example.com:3868 \
+		-host diareplay.example.com -realm example.com
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// AVPs that always differ between capture and replay
+var ignore = []uint32{
+	263, // Session-Id
+	264, // Origin-Host
+	278, // Origin-State-Id
+	282, // Route-Record
+	284, // Proxy-Info
+	296, // Origin-Realm
+}
+
+func main() {
+	file := flag.String("r", "", "input pcap or pcapng file")
+	uri := flag.String("peer", "", "peer URI")
+	host := flag.String("host", "", "local Origin-Host")
+	realm := flag.String("realm", "", "local Origin-Realm")
+	timeout := flag.Duration("t", time.Second*5, "answer timeout")
+	app := flag.Int64("app", -1, "replay only this Application-ID")
+	flag.Parse()
+
+	if len(*file) == 0 || len(*uri) == 0 || len(*host) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var e error
+	if dia.Host, e = dia.ParseIdentity(*host); e != nil {
+		log.Fatalln("invalid host:", e)
+	}
+	if len(*realm) != 0 {
+		dia.Realm, e = dia.ParseIdentity(*realm)
+	} else if i := strings.Index(*host, "."); i >= 0 {
+		dia.Realm, e = dia.ParseIdentity((*host)[i+1:])
+	}
+	if e != nil {
+		log.Fatalln("invalid realm:", e)
+	}
+
+	u, e := dia.ParseURI(*uri)
+	if e != nil {
+		log.Fatalln("invalid peer URI:", e)
+	}
+	if len(u.Transport) != 0 && u.Transport != "tcp" {
+		log.Fatalln("transport", u.Transport, "is not supported")
+	}
+	if u.Port == 0 {
+		u.Port = 3868
+	}
+
+	msgs, e := dia.ReadCaptureFile(*file)
+	if e != nil && len(msgs) == 0 {
+		log.Fatalln("failed to read capture:", e)
+	} else if e != nil {
+		log.Println("capture is truncated:", e)
+	}
+
+	var reqs []dia.CapturedMsg
+	ans := make(map[[2]uint32]dia.RawMsg)
+	for _, m := range msgs {
+		if m.AppID == 0 && (m.Code == 257 || m.Code == 280 || m.Code == 282) {
+			continue
+		}
+		if !m.FlgR {
+			ans[[2]uint32{m.HbHID, m.EtEID}] = m.RawMsg
+		} else if *app < 0 || uint32(*app) == m.AppID {
+			reqs = append(reqs, m)
+		}
+	}
+	if len(reqs) == 0 {
+		log.Fatalln("no request in capture")
+	}
+	for _, m := range reqs {
+		var vid uint32
+		for _, a := range m.AVP {
+			if a.Code == 260 {
+				vid, _, _ = dia.GetVendorSpecAppID(a)
+			}
+		}
+		dia.AddSupportedMessage(vid, m.AppID, m.Code, dia.RawReq{}, dia.RawAns{})
+	}
+
+	c, e := net.Dial("tcp", net.JoinHostPort(string(u.Fqdn), strconv.Itoa(u.Port)))
+	if e != nil {
+		log.Fatalln("failed to connect:", e)
+	}
+	con, e := dia.Dial(dia.Peer{Host: u.Fqdn}, c, *timeout)
+	if e != nil {
+		log.Fatalln("capability exchange failed:", e)
+	}
+	peer := *con.Peer
+	rewrite := func(m dia.RawMsg) dia.RawMsg {
+		m = m.Clone()
+		m.FlgT = false
+		for i, a := range m.AVP {
+			switch a.Code {
+			case 293:
+				m.AVP[i] = dia.SetDestinationHost(peer.Host)
+			case 283:
+				m.AVP[i] = dia.SetDestinationRealm(peer.Realm)
+			}
+		}
+		return m
+	}
+
+	ndiff := 0
+	for i, m := range reqs {
+		r := con.Send(dia.RawReq{RawMsg: rewrite(m.RawMsg)}, *timeout)
+		fmt.Printf("#%d %s app=%d cmd=%d: result=%s",
+			i, m.Time.Format(time.RFC3339Nano), m.AppID, m.Code, r.Result())
+		a, ok := r.(dia.RawAns)
+		if !ok {
+			ndiff++
+			fmt.Printf(", unexpected answer type %T\n", r)
+			continue
+		}
+
+		ca, ok := ans[[2]uint32{m.HbHID, m.EtEID}]
+		if !ok {
+			fmt.Println(", no captured answer")
+			continue
+		}
+		d := dia.DiffAVP(ca.AVP, a.AVP, ignore...)
+		if len(d) == 0 {
+			fmt.Println(", same")
+			continue
+		}
+		ndiff++
+		fmt.Printf(", %d differences (A=captured, B=replayed)\n", len(d))
+		for _, v := range d {
+			fmt.Println("  ", v)
+		}
+	}
+
+	con.Close(*timeout)
+	fmt.Printf("%d requests replayed, %d answers differ\n", len(reqs), ndiff)
+	if ndiff != 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+diasim is fake Diameter peer that answers requests by rules file.
+
+	diasim -l :3868 -host sim.example.com -c rules.json
+
+Rules file is JSON text like
+
+	{
+	  "behavior": {"ignore_dwr": false, "refuse_cer": 0, "dpr_after": 0,
+	               "multi_conn": true},
+	  "rules": [
+	    {"application": 16777312, "command": 8388647,
+	     "match": [{"name": "User-Name", "value": "440101234567890"}],
+	     "result": {"vendor": 10415, "code": 5001}},
+	    {"application": 16777312, "delay": "200ms",
+	     "result": {"code": 2001},
+	     "avp": [{"name": "Error-Message", "value": "simulated"}]}
+	  ]
+	}
+
+Request that matches no rule is answered with DIAMETER_UNABLE_TO_COMPLY.
+*/
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"strings"
+
+	dia "github.com/fkgi/diameter"
+	"github.com/fkgi/diameter/diasim"
+	_ "github.com/fkgi/diameter/ts29338"
+)
+
+func main() {
+	addr := flag.String("l", ":3868", "listen address")
+	host := flag.String("host", "", "local Origin-Host")
+	realm := flag.String("realm", "", "local Origin-Realm")
+	conf := flag.String("c", "", "rules file")
+	flag.Parse()
+
+	if len(*host) == 0 || len(*conf) == 0 {
+		flag.Usage()
+		return
+	}
+	var e error
+	if dia.Host, e = dia.ParseIdentity(*host); e != nil {
+		log.Fatalln("invalid host:", e)
+	}
+	if len(*realm) != 0 {
+		dia.Realm, e = dia.ParseIdentity(*realm)
+	} else if i := strings.Index(*host, "."); i >= 0 {
+		dia.Realm, e = dia.ParseIdentity((*host)[i+1:])
+	}
+	if e != nil {
+		log.Fatalln("invalid realm:", e)
+	}
+
+	c, e := diasim.LoadConfig(*conf)
+	if e != nil {
+		log.Fatalln("failed to load rules:", e)
+	}
+
+	l, e := net.Listen("tcp", *addr)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	log.Println("listening on", l.Addr())
+	log.Fatalln(diasim.New(c).Serve(l))
+}
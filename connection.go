@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,18 +24,62 @@ var (
 type Conn struct {
 	*Peer
 
-	wdTimer *time.Timer // system message timer
-	wdCount int         // watchdog expired counter
+	clock   Clock
+	wdTimer Timer // system message timer
+	wdCount int   // watchdog expired counter
+
+	notify    chan stateEvent
+	done      chan struct{}
+	st        atomic.Int32
+	initiator bool
+	pair      *Conn  // other Conn of the peer in election
+	cea       RawMsg // CEA that will be sent after election
+	cerID     uint32 // Hop-by-Hop ID of sent CER
+	ready     chan error
+
+	con       net.Conn
+	sndstack  map[uint32]chan pendingAns // nil after Peer-Disc
+	sndmu     sync.Mutex                 // lock of sndstack
+	wq        *msgQueue                  // write queue ordered by DRMP
+	wdone     chan struct{}              // closed when writer is stopped
+	rcvstack  *msgQueue                  // received requests ordered by DRMP
+	sndqueue  *msgQueue                  // sending messages ordered by DRMP
+	dups      sync.Map                   // received time of duplicate request by Hop-by-Hop ID
+	load      atomic.Uint64              // PEER type load that is received from the peer
+	peerState atomic.Uint32              // Origin-State-Id that is received from the peer
+	capture   atomic.Pointer[Capture]
+	hiding    atomic.Pointer[TopologyHiding]
+
+	Since time.Time
+	stats connStats
+}
 
-	notify chan stateEvent
-	state
-	con      net.Conn
-	sndstack map[uint32]chan RawMsg
-	rcvstack chan RawMsg
+type connStats struct {
+	RxReq        atomic.Uint64
+	Reject       atomic.Uint64
+	Duplicate    atomic.Uint64
+	Tx1xxx       atomic.Uint64
+	Tx2xxx       atomic.Uint64
+	Tx3xxx       atomic.Uint64
+	Tx4xxx       atomic.Uint64
+	Tx5xxx       atomic.Uint64
+	TxEtc        atomic.Uint64
+	TxReq        atomic.Uint64
+	TxReqFail    atomic.Uint64
+	TxReqTimeout atomic.Uint64
+	Rx1xxx       atomic.Uint64
+	Rx2xxx       atomic.Uint64
+	Rx3xxx       atomic.Uint64
+	Rx4xxx       atomic.Uint64
+	Rx5xxx       atomic.Uint64
+	RxEtc        atomic.Uint64
+}
 
-	Since        time.Time
+// ConnStats is snapshot of message counters of Conn
+type ConnStats struct {
 	RxReq        uint64
 	Reject       uint64
+	Duplicate    uint64
 	Tx1xxx       uint64
 	Tx2xxx       uint64
 	Tx3xxx       uint64
@@ -51,47 +97,121 @@ type Conn struct {
 	RxEtc        uint64
 }
 
+// Stats returns snapshot of message counters
+func (c *Conn) Stats() ConnStats {
+	return ConnStats{
+		RxReq:        c.stats.RxReq.Load(),
+		Reject:       c.stats.Reject.Load(),
+		Duplicate:    c.stats.Duplicate.Load(),
+		Tx1xxx:       c.stats.Tx1xxx.Load(),
+		Tx2xxx:       c.stats.Tx2xxx.Load(),
+		Tx3xxx:       c.stats.Tx3xxx.Load(),
+		Tx4xxx:       c.stats.Tx4xxx.Load(),
+		Tx5xxx:       c.stats.Tx5xxx.Load(),
+		TxEtc:        c.stats.TxEtc.Load(),
+		TxReq:        c.stats.TxReq.Load(),
+		TxReqFail:    c.stats.TxReqFail.Load(),
+		TxReqTimeout: c.stats.TxReqTimeout.Load(),
+		Rx1xxx:       c.stats.Rx1xxx.Load(),
+		Rx2xxx:       c.stats.Rx2xxx.Load(),
+		Rx3xxx:       c.stats.Rx3xxx.Load(),
+		Rx4xxx:       c.stats.Rx4xxx.Load(),
+		Rx5xxx:       c.stats.Rx5xxx.Load(),
+		RxEtc:        c.stats.RxEtc.Load()}
+}
+
 func (c *Conn) String() string {
 	w := new(bytes.Buffer)
+	st := c.Stats()
 
 	fmt.Fprintf(w, "%sPeer                =%s\n", Indent, c.Peer)
 	fmt.Fprintf(w, "%sStatus              =%s\n", Indent, c.State())
 	fmt.Fprintf(w, "%sUptime              =%s\n",
-		Indent, time.Now().Sub(c.Since).String())
-	fmt.Fprintf(w, "%sRx Request count    =%d\n", Indent, c.RxReq)
-	fmt.Fprintf(w, "%s%sReject count  =%d\n", Indent, Indent, c.Reject)
-	fmt.Fprintf(w, "%s%sTx 1xxx count =%d\n", Indent, Indent, c.Tx1xxx)
-	fmt.Fprintf(w, "%s%sTx 2xxx count =%d\n", Indent, Indent, c.Tx2xxx)
-	fmt.Fprintf(w, "%s%sTx 3xxx count =%d\n", Indent, Indent, c.Tx3xxx)
-	fmt.Fprintf(w, "%s%sTx 4xxx count =%d\n", Indent, Indent, c.Tx4xxx)
-	fmt.Fprintf(w, "%s%sTx 5xxx count =%d\n", Indent, Indent, c.Tx5xxx)
-	fmt.Fprintf(w, "%s%sTx etc count  =%d\n", Indent, Indent, c.TxEtc)
-	fmt.Fprintf(w, "%sTx Request count    =%d\n", Indent, c.TxReq)
-	fmt.Fprintf(w, "%s%sFailed count  =%d\n", Indent, Indent, c.TxReqFail)
-	fmt.Fprintf(w, "%s%sTimeout count =%d\n", Indent, Indent, c.TxReqTimeout)
-	fmt.Fprintf(w, "%s%sRx 1xxx count =%d\n", Indent, Indent, c.Rx1xxx)
-	fmt.Fprintf(w, "%s%sRx 2xxx count =%d\n", Indent, Indent, c.Rx2xxx)
-	fmt.Fprintf(w, "%s%sRx 3xxx count =%d\n", Indent, Indent, c.Rx3xxx)
-	fmt.Fprintf(w, "%s%sRx 4xxx count =%d\n", Indent, Indent, c.Rx4xxx)
-	fmt.Fprintf(w, "%s%sRx 5xxx count =%d\n", Indent, Indent, c.Rx5xxx)
-	fmt.Fprintf(w, "%s%sRx etc count  =%d\n", Indent, Indent, c.RxEtc)
+		Indent, c.clock.Now().Sub(c.Since).String())
+	fmt.Fprintf(w, "%sRx Request count    =%d\n", Indent, st.RxReq)
+	fmt.Fprintf(w, "%s%sReject count  =%d\n", Indent, Indent, st.Reject)
+	fmt.Fprintf(w, "%s%sDuplicate     =%d\n", Indent, Indent, st.Duplicate)
+	fmt.Fprintf(w, "%s%sTx 1xxx count =%d\n", Indent, Indent, st.Tx1xxx)
+	fmt.Fprintf(w, "%s%sTx 2xxx count =%d\n", Indent, Indent, st.Tx2xxx)
+	fmt.Fprintf(w, "%s%sTx 3xxx count =%d\n", Indent, Indent, st.Tx3xxx)
+	fmt.Fprintf(w, "%s%sTx 4xxx count =%d\n", Indent, Indent, st.Tx4xxx)
+	fmt.Fprintf(w, "%s%sTx 5xxx count =%d\n", Indent, Indent, st.Tx5xxx)
+	fmt.Fprintf(w, "%s%sTx etc count  =%d\n", Indent, Indent, st.TxEtc)
+	fmt.Fprintf(w, "%sTx Request count    =%d\n", Indent, st.TxReq)
+	fmt.Fprintf(w, "%s%sFailed count  =%d\n", Indent, Indent, st.TxReqFail)
+	fmt.Fprintf(w, "%s%sTimeout count =%d\n", Indent, Indent, st.TxReqTimeout)
+	fmt.Fprintf(w, "%s%sRx 1xxx count =%d\n", Indent, Indent, st.Rx1xxx)
+	fmt.Fprintf(w, "%s%sRx 2xxx count =%d\n", Indent, Indent, st.Rx2xxx)
+	fmt.Fprintf(w, "%s%sRx 3xxx count =%d\n", Indent, Indent, st.Rx3xxx)
+	fmt.Fprintf(w, "%s%sRx 4xxx count =%d\n", Indent, Indent, st.Rx4xxx)
+	fmt.Fprintf(w, "%s%sRx 5xxx count =%d\n", Indent, Indent, st.Rx5xxx)
+	fmt.Fprintf(w, "%s%sRx etc count  =%d\n", Indent, Indent, st.RxEtc)
 	fmt.Fprintf(w, "%sRx queue length     =%d\n", Indent, c.RxQueue())
 	fmt.Fprintf(w, "%sTx queue length     =%d\n", Indent, c.TxQueue())
 
 	return w.String()
 }
 
+func (c *Conn) countTx(r Result) {
+	switch r.Class() {
+	case Informational:
+		c.stats.Tx1xxx.Add(1)
+	case Success:
+		c.stats.Tx2xxx.Add(1)
+	case ProtocolError:
+		c.stats.Tx3xxx.Add(1)
+	case TransientFailure:
+		c.stats.Tx4xxx.Add(1)
+	case PermanentFailure:
+		c.stats.Tx5xxx.Add(1)
+	default:
+		c.stats.TxEtc.Add(1)
+	}
+}
+
+func (c *Conn) countRx(r Result) {
+	switch r.Class() {
+	case Informational:
+		c.stats.Rx1xxx.Add(1)
+	case Success:
+		c.stats.Rx2xxx.Add(1)
+	case ProtocolError:
+		c.stats.Rx3xxx.Add(1)
+	case TransientFailure:
+		c.stats.Rx4xxx.Add(1)
+	case PermanentFailure:
+		c.stats.Rx5xxx.Add(1)
+	default:
+		c.stats.RxEtc.Add(1)
+	}
+}
+
+// SetCapture set Capture that write messages of this Conn.
+// nil stop capturing.
+func (c *Conn) SetCapture(cp *Capture) {
+	if old := c.capture.Swap(cp); old != nil && old != cp {
+		old.forget(c)
+	}
+}
+
 // RxQueue returns length of Rx queue
 func (c *Conn) RxQueue() int {
-	return len(c.rcvstack)
+	return c.rcvstack.len()
 }
 
-// TxQueue returns length of Tx queue
+// TxQueue returns length of Tx queue, that is messages
+// waiting Snd-MSG event and messages in write queue
 func (c *Conn) TxQueue() int {
-	return len(c.sndstack)
+	return c.sndqueue.len() + c.wq.len()
 }
 
-// Dial make new Conn that use specified peernode and connection
+// Dial make new Conn that use specified peernode and connection.
+// Error is ElectionLost if the connection is closed by election
+// with responder connection from the same peer.
+// The peer rejects second Conn from local host unless it allows
+// multiple connections, and CER from the peer in open state of
+// the Conn is rejected unless MultiConn or p.MultiConn is true.
+// It returns error after the failed Conn is stopped.
 func Dial(p Peer, c net.Conn, d time.Duration) (*Conn, error) {
 	if c == nil {
 		return nil, ConnectionRefused{}
@@ -114,81 +234,91 @@ func Dial(p Peer, c net.Conn, d time.Duration) (*Conn, error) {
 		p.WDInterval = WDInterval
 	}
 
-	con := &Conn{
-		Peer:     &p,
-		notify:   make(chan stateEvent),
-		state:    closed,
-		con:      c,
-		sndstack: make(map[uint32]chan RawMsg, TxBuffer),
-		rcvstack: make(chan RawMsg, RxBuffer)}
+	con := newConn(&p, c)
+	con.initiator = true
 	go socketHandler(con)
-	Notify(StateUpdate{
-		oldStat: shutdown, newStat: con.state,
-		stateEvent: eventInit{}, conn: con, Err: nil})
+	go writeHandler(con)
+	publish(newStateUpdate(shutdown, eventInit{}, con, nil))
 	go eventHandler(con)
 
-	cer := MakeCER(con)
-	req := cer.ToRaw("")
+	req := MakeCER(con).ToRaw("")
 	req.HbHID = nextHbH()
 	req.EtEID = nextEtE()
 
-	ch := make(chan RawMsg)
-	con.sndstack[req.HbHID] = ch
-	con.notify <- eventConnect{m: req}
-
-	t := time.AfterFunc(d, func() {
-		m := cer.Failed(DiameterTooBusy).ToRaw("")
-		m.HbHID = req.HbHID
-		m.EtEID = req.EtEID
-		con.notify <- eventRcvCEA{m}
+	con.raise(eventStart{})
+	con.raise(eventConnAck{m: req})
+	t := con.clock.AfterFunc(d, func() {
+		con.raise(eventTimeout{})
 	})
 
-	ack := <-ch
+	e := <-con.ready
 	t.Stop()
-
-	if ack.Code == 0 {
-		return nil, ConnectionRefused{}
+	if e != nil {
+		<-con.done
+		return nil, e
 	}
+	addStatsConn(con)
 	return con, nil
 }
 
-// Accept new transport connection and return Conn
+// Accept new transport connection and return Conn.
+// CER from the peer that already has open Conn is rejected
+// unless MultiConn or MultiConn of p or the open Conn's Peer is true.
+// It returns error after the failed Conn is stopped.
 func Accept(p *Peer, c net.Conn) (*Conn, error) {
 	if c == nil {
 		return nil, ConnectionRefused{}
 	}
-	con := &Conn{
-		Peer:     p,
-		notify:   make(chan stateEvent),
-		state:    waitCER,
-		con:      c,
-		sndstack: make(map[uint32]chan RawMsg, TxBuffer),
-		rcvstack: make(chan RawMsg, RxBuffer)}
+	con := newConn(p, c)
 	go socketHandler(con)
+	go writeHandler(con)
+	publish(newStateUpdate(shutdown, eventInit{}, con, nil))
+	go eventHandler(con)
 
-	Notify(StateUpdate{
-		oldStat: shutdown, newStat: con.state,
-		stateEvent: eventInit{}, conn: con, Err: nil})
-
-	event := <-con.notify
-	old := con.state
-	e := event.exec(con)
-	Notify(StateUpdate{
-		oldStat: old, newStat: con.state,
-		stateEvent: event, conn: con, Err: e})
+	e := <-con.ready
 	if e != nil {
-		c.Close()
+		<-con.done
+		return con, e
 	}
-	go eventHandler(con)
+	addStatsConn(con)
+	return con, nil
+}
 
-	return con, e
+func newConn(p *Peer, c net.Conn) *Conn {
+	con := &Conn{
+		Peer:     p,
+		notify:   make(chan stateEvent),
+		done:     make(chan struct{}),
+		ready:    make(chan error, 1),
+		clock:    DefaultClock,
+		con:      c,
+		sndstack: make(map[uint32]chan pendingAns, TxBuffer),
+		wq:       newMsgQueue(TxBuffer),
+		wdone:    make(chan struct{}),
+		rcvstack: newMsgQueue(RxBuffer),
+		sndqueue: newMsgQueue(0)}
+	con.st.Store(int32(closed))
+	con.capture.Store(DefaultCapture)
+	return con
 }
 
 func socketHandler(c *Conn) {
 	for {
 		m := RawMsg{}
 		c.con.SetReadDeadline(time.Time{})
-		if _, e := m.ReadFrom(c.con); e != nil {
+		if _, e := m.ReadFrom(c.con); e == nil {
+			countMsg(c, m, false)
+			if cp := c.capture.Load(); cp != nil {
+				cp.capture(c, Rx, m)
+			}
+			c.rcvStateID(m)
+		} else if _, ok := e.(InvalidAVP); ok {
+			c.notify <- eventRcvErr{m: m, e: e}
+			continue
+		} else if _, ok := e.(InvalidMessage); ok {
+			c.notify <- eventRcvErr{m: m, e: e}
+			break
+		} else {
 			break
 		}
 
@@ -212,16 +342,29 @@ func socketHandler(c *Conn) {
 }
 
 func eventHandler(c *Conn) {
+	defer close(c.done)
+	defer func() { <-c.wdone }()
+	defer c.wq.close()
 	for {
 		event := <-c.notify
-		old := c.state
+		_, locked := event.(peerEvent)
+		if locked || !c.getState().isOpen() {
+			fsmLock.Lock()
+			locked = true
+		}
+		old := c.getState()
 		e := event.exec(c)
+		if locked {
+			fsmLock.Unlock()
+		}
 
-		Notify(StateUpdate{
-			oldStat: old, newStat: c.state,
-			stateEvent: event, conn: c, Err: e})
+		publish(newStateUpdate(old, event, c, e))
 
 		if _, ok := event.(eventPeerDisc); ok {
+			delStatsConn(c)
+			if cp := c.capture.Load(); cp != nil {
+				cp.forget(c)
+			}
 			break
 		}
 	}
@@ -237,82 +380,149 @@ func (c *Conn) NewSession() *Session {
 }
 */
 
-// Send Diameter request
+// Send Diameter request.
+// Answer has DIAMETER_TOO_BUSY if write queue of the Conn is full,
+// or DIAMETER_UNABLE_TO_DELIVER if the Conn is closed before answer.
 func (c *Conn) Send(m Request, d time.Duration) Answer {
 	sid := nextSession()
 	req := m.ToRaw(sid)
-	req.HbHID = nextHbH()
 	req.EtEID = nextEtE()
+	a, e := c.sendRaw(m, sid, req, d)
+	return answerOf(m, a, e)
+}
+
+// sendRaw sends request req of m with new Hop-by-Hop ID and waits answer.
+// Error is WriteQueueFull if the request is not sent by backpressure,
+// ConnectionRefused if the Conn is closed before answer, or
+// TimeoutExpired with local answer if no answer is received in d.
+func (c *Conn) sendRaw(m Request, sid string, req RawMsg, d time.Duration) (RawMsg, error) {
+	req.HbHID = nextHbH()
+	if c.abate(&req) {
+		a := m.Failed(ResultCode(DOICThrottleResult)).ToRaw(sid)
+		a.HbHID = req.HbHID
+		a.EtEID = req.EtEID
+		return a, nil
+	}
+
+	if c.TxQueue() >= TxBuffer {
+		c.stats.TxReq.Add(1)
+		c.stats.TxReqFail.Add(1)
+		return RawMsg{}, WriteQueueFull{}
+	}
 
-	ch := make(chan RawMsg)
-	c.sndstack[req.HbHID] = ch
-	c.notify <- eventSndMsg{m: req}
+	ch := make(chan pendingAns, 1)
+	if !c.addPending(req.HbHID, ch) {
+		return RawMsg{}, ConnectionRefused{}
+	}
+	h := c.hiding.Load()
+	if h != nil {
+		req = h.hideRequest(req)
+	}
+	start := c.clock.Now()
+	c.sndMsg(req, priorityOf(req, NoDRMP.Level()))
 
-	t := time.AfterFunc(d, func() {
-		m := m.Failed(DiameterTooBusy).ToRaw(sid)
+	t := c.clock.AfterFunc(d, func() {
+		m := m.Failed(ResultCode(DiameterTooBusy)).ToRaw(sid)
 		m.HbHID = req.HbHID
 		m.EtEID = req.EtEID
-		c.notify <- eventRcvMsg{m}
+		c.raise(eventSndTimeout{m})
 	})
 
-	a := <-ch
-	t.Stop()
+	r := <-ch
+	a := r.m
+	if h != nil && a.Code != 0 {
+		a = h.revealAnswer(a)
+	}
+	if t.Stop() && a.Code != 0 {
+		observeLatency(c, req, c.clock.Now().Sub(start))
+		c.rcvOLR(req, a)
+		c.rcvLoad(a)
+	}
+	return a, r.e
+}
+
+// answerOf returns Answer of m from result of sendRaw
+func answerOf(m Request, a RawMsg, e error) Answer {
+	if _, ok := e.(WriteQueueFull); ok {
+		return m.Failed(ResultCode(DiameterTooBusy))
+	}
+	if avperr, ok := e.(InvalidAVP); ok {
+		return m.Failed(ResultCode(avperr.Code))
+	}
+	return decodeAnswer(m, a)
+}
+
+// decodeAnswer returns Answer of m from received message a
+func decodeAnswer(m Request, a RawMsg) Answer {
 	if a.Code == 0 {
-		return m.Failed(DiameterUnableToDeliver)
+		return m.Failed(ResultCode(DiameterUnableToDeliver))
 	}
 
-	if app, ok := supportedApps[a.AppID]; !ok {
+	if app, ok := supportedApps()[a.AppID]; !ok {
 	} else if ans, ok := app.ans[a.Code]; !ok {
 	} else if ack, _, e := ans.FromRaw(a); e == nil {
 		return ack
 	} else if avperr, ok := e.(InvalidAVP); ok {
-		return m.Failed(uint32(avperr))
+		return m.Failed(ResultCode(avperr.Code))
 	} else {
-		return m.Failed(DiameterUnableToComply)
+		return m.Failed(ResultCode(DiameterUnableToComply))
 	}
 
-	if app, ok := supportedApps[0xffffffff]; !ok {
+	if app, ok := supportedApps()[0xffffffff]; !ok {
 	} else if ans, ok := app.ans[0]; ok {
 		ack, _, _ := ans.FromRaw(a)
 		return ack
 	}
 
-	return m.Failed(DiameterUnableToComply)
+	return m.Failed(ResultCode(DiameterUnableToComply))
 }
 
 // Recieve Diameter request
 func (c *Conn) Recieve() (Request, func(Answer), error) {
-	m := <-c.rcvstack
-	if m.Code == 0 {
-		c.rcvstack <- m
+	m, ok := c.rcvstack.pop(true)
+	if !ok {
 		return nil, nil, ConnectionRefused{}
 	}
+	h := c.hiding.Load()
+	if h != nil {
+		m = h.revealRequest(m)
+	}
 
 	var req Request
 
-	if app, ok := supportedApps[m.AppID]; ok {
+	if app, ok := supportedApps()[m.AppID]; ok {
 		req, _ = app.req[m.Code]
 	}
 
 	if req == nil {
-		app, _ := supportedApps[0xffffffff]
+		app, _ := supportedApps()[0xffffffff]
 		req, _ = app.req[0]
 	}
 
 	r, sid, e := req.FromRaw(m)
+	if e != nil {
+		a := errorAnswer(m, e)
+		if h != nil {
+			a = h.hideAnswer(a)
+		}
+		sndDuplicate(m, a)
+		c.sndMsg(a, priorityOf(a, priorityOf(m, NoDRMP.Level())))
+		return r, nil, e
+	}
 	f := func(ans Answer) {
 		a := ans.ToRaw(sid)
 		a.HbHID = m.HbHID
 		a.EtEID = m.EtEID
-		c.notify <- eventSndMsg{a}
-	}
-	if e != nil {
-		if avperr, ok := e.(InvalidAVP); ok {
-			f(req.Failed(uint32(avperr)))
-		} else {
-			f(req.Failed(DiameterUnableToComply))
+		sndOLR(c, m, &a)
+		sndLoad(c, &a, isLocalOrigin(a))
+		if h != nil {
+			a = h.hideAnswer(a)
 		}
-		return r, nil, e
+		sndDuplicate(m, a)
+		c.sndMsg(a, priorityOf(a, priorityOf(m, NoDRMP.Level())))
+	}
+	if c.isDuplicate(m.HbHID) {
+		return r, f, DuplicateRequest{}
 	}
 	return r, f, nil
 }
@@ -323,15 +533,17 @@ func (c *Conn) watchdog() {
 	req.HbHID = nextHbH()
 	req.EtEID = nextEtE()
 
-	ch := make(chan RawMsg)
-	c.sndstack[req.HbHID] = ch
-	c.notify <- eventWatchdog{m: req}
+	ch := make(chan pendingAns, 1)
+	if !c.addPending(req.HbHID, ch) {
+		return
+	}
+	c.raise(eventWatchdog{m: req})
 
-	t := time.AfterFunc(c.Peer.WDInterval, func() {
-		m := dwr.Failed(DiameterTooBusy).ToRaw("")
+	t := c.clock.AfterFunc(c.Peer.WDInterval, func() {
+		m := dwr.Failed(ResultCode(DiameterTooBusy)).ToRaw("")
 		m.HbHID = req.HbHID
 		m.EtEID = req.EtEID
-		c.notify <- eventRcvDWA{m}
+		c.raise(eventRcvDWA{m})
 	})
 
 	<-ch
@@ -340,7 +552,7 @@ func (c *Conn) watchdog() {
 
 // Close stop state machine
 func (c *Conn) Close(d time.Duration) {
-	if c == nil || c.state != open {
+	if c == nil || !c.getState().isOpen() {
 		return
 	}
 
@@ -349,21 +561,26 @@ func (c *Conn) Close(d time.Duration) {
 	req.HbHID = nextHbH()
 	req.EtEID = nextEtE()
 
-	ch := make(chan RawMsg)
-	c.sndstack[req.HbHID] = ch
-	c.notify <- eventStop{m: req}
+	ch := make(chan pendingAns, 1)
+	if !c.addPending(req.HbHID, ch) {
+		return
+	}
+	c.raise(eventStop{m: req})
 
-	t := time.AfterFunc(d, func() {
-		m := dpr.Failed(DiameterTooBusy).ToRaw("")
-		m.HbHID = req.HbHID
-		m.EtEID = req.EtEID
-		c.notify <- eventRcvDPA{m}
+	t := c.clock.AfterFunc(d, func() {
+		c.raise(eventTimeout{m: req})
 	})
 
 	<-ch
 	t.Stop()
 }
 
+// Done returns channel that is closed when the Conn is closed
+// and its goroutines are stopped
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
 // LocalAddr returns transport connection of state machine
 func (c *Conn) LocalAddr() net.Addr {
 	return c.con.LocalAddr()
@@ -376,5 +593,5 @@ func (c *Conn) PeerAddr() net.Addr {
 
 // State returns state machine state
 func (c *Conn) State() string {
-	return c.state.String()
+	return c.getState().String()
 }
@@ -0,0 +1,76 @@
+package diametertest
+
+import (
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// FindAVP returns first AVP of vendor and code in m
+func FindAVP(m dia.RawMsg, vendor, code uint32) (dia.RawAVP, bool) {
+	for _, a := range m.AVP {
+		if a.VenID == vendor && a.Code == code {
+			return a, true
+		}
+	}
+	return dia.RawAVP{}, false
+}
+
+// AssertCommand checks application, command and request flag of m
+func AssertCommand(tb testing.TB, m dia.RawMsg, app, code uint32, req bool) {
+	tb.Helper()
+	if m.AppID != app || m.Code != code || m.FlgR != req {
+		tb.Fatalf("message is app=%d cmd=%d request=%t, want app=%d cmd=%d request=%t",
+			m.AppID, m.Code, m.FlgR, app, code, req)
+	}
+}
+
+// AssertAVP checks m has AVP that is equal to want
+func AssertAVP(tb testing.TB, m dia.RawMsg, want dia.RawAVP) {
+	tb.Helper()
+	for _, a := range m.AVP {
+		if a.Equal(want) {
+			return
+		}
+	}
+	if a, ok := FindAVP(m, want.VenID, want.Code); ok {
+		tb.Fatalf("AVP is %s, want %s", a, want)
+	}
+	tb.Fatalf("AVP %d(vendor=%d) is not found", want.Code, want.VenID)
+}
+
+// AssertNoAVP checks m doesn't have AVP of vendor and code
+func AssertNoAVP(tb testing.TB, m dia.RawMsg, vendor, code uint32) {
+	tb.Helper()
+	if a, ok := FindAVP(m, vendor, code); ok {
+		tb.Fatalf("unexpected AVP %s", a)
+	}
+}
+
+// AssertResult checks Result-Code or Experimental-Result of m
+func AssertResult(tb testing.TB, m dia.RawMsg, want dia.Result) {
+	tb.Helper()
+	a, ok := FindAVP(m, 0, 268)
+	if !ok {
+		a, ok = FindAVP(m, 0, 297)
+	}
+	if !ok {
+		tb.Fatalf("result is not found, want %s", want)
+	}
+	if r, e := dia.GetResult(a); e != nil {
+		tb.Fatalf("invalid result AVP: %s", e)
+	} else if r != want {
+		tb.Fatalf("result is %s, want %s", r, want)
+	}
+}
+
+// AssertAVPs checks all AVPs of a and b are same except ignored codes
+func AssertAVPs(tb testing.TB, a, b []dia.RawAVP, ignore ...uint32) {
+	tb.Helper()
+	if d := dia.DiffAVP(a, b, ignore...); len(d) != 0 {
+		for _, v := range d {
+			tb.Error(v)
+		}
+		tb.FailNow()
+	}
+}
@@ -0,0 +1,165 @@
+package diametertest
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// Clock is fake dia.Clock that is moved by Advance
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock returns Clock that starts at t
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// UseClock set c as dia.DefaultClock until test is finished.
+// Conn that is made after this call uses c.
+func UseClock(tb testing.TB, c dia.Clock) {
+	old := dia.DefaultClock
+	dia.DefaultClock = c
+	tb.Cleanup(func() {
+		dia.DefaultClock = old
+	})
+}
+
+// Now returns current fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc returns timer that call f when clock is advanced over d
+func (c *Clock) AfterFunc(d time.Duration, f func()) dia.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{c: c, f: f, at: c.now.Add(d), active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves clock forward by d and fires expired timers in time order.
+// Each timer function runs in its own goroutine, same as time.AfterFunc.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *timer
+		for _, t := range c.timers {
+			if t.active && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.active = false
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		go next.f()
+	}
+	c.now = end
+	c.cleanup()
+	c.mu.Unlock()
+}
+
+// Pending returns expire time of active timers
+func (c *Clock) Pending() []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := make([]time.Time, 0, len(c.timers))
+	for _, t := range c.timers {
+		if t.active {
+			r = append(r, t.at)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Before(r[j]) })
+	return r
+}
+
+// Settle is time that active timers should be unchanged in WaitPending
+var Settle = time.Millisecond * 5
+
+// WaitPending waits until just n timers are active and
+// they are unchanged for Settle.
+// Timers are made and stopped by goroutines of Conn,
+// so test should wait them before Advance.
+func (c *Clock) WaitPending(tb testing.TB, n int) {
+	tb.Helper()
+	deadline := time.Now().Add(Timeout)
+	var last []time.Time
+	stable := time.Now()
+	for {
+		p := c.Pending()
+		if !equalTimes(p, last) {
+			last = p
+			stable = time.Now()
+		} else if len(p) == n && time.Since(stable) >= Settle {
+			return
+		}
+		if time.Now().After(deadline) {
+			tb.Fatalf("%d timers are active, want %d", len(p), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Clock) cleanup() {
+	l := c.timers[:0]
+	for _, t := range c.timers {
+		if t.active {
+			l = append(l, t)
+		}
+	}
+	c.timers = l
+}
+
+type timer struct {
+	c      *Clock
+	f      func()
+	at     time.Time
+	active bool
+}
+
+func (t *timer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	r := t.active
+	t.active = false
+	return r
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	r := t.active
+	t.at = t.c.now.Add(d)
+	if !r {
+		t.c.cleanup()
+		t.c.timers = append(t.c.timers, t)
+	}
+	t.active = true
+	return r
+}
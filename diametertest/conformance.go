@@ -0,0 +1,501 @@
+package diametertest
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// Transition is a row of peer state machine table
+type Transition struct {
+	State  string
+	Event  string
+	Action []string
+	Next   string
+}
+
+// RFC6733Table is peer state machine of RFC 6733 section 5.6
+// as written in the RFC.
+var RFC6733Table = []Transition{
+	{"Closed", "Start", []string{"I-Snd-Conn-Req"}, "Wait-Conn-Ack"},
+	{"Closed", "R-Conn-CER", []string{"R-Accept", "Process-CER", "R-Snd-CEA"}, "R-Open"},
+
+	{"Wait-Conn-Ack", "I-Rcv-Conn-Ack", []string{"I-Snd-CER"}, "Wait-I-CEA"},
+	{"Wait-Conn-Ack", "I-Rcv-Conn-Nack", []string{"Cleanup"}, "Closed"},
+	{"Wait-Conn-Ack", "R-Conn-CER", []string{"R-Accept", "Process-CER"}, "Wait-Conn-Ack/Elect"},
+	{"Wait-Conn-Ack", "Timeout", []string{"Error"}, "Closed"},
+
+	{"Wait-I-CEA", "I-Rcv-CEA", []string{"Process-CEA"}, "I-Open"},
+	{"Wait-I-CEA", "R-Conn-CER", []string{"R-Accept", "Process-CER", "Elect"}, "Wait-Returns"},
+	{"Wait-I-CEA", "I-Peer-Disc", []string{"I-Disc"}, "Closed"},
+	{"Wait-I-CEA", "I-Rcv-Non-CEA", []string{"Error"}, "Closed"},
+	{"Wait-I-CEA", "Timeout", []string{"Error"}, "Closed"},
+
+	{"Wait-Conn-Ack/Elect", "I-Rcv-Conn-Ack", []string{"I-Snd-CER", "Elect"}, "Wait-Returns"},
+	{"Wait-Conn-Ack/Elect", "I-Rcv-Conn-Nack", []string{"R-Snd-CEA"}, "R-Open"},
+	{"Wait-Conn-Ack/Elect", "R-Peer-Disc", []string{"R-Disc"}, "Wait-Conn-Ack"},
+	{"Wait-Conn-Ack/Elect", "R-Conn-CER", []string{"R-Reject"}, "Wait-Conn-Ack/Elect"},
+	{"Wait-Conn-Ack/Elect", "Timeout", []string{"Error"}, "Closed"},
+
+	{"Wait-Returns", "Win-Election", []string{"I-Disc", "R-Snd-CEA"}, "R-Open"},
+	{"Wait-Returns", "I-Peer-Disc", []string{"I-Disc", "R-Snd-CEA"}, "R-Open"},
+	{"Wait-Returns", "I-Rcv-CEA", []string{"R-Disc"}, "I-Open"},
+	{"Wait-Returns", "R-Peer-Disc", []string{"R-Disc"}, "Wait-I-CEA"},
+	{"Wait-Returns", "R-Conn-CER", []string{"R-Reject"}, "Wait-Returns"},
+	{"Wait-Returns", "Timeout", []string{"Error"}, "Closed"},
+
+	{"R-Open", "Send-Message", []string{"R-Snd-Message"}, "R-Open"},
+	{"R-Open", "R-Rcv-Message", []string{"Process"}, "R-Open"},
+	{"R-Open", "R-Rcv-DWR", []string{"Process-DWR", "R-Snd-DWA"}, "R-Open"},
+	{"R-Open", "R-Rcv-DWA", []string{"Process-DWA"}, "R-Open"},
+	{"R-Open", "R-Conn-CER", []string{"R-Reject"}, "R-Open"},
+	{"R-Open", "Stop", []string{"R-Snd-DPR"}, "Closing"},
+	{"R-Open", "R-Rcv-DPR", []string{"R-Snd-DPA"}, "Closing"},
+	{"R-Open", "R-Peer-Disc", []string{"R-Disc"}, "Closed"},
+
+	{"I-Open", "Send-Message", []string{"I-Snd-Message"}, "I-Open"},
+	{"I-Open", "I-Rcv-Message", []string{"Process"}, "I-Open"},
+	{"I-Open", "I-Rcv-DWR", []string{"Process-DWR", "I-Snd-DWA"}, "I-Open"},
+	{"I-Open", "I-Rcv-DWA", []string{"Process-DWA"}, "I-Open"},
+	{"I-Open", "R-Conn-CER", []string{"R-Reject"}, "I-Open"},
+	{"I-Open", "Stop", []string{"I-Snd-DPR"}, "Closing"},
+	{"I-Open", "I-Rcv-DPR", []string{"I-Snd-DPA"}, "Closing"},
+	{"I-Open", "I-Peer-Disc", []string{"I-Disc"}, "Closed"},
+
+	{"Closing", "I-Rcv-DPA", []string{"I-Disc"}, "Closed"},
+	{"Closing", "R-Rcv-DPA", []string{"R-Disc"}, "Closed"},
+	{"Closing", "Timeout", []string{"Error"}, "Closed"},
+	{"Closing", "I-Peer-Disc", []string{"I-Disc"}, "Closed"},
+	{"Closing", "R-Peer-Disc", []string{"R-Disc"}, "Closed"},
+}
+
+// Deviations is transitions of diameter package that differ from
+// RFC6733Table. Conn that sent DPA is closed without waiting
+// transport disconnection by the peer, since Conn is not reused.
+var Deviations = []Transition{
+	{"R-Open", "R-Rcv-DPR", []string{"R-Snd-DPA"}, "Closed"},
+	{"I-Open", "I-Rcv-DPR", []string{"I-Snd-DPA"}, "Closed"},
+}
+
+// Conformance checks peer state machine of diameter package.
+// Every row of RFC6733Table, or Deviations of the row, is compared
+// with dia.PeerTransition, and the row is driven by RawPeer over
+// net.Pipe if it is reachable.
+// Rows of Wait-Conn-Ack and Wait-Conn-Ack/Elect are not reachable
+// because dia.Dial takes connected transport.
+// R-Conn-CER with MultiConn is also checked.
+func Conformance(t *testing.T) {
+	n := 0
+	run := func(name string, f func(*testing.T)) {
+		n++
+		id := n
+		t.Run(name, func(t *testing.T) {
+			// Conn of previous row may not be closed yet,
+			// so each row uses different peer.
+			host := PeerHost
+			PeerHost = dia.Identity(fmt.Sprintf("peer%d.example", id))
+			t.Cleanup(func() { PeerHost = host })
+			Setup(t)
+			f(t)
+		})
+	}
+
+	for _, tr := range RFC6733Table {
+		tr := tr
+		for _, d := range Deviations {
+			if d.State == tr.State && d.Event == tr.Event {
+				tr = d
+			}
+		}
+		run(tr.State+"/"+tr.Event, func(t *testing.T) {
+			a, next, ok := dia.PeerTransition(tr.State, tr.Event)
+			if !ok {
+				t.Fatal("event is not defined")
+			}
+			if !reflect.DeepEqual(a, tr.Action) || next != tr.Next {
+				t.Fatalf("transition is %v -> %s, want %v -> %s",
+					a, next, tr.Action, tr.Next)
+			}
+			if f, ok := scenarios[tr.State+"/"+tr.Event]; ok {
+				f(t)
+			}
+		})
+	}
+	for _, name := range []string{
+		"I-Open/R-Conn-CER", "R-Open/R-Conn-CER",
+		"Peer/I-Open/R-Conn-CER", "Wait-I-CEA/R-Conn-CER"} {
+		run("MultiConn/"+name, multiScenarios[name])
+	}
+}
+
+var scenarios = map[string]func(*testing.T){
+	"Closed/Start":                 scnOpenI,
+	"Wait-Conn-Ack/I-Rcv-Conn-Ack": scnOpenI,
+	"Wait-I-CEA/I-Rcv-CEA":         scnOpenI,
+	"Closed/R-Conn-CER":            scnOpenR,
+
+	"Wait-I-CEA/I-Peer-Disc": func(t *testing.T) {
+		p, ch := dialPeer(t)
+		p.Expect(t, 0, 257, true)
+		p.Close()
+		assertFailed(t, ch)
+	},
+	"Wait-I-CEA/I-Rcv-Non-CEA": func(t *testing.T) {
+		p, ch := dialPeer(t)
+		p.Expect(t, 0, 257, true)
+		p.Write(p.DWR())
+		assertFailed(t, ch)
+		p.ExpectClosed(t)
+	},
+	"Wait-I-CEA/Timeout": func(t *testing.T) {
+		clk := NewClock(time.Now())
+		UseClock(t, clk)
+		p, ch := dialPeer(t)
+		p.Expect(t, 0, 257, true)
+		clk.WaitPending(t, 1)
+		clk.Advance(Timeout)
+		assertError(t, ch, dia.TimeoutExpired{})
+		p.ExpectClosed(t)
+	},
+
+	// local Host test.example is larger than peer.example
+	"Wait-I-CEA/R-Conn-CER":     scnWinElection,
+	"Wait-Returns/Win-Election": scnWinElection,
+
+	"Wait-Returns/I-Rcv-CEA": func(t *testing.T) {
+		i, ich, r, rch := loseElection(t)
+		i.Write(i.CEA(i.Messages()[0], dia.DiameterSuccess))
+		assertOpen(t, ich, "I-Open")
+		m := r.Expect(t, 0, 257, false)
+		AssertResult(t, m, dia.ResultCode(dia.DiameterElectionLost))
+		assertError(t, rch, dia.ElectionLost{})
+		r.ExpectClosed(t)
+	},
+	"Wait-Returns/I-Peer-Disc": func(t *testing.T) {
+		i, ich, r, rch := loseElection(t)
+		i.Close()
+		assertFailed(t, ich)
+		m := r.Expect(t, 0, 257, false)
+		AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+		assertOpen(t, rch, "R-Open")
+	},
+	"Wait-Returns/R-Peer-Disc": func(t *testing.T) {
+		rec := Record(t, dia.Filter{})
+		i, ich, r, rch := loseElection(t)
+		r.Close()
+		assertFailed(t, rch)
+		rec.WaitState(t, 1, "Wait-I-CEA")
+		i.Write(i.CEA(i.Messages()[0], dia.DiameterSuccess))
+		assertOpen(t, ich, "I-Open")
+	},
+	"Wait-Returns/R-Conn-CER": func(t *testing.T) {
+		i, ich, _, rch := loseElection(t)
+		p, ch := acceptPeer(t)
+		p.Write(p.CER())
+		assertError(t, ch, dia.ConnectionRefused{})
+		p.ExpectClosed(t)
+
+		i.Write(i.CEA(i.Messages()[0], dia.DiameterSuccess))
+		assertOpen(t, ich, "I-Open")
+		assertError(t, rch, dia.ElectionLost{})
+	},
+	"Wait-Returns/Timeout": func(t *testing.T) {
+		clk := NewClock(time.Now())
+		UseClock(t, clk)
+		i, ich, r, rch := loseElection(t)
+		clk.WaitPending(t, 1)
+		clk.Advance(Timeout)
+		assertError(t, ich, dia.TimeoutExpired{})
+		assertError(t, rch, dia.TimeoutExpired{})
+		i.ExpectClosed(t)
+		r.ExpectClosed(t)
+	},
+}
+
+func init() {
+	for _, role := range []string{"I-", "R-"} {
+		open := Dial
+		state := "I-Open"
+		if role == "R-" {
+			open = Accept
+			state = "R-Open"
+		}
+
+		scenarios[state+"/Send-Message"] = func(t *testing.T) {
+			c, p := open(t)
+			ch := make(chan dia.Answer, 1)
+			go func() { ch <- c.Send(Request(), Timeout) }()
+			m := p.Expect(t, AppID, CommandCode, true)
+			p.Answer(m, dia.ResultCode(dia.DiameterSuccess))
+			if a := <-ch; a.Result() != dia.ResultCode(dia.DiameterSuccess) {
+				t.Fatal("answer result is", a.Result())
+			}
+			WaitState(t, c, state)
+		}
+		scenarios[state+"/"+role+"Rcv-Message"] = func(t *testing.T) {
+			c, p := open(t)
+			p.Write(p.Request())
+			_, f, e := c.Recieve()
+			if e != nil {
+				t.Fatal("failed to receive request:", e)
+			}
+			f(dia.RawAns{RawMsg: dia.RawMsg{
+				Ver: dia.DiaVer, Code: CommandCode, AppID: AppID,
+				AVP: []dia.RawAVP{
+					dia.SetResultCode(dia.DiameterSuccess),
+					dia.SetOriginHost(dia.Host),
+					dia.SetOriginRealm(dia.Realm)}}})
+			m := p.Expect(t, AppID, CommandCode, false)
+			AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+			WaitState(t, c, state)
+		}
+		scenarios[state+"/"+role+"Rcv-DWR"] = func(t *testing.T) {
+			c, p := open(t)
+			p.Write(p.DWR())
+			m := p.Expect(t, 0, 280, false)
+			AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+			WaitState(t, c, state)
+		}
+		scenarios[state+"/"+role+"Rcv-DWA"] = func(t *testing.T) {
+			clk := NewClock(time.Now())
+			UseClock(t, clk)
+			rec := Record(t, dia.Filter{Types: []dia.NoticeType{dia.WatchdogNotice}})
+			c, p := open(t)
+			clk.WaitPending(t, 1)
+			clk.Advance(dia.WDInterval)
+			m := p.Expect(t, 0, 280, true)
+			p.Answer(m, dia.ResultCode(dia.DiameterSuccess))
+			rec.Wait(t, 2, func(dia.Notice) bool { return true })
+			WaitState(t, c, state)
+		}
+		scenarios[state+"/R-Conn-CER"] = func(t *testing.T) {
+			c, _ := open(t)
+			p, ch := acceptPeer(t)
+			p.Write(p.CER())
+			assertError(t, ch, dia.ConnectionRefused{})
+			p.ExpectClosed(t)
+			WaitState(t, c, state)
+		}
+		scenarios[state+"/Stop"] = func(t *testing.T) {
+			c, p := open(t)
+			go c.Close(Timeout)
+			m := p.Expect(t, 0, 282, true)
+			WaitState(t, c, "Closing")
+			p.Answer(m, dia.ResultCode(dia.DiameterSuccess))
+			WaitState(t, c, "Closed")
+		}
+		scenarios[state+"/"+role+"Rcv-DPR"] = func(t *testing.T) {
+			c, p := open(t)
+			p.Write(p.DPR())
+			m := p.Expect(t, 0, 282, false)
+			AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+			WaitState(t, c, "Closed")
+		}
+		scenarios[state+"/"+role+"Peer-Disc"] = func(t *testing.T) {
+			c, p := open(t)
+			p.Close()
+			WaitState(t, c, "Closed")
+		}
+
+		scenarios["Closing/"+role+"Rcv-DPA"] = scenarios[state+"/Stop"]
+		scenarios["Closing/"+role+"Peer-Disc"] = func(t *testing.T) {
+			c, p := open(t)
+			go c.Close(Timeout)
+			p.Expect(t, 0, 282, true)
+			WaitState(t, c, "Closing")
+			p.Close()
+			WaitState(t, c, "Closed")
+		}
+	}
+	scenarios["Closing/Timeout"] = func(t *testing.T) {
+		clk := NewClock(time.Now())
+		UseClock(t, clk)
+		c, p := Dial(t)
+		go c.Close(Timeout)
+		p.Expect(t, 0, 282, true)
+		WaitState(t, c, "Closing")
+		clk.WaitPending(t, 1)
+		clk.Advance(Timeout)
+		WaitState(t, c, "Closed")
+		p.ExpectClosed(t)
+	}
+}
+
+// multiScenarios is R-Conn-CER from the peer that has open Conn
+// with MultiConn. Second Conn is accepted and first Conn stays open,
+// but Conn in capability exchange is still elected.
+var multiScenarios = map[string]func(*testing.T){
+	"I-Open/R-Conn-CER": func(t *testing.T) {
+		useMultiConn(t)
+		c, _ := Dial(t)
+		scnOpenR(t)
+		WaitState(t, c, "I-Open")
+	},
+	"R-Open/R-Conn-CER": func(t *testing.T) {
+		useMultiConn(t)
+		c, _ := Accept(t)
+		scnOpenR(t)
+		WaitState(t, c, "R-Open")
+	},
+	"Peer/I-Open/R-Conn-CER": func(t *testing.T) {
+		c, s := net.Pipe()
+		p := NewRawPeer(s)
+		t.Cleanup(func() { c.Close(); s.Close() })
+		ch := make(chan handshake, 1)
+		go func() {
+			con, e := dia.Dial(dia.Peer{
+				Host: p.Host, Realm: p.Realm, MultiConn: true}, c, Timeout)
+			ch <- handshake{con, e}
+		}()
+		m := p.Expect(t, 0, 257, true)
+		p.Write(p.CEA(m, dia.DiameterSuccess))
+		con := assertOpen(t, ch, "I-Open")
+
+		scnOpenR(t)
+		WaitState(t, con, "I-Open")
+	},
+	"Wait-I-CEA/R-Conn-CER": func(t *testing.T) {
+		useMultiConn(t)
+		scnWinElection(t)
+	},
+}
+
+func useMultiConn(t *testing.T) {
+	old := dia.MultiConn
+	dia.MultiConn = true
+	t.Cleanup(func() { dia.MultiConn = old })
+}
+
+type handshake struct {
+	con *dia.Conn
+	err error
+}
+
+// dialPeer starts Dial to RawPeer, RawPeer does not answer CER
+func dialPeer(t *testing.T) (*RawPeer, <-chan handshake) {
+	c, s := net.Pipe()
+	p := NewRawPeer(s)
+	fin := stopPeer(t, c, s)
+
+	ch := make(chan handshake, 1)
+	go func() {
+		con, e := dia.Dial(dia.Peer{Host: p.Host, Realm: p.Realm}, c, Timeout)
+		fin <- con
+		ch <- handshake{con, e}
+	}()
+	return p, ch
+}
+
+// acceptPeer starts Accept from RawPeer, RawPeer does not send CER
+func acceptPeer(t *testing.T) (*RawPeer, <-chan handshake) {
+	c, s := net.Pipe()
+	p := NewRawPeer(c)
+	fin := stopPeer(t, c, s)
+
+	ch := make(chan handshake, 1)
+	go func() {
+		con, e := dia.Accept(nil, s)
+		fin <- con
+		ch <- handshake{con, e}
+	}()
+	return p, ch
+}
+
+// stopPeer closes c and s when the test is finished, and waits until
+// the Conn that is sent to returned channel is stopped
+func stopPeer(t *testing.T, c, s net.Conn) chan<- *dia.Conn {
+	fin := make(chan *dia.Conn, 1)
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+		select {
+		case con := <-fin:
+			stop(t, []*dia.Conn{con})
+		case <-time.After(Timeout):
+			t.Error("Dial or Accept is not finished")
+		}
+	})
+	return fin
+}
+
+func scnOpenI(t *testing.T) {
+	p, ch := dialPeer(t)
+	m := p.Expect(t, 0, 257, true)
+	p.Write(p.CEA(m, dia.DiameterSuccess))
+	assertOpen(t, ch, "I-Open")
+}
+
+func scnOpenR(t *testing.T) {
+	p, ch := acceptPeer(t)
+	p.Write(p.CER())
+	m := p.Expect(t, 0, 257, false)
+	AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+	assertOpen(t, ch, "R-Open")
+}
+
+func scnWinElection(t *testing.T) {
+	i, ich := dialPeer(t)
+	i.Expect(t, 0, 257, true)
+	r, rch := acceptPeer(t)
+	r.Write(r.CER())
+
+	assertError(t, ich, dia.ElectionLost{})
+	i.ExpectClosed(t)
+	m := r.Expect(t, 0, 257, false)
+	AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+	assertOpen(t, rch, "R-Open")
+}
+
+// loseElection makes initiator and responder Conn in Wait-Returns
+// with peer that has larger Origin-Host than local host.
+func loseElection(t *testing.T) (
+	i *RawPeer, ich <-chan handshake, r *RawPeer, rch <-chan handshake) {
+	rec := Record(t, dia.Filter{})
+	dia.Host = "a." + Host
+
+	i, ich = dialPeer(t)
+	i.Expect(t, 0, 257, true)
+	r, rch = acceptPeer(t)
+	r.Write(r.CER())
+	rec.WaitState(t, 2, "Wait-Returns")
+	return
+}
+
+func assertOpen(t *testing.T, ch <-chan handshake, s string) *dia.Conn {
+	t.Helper()
+	h := wait(t, ch)
+	if h.err != nil {
+		t.Fatal("capability exchange failed:", h.err)
+	}
+	WaitState(t, h.con, s)
+	return h.con
+}
+
+func assertFailed(t *testing.T, ch <-chan handshake) {
+	t.Helper()
+	if h := wait(t, ch); h.err == nil {
+		t.Fatal("capability exchange is not failed")
+	}
+}
+
+func assertError(t *testing.T, ch <-chan handshake, want error) {
+	t.Helper()
+	if h := wait(t, ch); reflect.TypeOf(h.err) != reflect.TypeOf(want) {
+		t.Fatalf("error is %v, want %T", h.err, want)
+	}
+}
+
+func wait(t *testing.T, ch <-chan handshake) handshake {
+	t.Helper()
+	select {
+	case h := <-ch:
+		return h
+	case <-time.After(Timeout):
+		t.Fatal("capability exchange is not finished")
+	}
+	return handshake{}
+}
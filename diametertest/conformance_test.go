@@ -0,0 +1,7 @@
+package diametertest
+
+import "testing"
+
+func TestConformance(t *testing.T) {
+	Conformance(t)
+}
@@ -0,0 +1,158 @@
+/*
+Package diametertest provides utilities for testing of Diameter applications.
+
+Setup prepares global identity and test application of diameter package.
+Pipe and Loopback returns Conn pair that finished CER/CEA.
+RawPeer is fake remote peer that records every message,
+and it is used for driving state machine of single Conn.
+Clock is fake clock that controls watchdog and other timers.
+
+Since diameter package uses global configuration,
+tests with this package should not run in parallel.
+*/
+package diametertest
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// Identity and application that are used by Setup
+var (
+	Host  dia.Identity = "test.example"
+	Realm dia.Identity = "example"
+	// PeerHost is Origin-Host of RawPeer
+	PeerHost dia.Identity = "peer.example"
+)
+
+// AppID and CommandCode are application and command of test message.
+// Command code 16777214 is reserved for experimental use.
+const (
+	AppID       uint32 = 16777214
+	CommandCode uint32 = 16777214
+)
+
+// Timeout is default timeout of waiting message or state
+var Timeout = time.Second
+
+var addApp sync.Once
+
+// Setup set dia.Host and dia.Realm to test identity
+// and add test application as RawReq/RawAns once.
+// Original values are restored when test is finished.
+func Setup(tb testing.TB) {
+	tb.Helper()
+	host, realm := dia.Host, dia.Realm
+	dia.Host, dia.Realm = Host, Realm
+	addApp.Do(func() {
+		dia.AddSupportedMessage(0, AppID, CommandCode, dia.RawReq{}, dia.RawAns{})
+	})
+	tb.Cleanup(func() {
+		dia.Host, dia.Realm = host, realm
+	})
+}
+
+// Request returns test request message with AVPs
+func Request(avp ...dia.RawAVP) dia.RawReq {
+	m := dia.RawMsg{
+		Ver: dia.DiaVer, FlgR: true, Code: CommandCode, AppID: AppID,
+		AVP: []dia.RawAVP{
+			dia.SetSessionID(""),
+			dia.SetOriginHost(dia.Host),
+			dia.SetOriginRealm(dia.Realm)}}
+	m.AVP = append(m.AVP, avp...)
+	return dia.RawReq{RawMsg: m}
+}
+
+// Pipe returns Conn pair on net.Pipe.
+// Both Conn has Host as Origin-Host, and state is open.
+func Pipe(tb testing.TB) (client, server *dia.Conn) {
+	tb.Helper()
+	c, s := net.Pipe()
+	return pair(tb, c, s)
+}
+
+// Loopback returns Conn pair on TCP loopback connection.
+func Loopback(tb testing.TB) (client, server *dia.Conn) {
+	tb.Helper()
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		tb.Fatal("failed to listen:", e)
+	}
+	defer l.Close()
+
+	ch := make(chan net.Conn, 1)
+	go func() {
+		s, _ := l.Accept()
+		ch <- s
+	}()
+	c, e := net.Dial("tcp", l.Addr().String())
+	if e != nil {
+		tb.Fatal("failed to dial:", e)
+	}
+	s := <-ch
+	if s == nil {
+		c.Close()
+		tb.Fatal("failed to accept")
+	}
+	return pair(tb, c, s)
+}
+
+func pair(tb testing.TB, c, s net.Conn) (client, server *dia.Conn) {
+	tb.Helper()
+	type result struct {
+		con *dia.Conn
+		e   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		con, e := dia.Accept(nil, s)
+		ch <- result{con, e}
+	}()
+
+	client, e := dia.Dial(dia.Peer{Host: dia.Host, Realm: dia.Realm}, c, Timeout)
+	r := <-ch
+	if e != nil || r.e != nil {
+		c.Close()
+		s.Close()
+		tb.Fatal("capability exchange failed:", e, r.e)
+	}
+	server = r.con
+	tb.Cleanup(func() {
+		stop(tb, []*dia.Conn{client, server}, c, s)
+	})
+	return
+}
+
+// stop closes transport connections and waits until Conns are stopped
+func stop(tb testing.TB, cons []*dia.Conn, nc ...net.Conn) {
+	for _, c := range nc {
+		c.Close()
+	}
+	for _, c := range cons {
+		if c == nil {
+			continue
+		}
+		select {
+		case <-c.Done():
+		case <-time.After(Timeout):
+			tb.Error("Conn is not stopped")
+		}
+	}
+}
+
+// WaitState waits until state of c become s
+func WaitState(tb testing.TB, c *dia.Conn, s string) {
+	tb.Helper()
+	deadline := time.Now().Add(Timeout)
+	for c.State() != s {
+		if time.Now().After(deadline) {
+			tb.Fatalf("state is %s, want %s", c.State(), s)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package diametertest
+
+import (
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+func TestSendTimeout(t *testing.T) {
+	Setup(t)
+	clk := NewClock(time.Unix(0, 0))
+	UseClock(t, clk)
+	c, p := Dial(t)
+
+	ch := make(chan dia.Answer, 1)
+	go func() { ch <- c.Send(Request(), time.Second*5) }()
+	p.Expect(t, AppID, CommandCode, true)
+
+	// watchdog and answer timer
+	clk.WaitPending(t, 2)
+	clk.Advance(time.Second * 5)
+
+	select {
+	case a := <-ch:
+		if r := a.Result(); r != dia.ResultCode(dia.DiameterTooBusy) {
+			t.Fatalf("result is %v, want DIAMETER_TOO_BUSY", r)
+		}
+	case <-time.After(Timeout):
+		t.Fatal("no answer")
+	}
+	if n := c.Stats().TxReqTimeout; n != 1 {
+		t.Fatalf("timeout count is %d, want 1", n)
+	}
+	if s := c.State(); s != "I-Open" {
+		t.Fatalf("state is %s, want I-Open", s)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	Setup(t)
+	clk := NewClock(time.Unix(0, 0))
+	UseClock(t, clk)
+	c, p := Dial(t)
+
+	for i := 0; i < 3; i++ {
+		clk.WaitPending(t, 1)
+		clk.Advance(dia.WDInterval)
+		m := p.Expect(t, 0, 280, true)
+		p.Answer(m, dia.ResultCode(dia.DiameterSuccess))
+	}
+
+	// received DWR restarts watchdog timer
+	clk.WaitPending(t, 1)
+	clk.Advance(dia.WDInterval - time.Second)
+	p.Write(p.DWR())
+	m := p.Expect(t, 0, 280, false)
+	AssertResult(t, m, dia.ResultCode(dia.DiameterSuccess))
+
+	clk.WaitPending(t, 1)
+	clk.Advance(dia.WDInterval - time.Second)
+	select {
+	case m := <-p.rx:
+		t.Fatalf("unexpected message %d before watchdog interval", m.Code)
+	case <-time.After(Settle):
+	}
+	clk.Advance(time.Second)
+	p.Expect(t, 0, 280, true)
+
+	if s := c.State(); s != "I-Open" {
+		t.Fatalf("state is %s, want I-Open", s)
+	}
+}
+
+func TestWatchdogExpired(t *testing.T) {
+	Setup(t)
+	clk := NewClock(time.Unix(0, 0))
+	UseClock(t, clk)
+	rec := Record(t, dia.Filter{})
+	c, p := Dial(t)
+
+	// DWR is sent WDExpired times without DWA
+	for i := 0; i < dia.WDExpired; i++ {
+		clk.WaitPending(t, 1)
+		clk.Advance(dia.WDInterval)
+		p.Expect(t, 0, 280, true)
+
+		// DWA timer
+		clk.WaitPending(t, 1)
+		clk.Advance(dia.WDInterval)
+	}
+
+	clk.WaitPending(t, 1)
+	clk.Advance(dia.WDInterval)
+	p.ExpectClosed(t)
+	WaitState(t, c, "Closed")
+	rec.WaitState(t, 1, "Closed")
+}
@@ -0,0 +1,64 @@
+package diametertest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// Recorder records notices that is published by diameter package
+type Recorder struct {
+	mu   sync.Mutex
+	list []dia.Notice
+}
+
+// Record starts recording notices that match f until test is finished
+func Record(tb testing.TB, f dia.Filter) *Recorder {
+	r := &Recorder{}
+	cancel := dia.Subscribe(func(n dia.Notice) {
+		r.mu.Lock()
+		r.list = append(r.list, n)
+		r.mu.Unlock()
+	}, f)
+	tb.Cleanup(cancel)
+	return r
+}
+
+// Notices returns all recorded notices
+func (r *Recorder) Notices() []dia.Notice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]dia.Notice{}, r.list...)
+}
+
+// Wait waits until n notices that satisfy f are recorded
+func (r *Recorder) Wait(tb testing.TB, n int, f func(dia.Notice) bool) {
+	tb.Helper()
+	deadline := time.Now().Add(Timeout)
+	for {
+		c := 0
+		for _, v := range r.Notices() {
+			if f(v) {
+				c++
+			}
+		}
+		if c >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			tb.Fatalf("%d notices are recorded, want %d", c, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// WaitState waits until n StateUpdate to state s are recorded
+func (r *Recorder) WaitState(tb testing.TB, n int, s string) {
+	tb.Helper()
+	r.Wait(tb, n, func(v dia.Notice) bool {
+		u, ok := v.(dia.StateUpdate)
+		return ok && u.NewState == s
+	})
+}
@@ -0,0 +1,247 @@
+package diametertest
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// RawPeer is fake remote peer that read and write RawMsg directly.
+// Every received message is recorded.
+type RawPeer struct {
+	Host  dia.Identity
+	Realm dia.Identity
+	// AutoDWA answers DWR by DWA automatically
+	AutoDWA bool
+
+	con  net.Conn
+	rx   chan dia.RawMsg
+	mu   sync.Mutex
+	msgs []dia.RawMsg
+}
+
+// NewRawPeer returns RawPeer on c with PeerHost identity
+func NewRawPeer(c net.Conn) *RawPeer {
+	p := &RawPeer{
+		Host:  PeerHost,
+		Realm: Realm,
+		con:   c,
+		rx:    make(chan dia.RawMsg, 1024)}
+	go func() {
+		for {
+			m := dia.RawMsg{}
+			if _, e := m.ReadFrom(c); e != nil {
+				close(p.rx)
+				return
+			}
+			p.mu.Lock()
+			p.msgs = append(p.msgs, m)
+			dwa := p.AutoDWA
+			p.mu.Unlock()
+
+			if dwa && m.FlgR && m.AppID == 0 && m.Code == 280 {
+				p.Answer(m, dia.ResultCode(dia.DiameterSuccess))
+				continue
+			}
+			p.rx <- m
+		}
+	}()
+	return p
+}
+
+// Dial returns Conn that is connected to RawPeer on net.Pipe.
+// RawPeer answers CER by success CEA.
+func Dial(tb testing.TB) (*dia.Conn, *RawPeer) {
+	tb.Helper()
+	c, s := net.Pipe()
+	p := NewRawPeer(s)
+
+	ch := make(chan error, 1)
+	go func() {
+		m, ok := <-p.rx
+		if !ok {
+			ch <- dia.ConnectionRefused{}
+			return
+		}
+		ch <- p.Write(p.CEA(m, dia.DiameterSuccess))
+	}()
+	con, e := dia.Dial(dia.Peer{Host: p.Host, Realm: p.Realm}, c, Timeout)
+	tb.Cleanup(func() { stop(tb, []*dia.Conn{con}, c, s) })
+	if e2 := <-ch; e == nil {
+		e = e2
+	}
+	if e != nil {
+		tb.Fatal("capability exchange failed:", e)
+	}
+	return con, p
+}
+
+// Accept returns Conn that is accepted from RawPeer on net.Pipe.
+// RawPeer sends CER and waits success CEA.
+func Accept(tb testing.TB) (*dia.Conn, *RawPeer) {
+	tb.Helper()
+	c, s := net.Pipe()
+	p := NewRawPeer(c)
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- p.Write(p.CER())
+	}()
+	con, e := dia.Accept(nil, s)
+	tb.Cleanup(func() { stop(tb, []*dia.Conn{con}, c, s) })
+	if e2 := <-ch; e == nil {
+		e = e2
+	}
+	if e != nil {
+		tb.Fatal("capability exchange failed:", e)
+	}
+	p.Expect(tb, 0, 257, false)
+	return con, p
+}
+
+// Write sends m to Conn.
+// Zero Hop-by-Hop and End-to-End ID of request are set to new value.
+func (p *RawPeer) Write(m dia.RawMsg) error {
+	if m.FlgR && m.HbHID == 0 {
+		m.HbHID = nextID()
+	}
+	if m.FlgR && m.EtEID == 0 {
+		m.EtEID = nextID()
+	}
+	p.con.SetWriteDeadline(time.Now().Add(Timeout))
+	_, e := m.WriteTo(p.con)
+	return e
+}
+
+// Close closes transport connection of RawPeer
+func (p *RawPeer) Close() error {
+	return p.con.Close()
+}
+
+// Messages returns all received messages
+func (p *RawPeer) Messages() []dia.RawMsg {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]dia.RawMsg{}, p.msgs...)
+}
+
+// Next waits next received message
+func (p *RawPeer) Next(tb testing.TB) dia.RawMsg {
+	tb.Helper()
+	select {
+	case m, ok := <-p.rx:
+		if !ok {
+			tb.Fatal("connection is closed")
+		}
+		return m
+	case <-time.After(Timeout):
+		tb.Fatal("no message is received")
+	}
+	return dia.RawMsg{}
+}
+
+// Expect waits next message and checks its command
+func (p *RawPeer) Expect(tb testing.TB, app, code uint32, req bool) dia.RawMsg {
+	tb.Helper()
+	m := p.Next(tb)
+	AssertCommand(tb, m, app, code, req)
+	return m
+}
+
+// ExpectClosed waits transport connection is closed by Conn
+func (p *RawPeer) ExpectClosed(tb testing.TB) {
+	tb.Helper()
+	for {
+		select {
+		case m, ok := <-p.rx:
+			if !ok {
+				return
+			}
+			tb.Log("message is received before close:", m.Code)
+		case <-time.After(Timeout):
+			tb.Fatal("connection is not closed")
+		}
+	}
+}
+
+// Answer sends answer of req with result r and additional AVPs
+func (p *RawPeer) Answer(req dia.RawMsg, r dia.Result, avp ...dia.RawAVP) error {
+	m := dia.RawMsg{
+		Ver: dia.DiaVer, FlgP: req.FlgP, FlgE: r.Class() == dia.ProtocolError,
+		Code: req.Code, AppID: req.AppID, HbHID: req.HbHID, EtEID: req.EtEID,
+		AVP: make([]dia.RawAVP, 0, len(avp)+4)}
+	if a, ok := FindAVP(req, 0, 263); ok {
+		m.AVP = append(m.AVP, a)
+	}
+	m.AVP = append(m.AVP,
+		dia.SetResult(r),
+		dia.SetOriginHost(p.Host),
+		dia.SetOriginRealm(p.Realm))
+	m.AVP = append(m.AVP, avp...)
+	return p.Write(m)
+}
+
+// CER returns CER from RawPeer that supports test application
+func (p *RawPeer) CER() dia.RawMsg {
+	return dia.CER{
+		OriginHost:    p.Host,
+		OriginRealm:   p.Realm,
+		HostIPAddress: []net.IP{net.IPv4(127, 0, 0, 1)},
+		VendorID:      dia.VendorID,
+		ProductName:   "diametertest",
+		ApplicationID: map[uint32][]uint32{0: {AppID}}}.ToRaw("")
+}
+
+// CEA returns CEA for req with result code r
+func (p *RawPeer) CEA(req dia.RawMsg, r uint32) dia.RawMsg {
+	m := dia.CEA{
+		ResultCode:    r,
+		OriginHost:    p.Host,
+		OriginRealm:   p.Realm,
+		HostIPAddress: []net.IP{net.IPv4(127, 0, 0, 1)},
+		VendorID:      dia.VendorID,
+		ProductName:   "diametertest",
+		ApplicationID: map[uint32][]uint32{0: {AppID}}}.ToRaw("")
+	m.HbHID, m.EtEID = req.HbHID, req.EtEID
+	m.FlgE = r != dia.DiameterSuccess
+	return m
+}
+
+// Request returns test request from RawPeer with AVPs
+func (p *RawPeer) Request(avp ...dia.RawAVP) dia.RawMsg {
+	m := dia.RawMsg{
+		Ver: dia.DiaVer, FlgR: true, Code: CommandCode, AppID: AppID,
+		AVP: []dia.RawAVP{
+			dia.SetSessionID(string(p.Host) + ";diametertest"),
+			dia.SetOriginHost(p.Host),
+			dia.SetOriginRealm(p.Realm)}}
+	m.AVP = append(m.AVP, avp...)
+	return m
+}
+
+// DWR returns DWR from RawPeer
+func (p *RawPeer) DWR() dia.RawMsg {
+	return dia.DWR{OriginHost: p.Host, OriginRealm: p.Realm}.ToRaw("")
+}
+
+// DPR returns DPR from RawPeer
+func (p *RawPeer) DPR() dia.RawMsg {
+	return dia.DPR{
+		OriginHost: p.Host, OriginRealm: p.Realm,
+		DisconnectCause: dia.Rebooting}.ToRaw("")
+}
+
+var idCh = make(chan uint32, 1)
+
+func init() {
+	idCh <- 1
+}
+
+func nextID() uint32 {
+	i := <-idCh
+	idCh <- i + 1
+	return i
+}
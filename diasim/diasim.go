@@ -0,0 +1,302 @@
+/*
+Package diasim is programmable Diameter peer for integration test.
+
+Simulator accepts connections and answers requests by rules.
+Rule matches Application-ID, Command-Code and AVP values of request,
+and makes answer, delay, drop or error answer.
+Behavior makes scripted misbehaviour of the peer,
+like refusing CER, ignoring DWR or sending DPR after N requests.
+
+Behavior is applied to each Conn of the Simulator, so more than one
+Simulator can run in a process. Simulator wraps diameter.HandleCER
+when first Simulator is made, and DWA is dropped by the transport of
+the Conn that ignores DWR.
+*/
+package diasim
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// Config is configuration of Simulator
+type Config struct {
+	Behavior Behavior `json:"behavior"`
+	Rules    []Rule   `json:"rules"`
+}
+
+// Behavior is scripted behaviour of the peer
+type Behavior struct {
+	// RefuseCER is Result-Code of CEA. Zero accept CER.
+	RefuseCER uint32 `json:"refuse_cer,omitempty"`
+	// IgnoreDWR drop DWA instead of sending
+	IgnoreDWR bool `json:"ignore_dwr,omitempty"`
+	// DPRAfter send DPR after answering this number of requests
+	DPRAfter int `json:"dpr_after,omitempty"`
+	// MultiConn accepts more than one connection from same peer
+	MultiConn bool `json:"multi_conn,omitempty"`
+}
+
+// Rule is rule of answer for request
+type Rule struct {
+	// AppID and Code match any value if nil
+	AppID *uint32 `json:"application,omitempty"`
+	Code  *uint32 `json:"command,omitempty"`
+	// Match is AVPs that request must contain
+	Match []dia.RawAVP `json:"match,omitempty"`
+
+	Delay Duration `json:"delay,omitempty"`
+	Drop  bool     `json:"drop,omitempty"`
+	// Result is Result-Code or Experimental-Result of answer.
+	// Answer of 3xxx result has E-bit.
+	Result struct {
+		VendorID uint32 `json:"vendor,omitempty"`
+		Code     uint32 `json:"code"`
+	} `json:"result"`
+	// AVP is additional AVPs of answer
+	AVP []dia.RawAVP `json:"avp,omitempty"`
+}
+
+// Duration is time.Duration that is written as "100ms" in JSON
+type Duration time.Duration
+
+// MarshalJSON returns JSON text of this value
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON make this value from JSON text
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if e := json.Unmarshal(b, &s); e != nil {
+		return e
+	}
+	v, e := time.ParseDuration(s)
+	*d = Duration(v)
+	return e
+}
+
+func (r Rule) match(m dia.RawMsg) bool {
+	if r.AppID != nil && *r.AppID != m.AppID {
+		return false
+	}
+	if r.Code != nil && *r.Code != m.Code {
+		return false
+	}
+	for _, a := range r.Match {
+		ok := false
+		for _, b := range m.AVP {
+			a.FlgM, a.FlgP = b.FlgM, b.FlgP
+			if a.Equal(b) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Rule) answer(m dia.RawMsg) dia.RawAns {
+	a := dia.RawMsg{
+		Ver: dia.DiaVer, FlgP: m.FlgP, Code: m.Code, AppID: m.AppID,
+		AVP: make([]dia.RawAVP, 0, len(r.AVP)+4)}
+	for _, v := range m.AVP {
+		if v.Code == 263 && v.VenID == 0 {
+			a.AVP = append(a.AVP, v)
+			break
+		}
+	}
+	res := dia.ResultCode(r.Result.Code)
+	if r.Result.VendorID != 0 {
+		res = dia.ExperimentalResult(r.Result.VendorID, r.Result.Code)
+	}
+	if res.Code == 0 {
+		res = dia.ResultCode(dia.DiameterSuccess)
+	}
+	a.FlgE = res.Class() == dia.ProtocolError
+	a.AVP = append(a.AVP,
+		dia.SetResult(res),
+		dia.SetOriginHost(dia.Host),
+		dia.SetOriginRealm(dia.Realm))
+	a.AVP = append(a.AVP, r.AVP...)
+	return dia.RawAns{RawMsg: a}
+}
+
+// LoadConfig read JSON config file
+func LoadConfig(path string) (c Config, e error) {
+	b, e := os.ReadFile(path)
+	if e == nil {
+		e = json.Unmarshal(b, &c)
+	}
+	return
+}
+
+// Simulator is fake Diameter peer
+type Simulator struct {
+	Config
+	// Timeout is timeout of DPR
+	Timeout time.Duration
+
+	wg sync.WaitGroup
+}
+
+// New returns Simulator of config c.
+// Applications in rules are added to supported applications.
+func New(c Config) *Simulator {
+	s := &Simulator{Config: c, Timeout: time.Second}
+	for _, r := range c.Rules {
+		if r.AppID != nil && r.Code != nil {
+			dia.AddSupportedMessage(0, *r.AppID, *r.Code, dia.RawReq{}, dia.RawAns{})
+		}
+	}
+	dia.AddSupportedMessage(0, 0xffffffff, 0, dia.RawReq{}, dia.RawAns{})
+
+	hook.Do(func() {
+		cer := dia.HandleCER
+		dia.HandleCER = func(r dia.CER, c *dia.Conn) dia.CEA {
+			s := simulatorOf(c)
+			if s != nil {
+				// Peer of the Conn accepts any host
+				c.Peer.Host, c.Peer.Realm = r.OriginHost, r.OriginRealm
+			}
+			a := cer(r, c)
+			if s != nil && s.Behavior.RefuseCER != 0 {
+				a.ResultCode = s.Behavior.RefuseCER
+			}
+			return a
+		}
+	})
+	return s
+}
+
+var (
+	hook sync.Once
+	// sims is Simulator of Peer of accepted Conn,
+	// the Peer is made for each Conn
+	sims sync.Map
+)
+
+func simulatorOf(c *dia.Conn) *Simulator {
+	if v, ok := sims.Load(c.Peer); ok {
+		return v.(*Simulator)
+	}
+	return nil
+}
+
+// Serve accept connections from l and answer requests
+func (s *Simulator) Serve(l net.Listener) error {
+	for {
+		c, e := l.Accept()
+		if e != nil {
+			s.wg.Wait()
+			return e
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.ServeConn(c)
+		}()
+	}
+}
+
+// ServeConn answer requests on c until the connection is closed
+func (s *Simulator) ServeConn(c net.Conn) error {
+	p := &dia.Peer{MultiConn: s.Behavior.MultiConn}
+	sims.Store(p, s)
+	defer sims.Delete(p)
+	if s.Behavior.IgnoreDWR {
+		c = dwaFilter{c}
+	}
+
+	con, e := dia.Accept(p, c)
+	if e != nil {
+		return e
+	}
+
+	var n int64
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		req, f, e := con.Recieve()
+		if _, ok := e.(dia.ConnectionRefused); ok {
+			return nil
+		} else if e != nil {
+			continue
+		}
+		m := req.ToRaw("")
+		if v, ok := req.(dia.RawReq); ok {
+			m = v.RawMsg
+		}
+
+		var rule *Rule
+		for i := range s.Rules {
+			if s.Rules[i].match(m) {
+				rule = &s.Rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			rule = &Rule{}
+			rule.Result.Code = dia.DiameterUnableToComply
+		}
+		if rule.Drop {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Rule) {
+			defer wg.Done()
+			time.Sleep(time.Duration(r.Delay))
+			f(r.answer(m))
+			if s.Behavior.DPRAfter > 0 &&
+				atomic.AddInt64(&n, 1) == int64(s.Behavior.DPRAfter) {
+				con.Close(s.Timeout)
+			}
+		}(rule)
+	}
+}
+
+// dwaFilter drops DWA in messages that are written to net.Conn
+type dwaFilter struct {
+	net.Conn
+}
+
+func (c dwaFilter) Write(b []byte) (int, error) {
+	buf := make([]byte, 0, len(b))
+	for r := b; len(r) != 0; {
+		l := len(r)
+		if l >= 20 {
+			l = int(r[1])<<16 | int(r[2])<<8 | int(r[3])
+		}
+		if l < 20 || l > len(r) {
+			buf = append(buf, r...)
+			break
+		}
+		if !isDWA(r[:l]) {
+			buf = append(buf, r[:l]...)
+		}
+		r = r[l:]
+	}
+	if len(buf) != 0 {
+		if _, e := c.Conn.Write(buf); e != nil {
+			return 0, e
+		}
+	}
+	return len(b), nil
+}
+
+// isDWA returns true if b is DWA message
+func isDWA(b []byte) bool {
+	return b[4]&0x80 == 0 &&
+		b[5] == 0 && b[6] == 0x01 && b[7] == 0x18 &&
+		b[8] == 0 && b[9] == 0 && b[10] == 0 && b[11] == 0
+}
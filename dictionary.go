@@ -0,0 +1,169 @@
+package diameter
+
+import "sync"
+
+// AVPType is data format of AVP
+type AVPType int
+
+// AVP data formats
+const (
+	OctetString AVPType = iota
+	Integer32
+	Integer64
+	Unsigned32
+	Unsigned64
+	Float32
+	Float64
+	Grouped
+	Address
+	Time
+	UTF8String
+	DiameterIdentity
+	DiameterURI
+	EnumeratedType
+)
+
+func (t AVPType) String() string {
+	switch t {
+	case OctetString:
+		return "OctetString"
+	case Integer32:
+		return "Integer32"
+	case Integer64:
+		return "Integer64"
+	case Unsigned32:
+		return "Unsigned32"
+	case Unsigned64:
+		return "Unsigned64"
+	case Float32:
+		return "Float32"
+	case Float64:
+		return "Float64"
+	case Grouped:
+		return "Grouped"
+	case Address:
+		return "Address"
+	case Time:
+		return "Time"
+	case UTF8String:
+		return "UTF8String"
+	case DiameterIdentity:
+		return "DiameterIdentity"
+	case DiameterURI:
+		return "DiameterURI"
+	case EnumeratedType:
+		return "Enumerated"
+	}
+	return "unknown"
+}
+
+// AVPDefinition is dictionary entry of AVP
+type AVPDefinition struct {
+	Name  string
+	Code  uint32
+	VenID uint32
+	Type  AVPType
+}
+
+type avpKey struct {
+	code, vendor uint32
+}
+
+var dictionary = struct {
+	sync.RWMutex
+	byCode map[avpKey]AVPDefinition
+	byName map[string]AVPDefinition
+}{
+	byCode: make(map[avpKey]AVPDefinition),
+	byName: make(map[string]AVPDefinition)}
+
+// RegisterAVP add AVP definition to dictionary
+func RegisterAVP(vendor, code uint32, name string, t AVPType) {
+	d := AVPDefinition{Name: name, Code: code, VenID: vendor, Type: t}
+	dictionary.Lock()
+	defer dictionary.Unlock()
+	dictionary.byCode[avpKey{d.Code, d.VenID}] = d
+	dictionary.byName[d.Name] = d
+}
+
+// LookupAVP returns AVP definition of vendor and code
+func LookupAVP(vendor, code uint32) (AVPDefinition, bool) {
+	dictionary.RLock()
+	defer dictionary.RUnlock()
+	d, ok := dictionary.byCode[avpKey{code, vendor}]
+	return d, ok
+}
+
+// LookupAVPByName returns AVP definition of name
+func LookupAVPByName(name string) (AVPDefinition, bool) {
+	dictionary.RLock()
+	defer dictionary.RUnlock()
+	d, ok := dictionary.byName[name]
+	return d, ok
+}
+
+func init() {
+	RegisterAVP(0, 1, "User-Name", UTF8String)
+	RegisterAVP(0, 25, "Class", OctetString)
+	RegisterAVP(0, 27, "Session-Timeout", Unsigned32)
+	RegisterAVP(0, 33, "Proxy-State", OctetString)
+	RegisterAVP(0, 44, "Accounting-Session-Id", OctetString)
+	RegisterAVP(0, 50, "Acct-Multi-Session-Id", UTF8String)
+	RegisterAVP(0, 55, "Event-Timestamp", Time)
+	RegisterAVP(0, 85, "Acct-Interim-Interval", Unsigned32)
+	RegisterAVP(0, 257, "Host-IP-Address", Address)
+	RegisterAVP(0, 258, "Auth-Application-Id", Unsigned32)
+	RegisterAVP(0, 259, "Acct-Application-Id", Unsigned32)
+	RegisterAVP(0, 260, "Vendor-Specific-Application-Id", Grouped)
+	RegisterAVP(0, 261, "Redirect-Host-Usage", EnumeratedType)
+	RegisterAVP(0, 262, "Redirect-Max-Cache-Time", Unsigned32)
+	RegisterAVP(0, 263, "Session-Id", UTF8String)
+	RegisterAVP(0, 264, "Origin-Host", DiameterIdentity)
+	RegisterAVP(0, 265, "Supported-Vendor-Id", Unsigned32)
+	RegisterAVP(0, 266, "Vendor-Id", Unsigned32)
+	RegisterAVP(0, 267, "Firmware-Revision", Unsigned32)
+	RegisterAVP(0, 268, "Result-Code", Unsigned32)
+	RegisterAVP(0, 269, "Product-Name", UTF8String)
+	RegisterAVP(0, 270, "Session-Binding", Unsigned32)
+	RegisterAVP(0, 271, "Session-Server-Failover", EnumeratedType)
+	RegisterAVP(0, 272, "Multi-Round-Time-Out", Unsigned32)
+	RegisterAVP(0, 273, "Disconnect-Cause", EnumeratedType)
+	RegisterAVP(0, 274, "Auth-Request-Type", EnumeratedType)
+	RegisterAVP(0, 276, "Auth-Grace-Period", Unsigned32)
+	RegisterAVP(0, 277, "Auth-Session-State", EnumeratedType)
+	RegisterAVP(0, 278, "Origin-State-Id", Unsigned32)
+	RegisterAVP(0, 279, "Failed-AVP", Grouped)
+	RegisterAVP(0, 280, "Proxy-Host", DiameterIdentity)
+	RegisterAVP(0, 281, "Error-Message", UTF8String)
+	RegisterAVP(0, 282, "Route-Record", DiameterIdentity)
+	RegisterAVP(0, 283, "Destination-Realm", DiameterIdentity)
+	RegisterAVP(0, 284, "Proxy-Info", Grouped)
+	RegisterAVP(0, 285, "Re-Auth-Request-Type", EnumeratedType)
+	RegisterAVP(0, 287, "Accounting-Sub-Session-Id", Unsigned64)
+	RegisterAVP(0, 291, "Authorization-Lifetime", Unsigned32)
+	RegisterAVP(0, 292, "Redirect-Host", DiameterURI)
+	RegisterAVP(0, 293, "Destination-Host", DiameterIdentity)
+	RegisterAVP(0, 294, "Error-Reporting-Host", DiameterIdentity)
+	RegisterAVP(0, 295, "Termination-Cause", EnumeratedType)
+	RegisterAVP(0, 296, "Origin-Realm", DiameterIdentity)
+	RegisterAVP(0, 297, "Experimental-Result", Grouped)
+	RegisterAVP(0, 298, "Experimental-Result-Code", Unsigned32)
+	RegisterAVP(0, 299, "Inband-Security-Id", Unsigned32)
+	RegisterAVP(0, 301, "DRMP", EnumeratedType)
+	RegisterAVP(0, 480, "Accounting-Record-Type", EnumeratedType)
+	RegisterAVP(0, 483, "Accounting-Realtime-Required", EnumeratedType)
+	RegisterAVP(0, 485, "Accounting-Record-Number", Unsigned32)
+
+	RegisterAVP(0, 621, "OC-Supported-Features", Grouped)
+	RegisterAVP(0, 622, "OC-Feature-Vector", Unsigned64)
+	RegisterAVP(0, 623, "OC-OLR", Grouped)
+	RegisterAVP(0, 624, "OC-Sequence-Number", Unsigned64)
+	RegisterAVP(0, 625, "OC-Validity-Duration", Unsigned32)
+	RegisterAVP(0, 626, "OC-Report-Type", EnumeratedType)
+	RegisterAVP(0, 627, "OC-Reduction-Percentage", Unsigned32)
+
+	RegisterAVP(0, 649, "SourceID", DiameterIdentity)
+	RegisterAVP(0, 650, "Load", Grouped)
+	RegisterAVP(0, 651, "Load-Type", EnumeratedType)
+	RegisterAVP(0, 652, "Load-Value", Unsigned64)
+}
@@ -0,0 +1,94 @@
+package diameter
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// AVPDiff is difference of an AVP between two AVP lists
+type AVPDiff struct {
+	Code  uint32
+	VenID uint32
+	Index int     // index in AVPs that have same Code and Vendor-ID
+	A     *RawAVP // nil if the AVP does not exist in A
+	B     *RawAVP // nil if the AVP does not exist in B
+}
+
+func (d AVPDiff) String() string {
+	switch {
+	case d.A == nil:
+		return fmt.Sprintf("AVP %d(vendor=%d)[%d] only in B: % x",
+			d.Code, d.VenID, d.Index, d.B.data)
+	case d.B == nil:
+		return fmt.Sprintf("AVP %d(vendor=%d)[%d] only in A: % x",
+			d.Code, d.VenID, d.Index, d.A.data)
+	}
+	return fmt.Sprintf("AVP %d(vendor=%d)[%d] differ: A=% x, B=% x",
+		d.Code, d.VenID, d.Index, d.A.data, d.B.data)
+}
+
+// Equal returns true if flags and data of a and b are same
+func (a RawAVP) Equal(b RawAVP) bool {
+	return a.Code == b.Code && a.VenID == b.VenID &&
+		a.FlgV == b.FlgV && a.FlgM == b.FlgM && a.FlgP == b.FlgP &&
+		bytes.Equal(a.data, b.data)
+}
+
+// DiffAVP compare AVPs in a and b.
+// AVPs that have same Code and Vendor-ID are compared in order of appearance,
+// and AVPs that Code is in ignore are skipped.
+func DiffAVP(a, b []RawAVP, ignore ...uint32) []AVPDiff {
+	type key struct{ code, ven uint32 }
+	skip := func(c uint32) bool {
+		for _, i := range ignore {
+			if i == c {
+				return true
+			}
+		}
+		return false
+	}
+
+	keys := []key{}
+	ma := make(map[key][]*RawAVP)
+	mb := make(map[key][]*RawAVP)
+	for i := range a {
+		k := key{a[i].Code, a[i].VenID}
+		if skip(k.code) {
+			continue
+		}
+		if _, ok := ma[k]; !ok {
+			keys = append(keys, k)
+		}
+		ma[k] = append(ma[k], &a[i])
+	}
+	for i := range b {
+		k := key{b[i].Code, b[i].VenID}
+		if skip(k.code) {
+			continue
+		}
+		if _, ok := ma[k]; !ok {
+			if _, ok := mb[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		mb[k] = append(mb[k], &b[i])
+	}
+
+	var r []AVPDiff
+	for _, k := range keys {
+		la, lb := ma[k], mb[k]
+		for i := 0; i < len(la) || i < len(lb); i++ {
+			d := AVPDiff{Code: k.code, VenID: k.ven, Index: i}
+			if i < len(la) {
+				d.A = la[i]
+			}
+			if i < len(lb) {
+				d.B = lb[i]
+			}
+			if d.A == nil || d.B == nil || !d.A.Equal(*d.B) {
+				r = append(r, d)
+			}
+		}
+	}
+	return r
+}
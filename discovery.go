@@ -0,0 +1,509 @@
+package diameter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver is DNS resolver for peer discovery.
+// LookupSRV and LookupIPAddr are same as net.Resolver.
+type Resolver interface {
+	LookupNAPTR(ctx context.Context, name string) ([]*NAPTR, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DefaultResolver is Resolver that is used by Discover if resolver is nil
+var DefaultResolver Resolver = &DNSResolver{}
+
+// NAPTR is DNS NAPTR resource record
+type NAPTR struct {
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+// Candidate is peer that is discovered by DNS
+type Candidate struct {
+	URI
+	Addrs []net.IP
+}
+
+// Discover returns prioritised peer candidates of realm by NAPTR,
+// SRV and A/AAAA records (RFC 6733 section 5.2, RFC 6408) with resolver r.
+// Application specific service like "aaa+ap16777312" is used
+// only if apps is empty or apps has the application.
+// SRV records of the realm are used if NAPTR record is not found.
+// SRV records of same priority are ordered by weight (RFC 2782).
+func Discover(ctx context.Context, r Resolver, realm Identity, apps ...uint32) ([]Candidate, error) {
+	if r == nil {
+		r = DefaultResolver
+	}
+	name := strings.TrimSuffix(string(realm), ".")
+
+	var res []Candidate
+	rrs, _ := r.LookupNAPTR(ctx, name)
+	sort.SliceStable(rrs, func(i, j int) bool {
+		if rrs[i].Order != rrs[j].Order {
+			return rrs[i].Order < rrs[j].Order
+		}
+		return rrs[i].Preference < rrs[j].Preference
+	})
+	for _, rr := range rrs {
+		scheme, transport, ok := parseNAPTRService(rr.Service, apps)
+		if !ok || len(rr.Replacement) == 0 || rr.Replacement == "." {
+			continue
+		}
+		switch strings.ToLower(rr.Flags) {
+		case "s":
+			if len(transport) == 0 {
+				scheme, transport = srvTransport(rr.Replacement)
+			}
+			res = append(res, lookupSRV(ctx, r, scheme, transport, rr.Replacement)...)
+		case "a":
+			if len(transport) == 0 {
+				transport = "tcp"
+			}
+			u := URI{
+				Scheme:    scheme,
+				Fqdn:      Identity(strings.TrimSuffix(rr.Replacement, ".")),
+				Port:      defaultPort(scheme),
+				Transport: transport,
+				Protocol:  "diameter"}
+			if ips := lookupIP(ctx, r, rr.Replacement); len(ips) != 0 {
+				res = append(res, Candidate{URI: u, Addrs: ips})
+			}
+		}
+	}
+
+	if len(rrs) == 0 {
+		for _, s := range []string{
+			"_diameter._tcp.", "_diameter._sctp.",
+			"_diameters._tcp.", "_diameters._sctp."} {
+			scheme, transport := srvTransport(s)
+			res = append(res, lookupSRV(ctx, r, scheme, transport, s+name)...)
+		}
+	}
+
+	// remove duplicate candidate
+	l := make([]Candidate, 0, len(res))
+	m := make(map[string]bool)
+	for _, c := range res {
+		if k := c.URI.String(); !m[k] {
+			m[k] = true
+			l = append(l, c)
+		}
+	}
+	if len(l) == 0 {
+		return nil, DiscoveryFailed{Realm: realm}
+	}
+	return l, nil
+}
+
+// parseNAPTRService returns scheme and transport of S-NAPTR service.
+// Transport is empty if it is not specified in the service.
+func parseNAPTRService(s string, apps []uint32) (scheme, transport string, ok bool) {
+	s = strings.ToLower(s)
+	switch s {
+	case "aaa+d2t":
+		return "aaa", "tcp", true
+	case "aaa+d2s":
+		return "aaa", "sctp", true
+	case "aaas+d2t":
+		return "aaas", "tcp", true
+	case "aaas+d2s":
+		return "aaas", "sctp", true
+	}
+
+	// RFC 6408 service, like "aaa:diameter.tcp" or
+	// application service, like "aaa+ap16777312:diameter.tcp"
+	tag, proto, _ := strings.Cut(s, ":")
+	if tag != "aaa" {
+		if !strings.HasPrefix(tag, "aaa+ap") {
+			return
+		}
+		a, e := strconv.ParseUint(tag[len("aaa+ap"):], 10, 32)
+		if e != nil {
+			return
+		}
+		if len(apps) != 0 {
+			found := false
+			for _, v := range apps {
+				found = found || v == uint32(a)
+			}
+			if !found {
+				return
+			}
+		}
+	}
+	scheme = "aaa"
+	switch proto {
+	case "":
+	case "diameter.tcp":
+		transport = "tcp"
+	case "diameter.sctp":
+		transport = "sctp"
+	case "diameter.tls.tcp":
+		scheme, transport = "aaas", "tcp"
+	case "diameter.dtls.sctp":
+		scheme, transport = "aaas", "sctp"
+	default:
+		return
+	}
+	return scheme, transport, true
+}
+
+// srvTransport returns scheme and transport of SRV name
+func srvTransport(name string) (scheme, transport string) {
+	scheme, transport = "aaa", "tcp"
+	n := strings.ToLower(name)
+	if strings.HasPrefix(n, "_diameters.") {
+		scheme = "aaas"
+	}
+	if strings.Contains(n, "._sctp.") {
+		transport = "sctp"
+	}
+	return
+}
+
+func defaultPort(scheme string) int {
+	if scheme == "aaas" {
+		return 5658
+	}
+	return 3868
+}
+
+func lookupSRV(ctx context.Context, r Resolver, scheme, transport, name string) []Candidate {
+	_, srvs, e := r.LookupSRV(ctx, "", "", name)
+	if e != nil {
+		return nil
+	}
+	var res []Candidate
+	for _, s := range orderSRV(srvs) {
+		if s.Target == "." {
+			continue
+		}
+		ips := lookupIP(ctx, r, s.Target)
+		if len(ips) == 0 {
+			continue
+		}
+		res = append(res, Candidate{
+			URI: URI{
+				Scheme:    scheme,
+				Fqdn:      Identity(strings.TrimSuffix(s.Target, ".")),
+				Port:      int(s.Port),
+				Transport: transport,
+				Protocol:  "diameter"},
+			Addrs: ips})
+	}
+	return res
+}
+
+// orderSRV returns SRV records sorted by priority, and ordered by
+// weighted random selection of RFC 2782 in same priority
+func orderSRV(srvs []*net.SRV) []*net.SRV {
+	sort.SliceStable(srvs, func(i, j int) bool {
+		return srvs[i].Priority < srvs[j].Priority
+	})
+	res := make([]*net.SRV, 0, len(srvs))
+	for i := 0; i < len(srvs); {
+		j := i
+		for j < len(srvs) && srvs[j].Priority == srvs[i].Priority {
+			j++
+		}
+		// zero weight records are placed first
+		l := append([]*net.SRV{}, srvs[i:j]...)
+		sort.SliceStable(l, func(a, b int) bool {
+			return l[a].Weight == 0 && l[b].Weight != 0
+		})
+		for len(l) != 0 {
+			sum := 0
+			for _, s := range l {
+				sum += int(s.Weight)
+			}
+			n := rand.Intn(sum + 1)
+			k := 0
+			for run := int(l[0].Weight); run < n; run += int(l[k].Weight) {
+				k++
+			}
+			res = append(res, l[k])
+			l = append(l[:k], l[k+1:]...)
+		}
+		i = j
+	}
+	return res
+}
+
+func lookupIP(ctx context.Context, r Resolver, host string) []net.IP {
+	addrs, e := r.LookupIPAddr(ctx, host)
+	if e != nil {
+		return nil
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips
+}
+
+// DNSResolver is Resolver that query to DNS server.
+// Zero value uses system DNS configuration.
+type DNSResolver struct {
+	// Server is address of DNS server, like "127.0.0.1:53".
+	// Empty is first nameserver in /etc/resolv.conf.
+	Server string
+	// Timeout of a query, zero is TransportTimeout
+	Timeout time.Duration
+}
+
+func (d *DNSResolver) server() string {
+	if len(d.Server) != 0 {
+		return d.Server
+	}
+	f, e := os.Open("/etc/resolv.conf")
+	if e != nil {
+		return "127.0.0.1:53"
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if l := strings.Fields(s.Text()); len(l) >= 2 && l[0] == "nameserver" {
+			return net.JoinHostPort(l[1], "53")
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+func (d *DNSResolver) resolver() *net.Resolver {
+	if len(d.Server) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var nd net.Dialer
+			return nd.DialContext(ctx, network, d.Server)
+		}}
+}
+
+// LookupSRV returns SRV records
+func (d *DNSResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return d.resolver().LookupSRV(ctx, service, proto, name)
+}
+
+// LookupIPAddr returns A and AAAA records
+func (d *DNSResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return d.resolver().LookupIPAddr(ctx, host)
+}
+
+// LookupNAPTR returns NAPTR records of name.
+// Query is sent by UDP with EDNS, and it is sent again by TCP
+// if the response is truncated.
+func (d *DNSResolver) LookupNAPTR(ctx context.Context, name string) ([]*NAPTR, error) {
+	t := d.Timeout
+	if t == 0 {
+		t = TransportTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, t)
+	defer cancel()
+
+	id := uint16(rand.Uint32())
+	q, e := dnsQuery(id, name, 35)
+	if e != nil {
+		return nil, e
+	}
+	b, e := d.exchange(ctx, "udp", id, q)
+	if e == nil && b[2]&0x02 != 0 {
+		b, e = d.exchange(ctx, "tcp", id, q)
+	}
+	if e != nil {
+		return nil, e
+	}
+	return parseNAPTR(name, b)
+}
+
+// exchange sends query q with id by network and returns the response
+func (d *DNSResolver) exchange(ctx context.Context, network string, id uint16, q []byte) ([]byte, error) {
+	var nd net.Dialer
+	c, e := nd.DialContext(ctx, network, d.server())
+	if e != nil {
+		return nil, e
+	}
+	defer c.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		c.SetDeadline(dl)
+	}
+
+	if network == "tcp" {
+		q = append(binary.BigEndian.AppendUint16(nil, uint16(len(q))), q...)
+		if _, e = c.Write(q); e != nil {
+			return nil, e
+		}
+		l := make([]byte, 2)
+		if _, e = io.ReadFull(c, l); e != nil {
+			return nil, e
+		}
+		b := make([]byte, binary.BigEndian.Uint16(l))
+		if _, e = io.ReadFull(c, b); e != nil {
+			return nil, e
+		}
+		if len(b) < 12 || binary.BigEndian.Uint16(b) != id {
+			return nil, &net.DNSError{Err: "malformed DNS response"}
+		}
+		return b, nil
+	}
+
+	if _, e = c.Write(q); e != nil {
+		return nil, e
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, e := c.Read(buf)
+		if e != nil {
+			return nil, e
+		}
+		if n >= 12 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// dnsQuery returns DNS query message of name and type t
+// with EDNS OPT record of 4096 bytes UDP payload size
+func dnsQuery(id uint16, name string, t uint16) ([]byte, error) {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(b[4:], 1)
+	binary.BigEndian.PutUint16(b[10:], 1)
+	for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(l) == 0 || len(l) > 63 {
+			return nil, fmt.Errorf("invalid domain name %s", name)
+		}
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(b, t), 1)
+
+	// OPT record, root name, type 41, class is UDP payload size
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(b, 41), 4096)
+	return append(b, 0, 0, 0, 0, 0, 0), nil
+}
+
+// parseNAPTR returns NAPTR records in DNS response message b
+func parseNAPTR(name string, b []byte) ([]*NAPTR, error) {
+	malformed := &net.DNSError{Err: "malformed DNS response", Name: name}
+	if len(b) < 12 {
+		return nil, malformed
+	}
+	switch b[3] & 0x0f {
+	case 0:
+	case 3:
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	qd := int(binary.BigEndian.Uint16(b[4:]))
+	an := int(binary.BigEndian.Uint16(b[6:]))
+
+	p := 12
+	var e error
+	for i := 0; i < qd; i++ {
+		if _, p, e = dnsName(b, p); e != nil || p+4 > len(b) {
+			return nil, malformed
+		}
+		p += 4
+	}
+	var res []*NAPTR
+	for i := 0; i < an; i++ {
+		if _, p, e = dnsName(b, p); e != nil || p+10 > len(b) {
+			return nil, malformed
+		}
+		t := binary.BigEndian.Uint16(b[p:])
+		l := int(binary.BigEndian.Uint16(b[p+8:]))
+		p += 10
+		if p+l > len(b) {
+			return nil, malformed
+		}
+		if t == 35 {
+			rr, e := naptrData(b, p, p+l)
+			if e != nil {
+				return nil, malformed
+			}
+			res = append(res, rr)
+		}
+		p += l
+	}
+	return res, nil
+}
+
+func naptrData(b []byte, p, end int) (*NAPTR, error) {
+	if p+4 > end {
+		return nil, fmt.Errorf("short data")
+	}
+	rr := &NAPTR{
+		Order:      binary.BigEndian.Uint16(b[p:]),
+		Preference: binary.BigEndian.Uint16(b[p+2:])}
+	p += 4
+	for _, s := range []*string{&rr.Flags, &rr.Service, &rr.Regexp} {
+		if p >= end || p+1+int(b[p]) > end {
+			return nil, fmt.Errorf("short data")
+		}
+		*s = string(b[p+1 : p+1+int(b[p])])
+		p += 1 + int(b[p])
+	}
+	var e error
+	rr.Replacement, _, e = dnsName(b[:end], p)
+	return rr, e
+}
+
+// dnsName returns domain name at p of DNS message b and next position
+func dnsName(b []byte, p int) (string, int, error) {
+	var l []string
+	next := -1
+	for jump := 0; jump < 64; {
+		if p >= len(b) {
+			return "", 0, fmt.Errorf("short data")
+		}
+		n := int(b[p])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = p + 1
+			}
+			return strings.Join(l, ".") + ".", next, nil
+		case n&0xc0 == 0xc0:
+			if p+1 >= len(b) {
+				return "", 0, fmt.Errorf("short data")
+			}
+			if next < 0 {
+				next = p + 2
+			}
+			p = int(binary.BigEndian.Uint16(b[p:]) & 0x3fff)
+			jump++
+		case n&0xc0 != 0:
+			return "", 0, fmt.Errorf("invalid label type")
+		case p+1+n > len(b):
+			return "", 0, fmt.Errorf("short data")
+		default:
+			l = append(l, string(b[p+1:p+1+n]))
+			p += 1 + n
+		}
+	}
+	return "", 0, fmt.Errorf("too many compression pointer")
+}
@@ -0,0 +1,146 @@
+package diameter_test
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+)
+
+func TestDNSName(t *testing.T) {
+	// "example.com." at 0, "a.example.com." at 13 by pointer to 0
+	base := []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		1, 'a', 0xc0, 0}
+
+	tests := []struct {
+		name string
+		b    []byte
+		p    int
+		want string
+		next int
+		err  bool
+	}{
+		{"plain", base, 0, "example.com.", 13, false},
+		{"pointer", base, 13, "a.example.com.", 17, false},
+		{"pointer only", append(base, 0xc0, 8), 17, "com.", 19, false},
+		{"root", []byte{0}, 0, ".", 1, false},
+		{"self loop", []byte{0xc0, 0}, 0, "", 0, true},
+		{"mutual loop", []byte{1, 'a', 0xc0, 4, 1, 'b', 0xc0, 0}, 0, "", 0, true},
+		{"pointer out of range", []byte{0xc0, 0x10}, 0, "", 0, true},
+		{"truncated pointer", []byte{1, 'a', 0xc0}, 0, "", 0, true},
+		{"truncated label", []byte{5, 'a', 'b'}, 0, "", 0, true},
+		{"no terminator", []byte{1, 'a'}, 0, "", 0, true},
+		{"reserved label type", []byte{0x41, 'a', 0}, 0, "", 0, true},
+		{"out of message", base, len(base), "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, n, e := dia.DNSName(tt.b, tt.p)
+			if tt.err {
+				if e == nil {
+					t.Fatalf("no error, got %q", s)
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if s != tt.want || n != tt.next {
+				t.Fatalf("name is %q at %d, want %q at %d", s, n, tt.want, tt.next)
+			}
+		})
+	}
+}
+
+// dnsMsg returns DNS response with rcode, question of single label name
+// and answers that has name by pointer to the question
+func dnsMsg(rcode byte, name string, an ...[]byte) []byte {
+	b := []byte{0, 1, 0x81, 0x80 | rcode, 0, 1, 0, byte(len(an)), 0, 0, 0, 0}
+	b = append(append(b, byte(len(name))), name...)
+	b = append(b, 0, 0, 35, 0, 1)
+	for _, a := range an {
+		b = append(b, a...)
+	}
+	return b
+}
+
+// naptrRR returns NAPTR resource record
+func naptrRR(order, pref uint16, flags, service string, repl ...byte) []byte {
+	d := binary.BigEndian.AppendUint16(nil, order)
+	d = binary.BigEndian.AppendUint16(d, pref)
+	for _, s := range []string{flags, service, ""} {
+		d = append(append(d, byte(len(s))), s...)
+	}
+	d = append(d, repl...)
+	return rr(35, d)
+}
+
+// rr returns resource record of type t with name by pointer to question
+func rr(t uint16, d []byte) []byte {
+	b := []byte{0xc0, 12}
+	b = binary.BigEndian.AppendUint16(b, t)
+	b = append(b, 0, 1, 0, 0, 0, 60)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(d)))
+	return append(b, d...)
+}
+
+func TestParseNAPTR(t *testing.T) {
+	tcp := naptrRR(10, 5, "s", "aaa+D2T", 4, '_', 't', 'c', 'p', 0xc0, 12)
+	sctp := naptrRR(20, 5, "s", "aaa+D2S", 0)
+
+	tests := []struct {
+		name     string
+		b        []byte
+		want     []dia.NAPTR
+		notFound bool
+		err      bool
+	}{
+		{"records", dnsMsg(0, "example", tcp, sctp), []dia.NAPTR{
+			{Order: 10, Preference: 5, Flags: "s", Service: "aaa+D2T", Replacement: "_tcp.example."},
+			{Order: 20, Preference: 5, Flags: "s", Service: "aaa+D2S", Replacement: "."}}, false, false},
+		{"other type", dnsMsg(0, "example", rr(1, []byte{192, 0, 2, 1}), sctp), []dia.NAPTR{
+			{Order: 20, Preference: 5, Flags: "s", Service: "aaa+D2S", Replacement: "."}}, false, false},
+		{"no answer", dnsMsg(0, "example"), nil, false, false},
+		{"nxdomain", dnsMsg(3, "example"), nil, true, true},
+		{"servfail", dnsMsg(2, "example"), nil, false, true},
+		{"short header", []byte{0, 1, 0x81}, nil, false, true},
+		{"truncated question", dnsMsg(0, "example")[:20], nil, false, true},
+		{"truncated answer", dnsMsg(0, "example", tcp[:len(tcp)-3]), nil, false, true},
+		{"missing answer", func() []byte {
+			b := dnsMsg(0, "example", tcp)
+			b[7] = 2
+			return b
+		}(), nil, false, true},
+		{"short rdata", dnsMsg(0, "example", rr(35, []byte{0, 10, 0, 5, 1})), nil, false, true},
+		// replacement at 52 points itself
+		{"replacement loop", dnsMsg(0, "example",
+			naptrRR(10, 5, "s", "aaa+D2T", 0xc0, 52)), nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, e := dia.ParseNAPTR("example", tt.b)
+			if tt.err {
+				de, ok := e.(*net.DNSError)
+				if !ok {
+					t.Fatalf("error is %v, want DNSError", e)
+				}
+				if de.IsNotFound != tt.notFound {
+					t.Fatalf("not found is %v", de.IsNotFound)
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if len(l) != len(tt.want) {
+				t.Fatalf("records are %d, want %d", len(l), len(tt.want))
+			}
+			for i, r := range l {
+				if *r != tt.want[i] {
+					t.Fatalf("record %d is %+v, want %+v", i, *r, tt.want[i])
+				}
+			}
+		})
+	}
+}
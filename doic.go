@@ -0,0 +1,317 @@
+package diameter
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DOIC (RFC 7683) configuration
+var (
+	// DOIC enables OC-Supported-Features negotiation.
+	// Sent request has OC-Supported-Features, and received OC-OLR
+	// in answer makes abatement of following requests.
+	DOIC = false
+	// DOICThrottleResult is Result-Code of request that is
+	// answered locally by abatement
+	DOICThrottleResult = DiameterTooBusy
+)
+
+// OLRDefaultAlgo is OLR_DEFAULT_ALGO bit of OC-Feature-Vector
+const OLRDefaultAlgo uint64 = 0x0000000000000001
+
+// ReportType is value of OC-Report-Type AVP
+type ReportType Enumerated
+
+const (
+	// HostReport is HOST_REPORT
+	HostReport ReportType = 0
+	// RealmReport is REALM_REPORT
+	RealmReport ReportType = 1
+)
+
+// OLR is overload report of OC-OLR AVP
+type OLR struct {
+	SequenceNumber      uint64
+	ReportType          ReportType
+	ReductionPercentage uint32
+	ValidityDuration    time.Duration
+}
+
+// SetOCSupportedFeatures make OC-Supported-Features AVP
+func SetOCSupportedFeatures(v uint64) (a RawAVP) {
+	a = RawAVP{Code: 621, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	f := RawAVP{Code: 622, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	f.Encode(v)
+	a.Encode([]RawAVP{f})
+	return
+}
+
+// GetOCSupportedFeatures read OC-Supported-Features AVP
+func GetOCSupportedFeatures(a RawAVP) (v uint64, e error) {
+	o := []RawAVP{}
+	if a.FlgV || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
+		e = a.Decode(&o)
+	}
+	for _, a := range o {
+		if a.VenID == 0 && a.Code == 622 {
+			e = a.Decode(&v)
+		}
+	}
+	return
+}
+
+// SetOCOLR make OC-OLR AVP
+func SetOCOLR(v OLR) (a RawAVP) {
+	a = RawAVP{Code: 623, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	sn := RawAVP{Code: 624, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	sn.Encode(v.SequenceNumber)
+	rt := RawAVP{Code: 626, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	rt.Encode(Enumerated(v.ReportType))
+	rp := RawAVP{Code: 627, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	rp.Encode(v.ReductionPercentage)
+	vd := RawAVP{Code: 625, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	vd.Encode(uint32(v.ValidityDuration / time.Second))
+	a.Encode([]RawAVP{sn, rt, rp, vd})
+	return
+}
+
+// GetOCOLR read OC-OLR AVP.
+// ValidityDuration is 30 seconds if OC-Validity-Duration is absent.
+func GetOCOLR(a RawAVP) (v OLR, e error) {
+	o := []RawAVP{}
+	if a.FlgV || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
+		e = a.Decode(&o)
+	}
+	v.ValidityDuration = time.Second * 30
+	sn, rt := false, false
+	for _, a := range o {
+		if a.VenID != 0 || e != nil {
+			continue
+		}
+		switch a.Code {
+		case 624:
+			e = a.Decode(&v.SequenceNumber)
+			sn = true
+		case 626:
+			var t Enumerated
+			e = a.Decode(&t)
+			v.ReportType = ReportType(t)
+			rt = true
+		case 627:
+			e = a.Decode(&v.ReductionPercentage)
+		case 625:
+			var d uint32
+			e = a.Decode(&d)
+			if d > 86400 {
+				d = 86400
+			}
+			v.ValidityDuration = time.Second * time.Duration(d)
+		}
+	}
+	if e == nil && (!sn || !rt) {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: a}
+	}
+	return
+}
+
+// overload reports that is received by reacting node
+var overloads = struct {
+	sync.Mutex
+	host  map[string]overload
+	realm map[string]overload
+}{
+	host:  make(map[string]overload),
+	realm: make(map[string]overload)}
+
+type overload struct {
+	OLR
+	expire time.Time
+}
+
+// local overload report of reporting node
+var localOLR = struct {
+	sync.RWMutex
+	olr *OLR
+	seq uint64
+	end time.Time // end of report with zero validity
+}{}
+
+// ReportOverload set overload report of local host that is sent
+// in answers to DOIC supported requests. Zero validity is 30 seconds.
+// Zero reduction ends overload report by report with zero validity,
+// that is sent during validity of the previous report.
+func ReportOverload(t ReportType, reduction uint32, validity time.Duration) {
+	if validity == 0 {
+		validity = time.Second * 30
+	}
+	localOLR.Lock()
+	defer localOLR.Unlock()
+	if reduction == 0 {
+		if o := localOLR.olr; o != nil && o.ValidityDuration != 0 {
+			localOLR.seq++
+			localOLR.end = DefaultClock.Now().Add(o.ValidityDuration)
+			localOLR.olr = &OLR{
+				SequenceNumber: localOLR.seq,
+				ReportType:     o.ReportType}
+		}
+		return
+	}
+	localOLR.seq++
+	if reduction > 100 {
+		reduction = 100
+	}
+	localOLR.olr = &OLR{
+		SequenceNumber:      localOLR.seq,
+		ReportType:          t,
+		ReductionPercentage: reduction,
+		ValidityDuration:    validity}
+}
+
+// Overload returns overload report that is received from host or realm
+func Overload(host, realm Identity) (OLR, bool) {
+	return overloadAt(DefaultClock.Now(), host, realm)
+}
+
+// overloadAt returns overload report of host or realm that is valid at now
+func overloadAt(now time.Time, host, realm Identity) (OLR, bool) {
+	overloads.Lock()
+	defer overloads.Unlock()
+	if v, ok := overloads.host[strings.ToLower(string(host))]; ok && now.Before(v.expire) {
+		return v.OLR, true
+	}
+	if v, ok := overloads.realm[strings.ToLower(string(realm))]; ok && now.Before(v.expire) {
+		return v.OLR, true
+	}
+	return OLR{}, false
+}
+
+// doicDest returns destination of request m that is sent to c
+func doicDest(c *Conn, m RawMsg) (host, realm Identity) {
+	for _, a := range m.AVP {
+		if a.VenID != 0 {
+			continue
+		}
+		switch a.Code {
+		case 293:
+			host, _ = GetDestinationHost(a)
+		case 283:
+			realm, _ = GetDestinationRealm(a)
+		}
+	}
+	if len(host) == 0 {
+		host = c.Peer.Host
+	}
+	if len(realm) == 0 {
+		realm = c.Peer.Realm
+	}
+	return
+}
+
+// abate returns true if request m should be throttled
+// by overload report, and add OC-Supported-Features to m.
+func (c *Conn) abate(m *RawMsg) bool {
+	if !DOIC || !m.FlgR {
+		return false
+	}
+	ok := false
+	for _, a := range m.AVP {
+		if a.VenID == 0 && a.Code == 621 {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		m.AVP = append(m.AVP, SetOCSupportedFeatures(OLRDefaultAlgo))
+	}
+
+	host, realm := doicDest(c, *m)
+	o, ok := overloadAt(c.clock.Now(), host, realm)
+	return ok && rand.Intn(100) < int(o.ReductionPercentage)
+}
+
+// rcvOLR stores OC-OLR in answer a of request m
+func (c *Conn) rcvOLR(m, a RawMsg) {
+	if !DOIC {
+		return
+	}
+	var olr RawAVP
+	var origin Identity
+	for _, v := range a.AVP {
+		if v.VenID != 0 {
+			continue
+		}
+		switch v.Code {
+		case 623:
+			olr = v
+		case 264:
+			origin, _ = GetOriginHost(v)
+		}
+	}
+	if olr.Code == 0 {
+		return
+	}
+	o, e := GetOCOLR(olr)
+	if e != nil {
+		return
+	}
+
+	var key string
+	var l map[string]overload
+	if o.ReportType == RealmReport {
+		_, realm := doicDest(c, m)
+		key, l = strings.ToLower(string(realm)), overloads.realm
+	} else {
+		if len(origin) == 0 {
+			origin = c.Peer.Host
+		}
+		key, l = strings.ToLower(string(origin)), overloads.host
+	}
+
+	overloads.Lock()
+	defer overloads.Unlock()
+	if old, ok := l[key]; ok && old.SequenceNumber >= o.SequenceNumber {
+		return
+	}
+	if o.ValidityDuration == 0 || o.ReductionPercentage == 0 {
+		delete(l, key)
+		return
+	}
+	l[key] = overload{OLR: o, expire: c.clock.Now().Add(o.ValidityDuration)}
+}
+
+// sndOLR adds OC-Supported-Features and OC-OLR to answer a
+// that is sent to c if request m supports DOIC
+func sndOLR(c *Conn, m RawMsg, a *RawMsg) {
+	if !DOIC {
+		return
+	}
+	ok := false
+	for _, v := range m.AVP {
+		if v.VenID == 0 && v.Code == 621 {
+			ok = true
+			break
+		}
+	}
+	for _, v := range a.AVP {
+		if v.VenID == 0 && (v.Code == 621 || v.Code == 623) {
+			ok = false
+		}
+	}
+	if !ok {
+		return
+	}
+	a.AVP = append(a.AVP, SetOCSupportedFeatures(OLRDefaultAlgo))
+
+	localOLR.RLock()
+	defer localOLR.RUnlock()
+	if o := localOLR.olr; o != nil &&
+		(o.ValidityDuration != 0 || c.clock.Now().Before(localOLR.end)) {
+		a.AVP = append(a.AVP, SetOCOLR(*o))
+	}
+}
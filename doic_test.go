@@ -0,0 +1,71 @@
+package diameter_test
+
+import (
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+)
+
+// groupedAVP returns grouped AVP of code that contains avp
+func groupedAVP(code uint32, avp ...dia.RawAVP) dia.RawAVP {
+	a := dia.RawAVP{Code: code}
+	a.Encode(avp)
+	return a
+}
+
+// uintAVP returns AVP of code with Unsigned32 or Unsigned64 value v
+func uintAVP(code uint32, v any) dia.RawAVP {
+	a := dia.RawAVP{Code: code}
+	a.Encode(v)
+	return a
+}
+
+func TestGetOCOLR(t *testing.T) {
+	sn := uintAVP(624, uint64(5))
+	rt := uintAVP(626, dia.Enumerated(dia.RealmReport))
+	rp := uintAVP(627, uint32(40))
+
+	tests := []struct {
+		name string
+		avp  dia.RawAVP
+		want dia.OLR
+		err  bool
+	}{
+		{"round trip", dia.SetOCOLR(dia.OLR{
+			SequenceNumber: 7, ReportType: dia.HostReport,
+			ReductionPercentage: 20, ValidityDuration: time.Minute}),
+			dia.OLR{7, dia.HostReport, 20, time.Minute}, false},
+		{"default validity", groupedAVP(623, sn, rt, rp),
+			dia.OLR{5, dia.RealmReport, 40, time.Second * 30}, false},
+		{"zero validity", groupedAVP(623, sn, rt, rp, uintAVP(625, uint32(0))),
+			dia.OLR{5, dia.RealmReport, 40, 0}, false},
+		{"max validity", groupedAVP(623, sn, rt, uintAVP(625, uint32(100000))),
+			dia.OLR{5, dia.RealmReport, 0, time.Hour * 24}, false},
+		{"no sequence", groupedAVP(623, rt, rp), dia.OLR{}, true},
+		{"no report type", groupedAVP(623, sn, rp), dia.OLR{}, true},
+		{"invalid value", groupedAVP(623, uintAVP(624, uint32(5)), rt), dia.OLR{}, true},
+		{"V flag", func() dia.RawAVP {
+			a := groupedAVP(623, sn, rt)
+			a.FlgV, a.VenID = true, 10415
+			return a
+		}(), dia.OLR{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, e := dia.GetOCOLR(tt.avp)
+			if tt.err {
+				if e == nil {
+					t.Fatalf("no error, got %+v", v)
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if v != tt.want {
+				t.Fatalf("OLR is %+v, want %+v", v, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package diameter
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// DRMP is Diameter Routing Message Priority (RFC 7944) AVP value.
+// Priority0 is the highest priority.
+type DRMP int
+
+// DRMP values
+const (
+	// NoDRMP indicate DRMP AVP is not present, it is handled as Priority10
+	NoDRMP DRMP = iota
+	Priority0
+	Priority1
+	Priority2
+	Priority3
+	Priority4
+	Priority5
+	Priority6
+	Priority7
+	Priority8
+	Priority9
+	Priority10
+	Priority11
+	Priority12
+	Priority13
+	Priority14
+	Priority15
+)
+
+// Level returns priority level 0-15 of DRMP, smaller is higher
+func (v DRMP) Level() int {
+	if v < Priority0 || v > Priority15 {
+		return 10
+	}
+	return int(v - Priority0)
+}
+
+// SetDRMP make DRMP AVP
+func SetDRMP(v DRMP) (a RawAVP) {
+	a = RawAVP{Code: 301, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	a.Encode(Enumerated(v.Level()))
+	return
+}
+
+// GetDRMP read DRMP AVP
+func GetDRMP(a RawAVP) (v DRMP, e error) {
+	var t Enumerated
+	if a.FlgV || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else if e = a.Decode(&t); e != nil {
+	} else if t < 0 || t > 15 {
+		e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
+	} else {
+		v = DRMP(t) + Priority0
+	}
+	return
+}
+
+// priorityOf returns priority level of message m,
+// or def if m does not have DRMP AVP.
+// Answer without DRMP has same priority as the request.
+func priorityOf(m RawMsg, def int) int {
+	for _, a := range m.AVP {
+		if a.Code == 301 && a.VenID == 0 {
+			if v, e := GetDRMP(a); e == nil {
+				return v.Level()
+			}
+		}
+	}
+	return def
+}
+
+// msgQueue is priority queue of messages.
+// Messages with same priority are FIFO.
+type msgQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	h      msgHeap
+	seq    uint64
+	max    int
+	closed bool
+}
+
+type queuedMsg struct {
+	m    RawMsg
+	pri  int
+	seq  uint64
+	done chan struct{} // flush marker
+}
+
+type msgHeap []queuedMsg
+
+func (h msgHeap) Len() int { return len(h) }
+func (h msgHeap) Less(i, j int) bool {
+	if h[i].pri != h[j].pri {
+		return h[i].pri < h[j].pri
+	}
+	return h[i].seq < h[j].seq
+}
+func (h msgHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *msgHeap) Push(x interface{}) { *h = append(*h, x.(queuedMsg)) }
+func (h *msgHeap) Pop() interface{} {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
+
+func newMsgQueue(max int) *msgQueue {
+	q := &msgQueue{max: max}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds m with priority level pri to the queue,
+// it returns false if the queue is full or closed
+func (q *msgQueue) push(m RawMsg, pri int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || (q.max > 0 && len(q.h) >= q.max) {
+		return false
+	}
+	q.seq++
+	heap.Push(&q.h, queuedMsg{m: m, pri: pri, seq: q.seq})
+	q.cond.Signal()
+	return true
+}
+
+// mark adds flush marker done that is popped after
+// all messages in the queue, it ignores max length.
+// It returns false if the queue is closed.
+func (q *msgQueue) mark(done chan struct{}) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.seq++
+	heap.Push(&q.h, queuedMsg{pri: Priority15.Level() + 1, seq: q.seq, done: done})
+	q.cond.Signal()
+	return true
+}
+
+// pop removes the highest priority message,
+// it waits message if wait is true and the queue is empty.
+// It returns false if the queue is closed and empty.
+func (q *msgQueue) pop(wait bool) (RawMsg, bool) {
+	v, ok := q.take(wait)
+	return v.m, ok
+}
+
+// take is pop that returns queued entry with its priority
+func (q *msgQueue) take(wait bool) (queuedMsg, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for wait && len(q.h) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.h) == 0 {
+		return queuedMsg{}, false
+	}
+	return heap.Pop(&q.h).(queuedMsg), true
+}
+
+// close wakes up all waiting pop, following push fails
+func (q *msgQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+func (q *msgQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+func (q *msgQueue) len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.h)
+}
+
+// sndMsg queues message m with priority level pri and
+// raises Snd-MSG event that sends the highest priority message
+func (c *Conn) sndMsg(m RawMsg, pri int) {
+	c.sndqueue.push(m, pri)
+	c.raise(eventSndMsg{})
+}
@@ -0,0 +1,172 @@
+package diameter
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DuplicateMode is action for duplicate request
+type DuplicateMode int
+
+const (
+	// DuplicateOff disables duplicate detection
+	DuplicateOff DuplicateMode = iota
+	// DuplicateReplay sends answer of original request to duplicate request.
+	// Duplicate request that is received before the answer waits it.
+	DuplicateReplay
+	// DuplicateMark passes duplicate request to Recieve
+	// with DuplicateRequest error
+	DuplicateMark
+)
+
+var (
+	// DuplicateCheck is mode of duplicate detection.
+	// Every received request is remembered by Origin-Host and End-to-End ID,
+	// and request with T flag is checked with them.
+	DuplicateCheck = DuplicateOff
+	// DuplicateWindow is time that received request is remembered
+	DuplicateWindow = time.Minute * 4
+)
+
+type dupKey struct {
+	host string
+	id   uint32
+}
+
+type dupEntry struct {
+	at   time.Time
+	ans  RawMsg // zero Code before answer is sent
+	wait []dupWaiter
+}
+
+// dupWaiter is duplicate request that waits answer of original request
+type dupWaiter struct {
+	c   *Conn
+	hbh uint32
+}
+
+// dupRef is key of dupEntry in received order
+type dupRef struct {
+	k  dupKey
+	at time.Time
+}
+
+var dupCache = struct {
+	sync.Mutex
+	m     map[dupKey]*dupEntry
+	order []dupRef
+}{m: make(map[dupKey]*dupEntry)}
+
+// expireDuplicate removes entries that are older than DuplicateWindow
+// from head of received order. dupCache must be locked.
+func expireDuplicate(now time.Time) {
+	for len(dupCache.order) != 0 {
+		r := dupCache.order[0]
+		if now.Sub(r.at) <= DuplicateWindow {
+			break
+		}
+		if d, ok := dupCache.m[r.k]; ok && d.at.Equal(r.at) {
+			delete(dupCache.m, r.k)
+		}
+		dupCache.order = dupCache.order[1:]
+	}
+}
+
+func dupKeyOf(m RawMsg) (dupKey, bool) {
+	for _, a := range m.AVP {
+		if a.Code == 264 && a.VenID == 0 {
+			h, e := GetOriginHost(a)
+			return dupKey{host: strings.ToLower(string(h)), id: m.EtEID}, e == nil
+		}
+	}
+	return dupKey{}, false
+}
+
+// rcvDuplicate records request m and returns true if m is duplicate
+// and it is answered or waits answer by DuplicateReplay.
+func (c *Conn) rcvDuplicate(m RawMsg) bool {
+	if DuplicateCheck == DuplicateOff {
+		return false
+	}
+	k, ok := dupKeyOf(m)
+	if !ok {
+		return false
+	}
+	now := c.clock.Now()
+
+	dupCache.Lock()
+	expireDuplicate(now)
+	d, ok := dupCache.m[k]
+	if !ok || !m.FlgT || now.Sub(d.at) > DuplicateWindow {
+		dupCache.m[k] = &dupEntry{at: now}
+		dupCache.order = append(dupCache.order, dupRef{k: k, at: now})
+		dupCache.Unlock()
+		return false
+	}
+	c.stats.Duplicate.Add(1)
+
+	if DuplicateCheck == DuplicateMark {
+		dupCache.Unlock()
+		// marked request that is not answered is also expired
+		c.dups.Range(func(k, v any) bool {
+			if now.Sub(v.(time.Time)) > DuplicateWindow {
+				c.dups.Delete(k)
+			}
+			return true
+		})
+		c.dups.Store(m.HbHID, now)
+		return false
+	}
+	if d.ans.Code == 0 {
+		d.wait = append(d.wait, dupWaiter{c: c, hbh: m.HbHID})
+		dupCache.Unlock()
+		return true
+	}
+	a := d.ans.Clone()
+	dupCache.Unlock()
+
+	a.HbHID = m.HbHID
+	e := c.write(a)
+	publish(MessageEvent{newMessageInfo(c, Tx, a, e)})
+	if e != nil {
+		c.con.Close()
+	}
+	return true
+}
+
+// sndDuplicate records answer a of request m,
+// and sends it to duplicate requests that wait the answer.
+func sndDuplicate(m, a RawMsg) {
+	if DuplicateCheck == DuplicateOff {
+		return
+	}
+	k, ok := dupKeyOf(m)
+	if !ok {
+		return
+	}
+
+	dupCache.Lock()
+	d, ok := dupCache.m[k]
+	if !ok {
+		dupCache.Unlock()
+		return
+	}
+	d.ans = a.Clone()
+	w := d.wait
+	d.wait = nil
+	dupCache.Unlock()
+
+	for _, v := range w {
+		r := a.Clone()
+		r.HbHID = v.hbh
+		v.c.sndMsg(r, priorityOf(r, priorityOf(m, NoDRMP.Level())))
+	}
+}
+
+// isDuplicate returns true if request with hbh is marked as duplicate
+// in DuplicateWindow
+func (c *Conn) isDuplicate(hbh uint32) bool {
+	v, ok := c.dups.LoadAndDelete(hbh)
+	return ok && c.clock.Now().Sub(v.(time.Time)) <= DuplicateWindow
+}
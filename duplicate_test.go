@@ -0,0 +1,119 @@
+package diameter_test
+
+import (
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+	dt "github.com/fkgi/diameter/diametertest"
+)
+
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    dia.DuplicateMode
+		flgT    bool
+		advance time.Duration
+		replay  bool // answered without Recieve
+		marked  bool // Recieve returns DuplicateRequest
+	}{
+		{"off", dia.DuplicateOff, true, 0, false, false},
+		{"replay", dia.DuplicateReplay, true, 0, true, false},
+		{"replay without T flag", dia.DuplicateReplay, false, 0, false, false},
+		{"replay after window", dia.DuplicateReplay, true, time.Second * 2, false, false},
+		{"mark", dia.DuplicateMark, true, 0, false, true},
+		{"mark after window", dia.DuplicateMark, true, time.Second * 2, false, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt.Setup(t)
+			clk := dt.NewClock(time.Unix(0, 0))
+			dt.UseClock(t, clk)
+			mode, window := dia.DuplicateCheck, dia.DuplicateWindow
+			dia.DuplicateCheck, dia.DuplicateWindow = tt.mode, time.Second
+			t.Cleanup(func() { dia.DuplicateCheck, dia.DuplicateWindow = mode, window })
+			c, p := dt.Accept(t)
+
+			m := p.Request()
+			m.EtEID = uint32(0x3900 + i)
+			p.Write(m)
+			r, f, e := c.Recieve()
+			if e != nil {
+				t.Fatal(e)
+			}
+			f(r.Failed(dia.ResultCode(dia.DiameterSuccess)))
+			a := p.Expect(t, dt.AppID, dt.CommandCode, false)
+
+			clk.Advance(tt.advance)
+			m.FlgT = tt.flgT
+			m.HbHID = a.HbHID + 1
+			p.Write(m)
+			if !tt.replay {
+				r, f, e = c.Recieve()
+				if _, ok := e.(dia.DuplicateRequest); ok != tt.marked {
+					t.Fatalf("error is %v", e)
+				}
+				f(r.Failed(dia.ResultCode(dia.DiameterUnableToComply)))
+			}
+			a2 := p.Expect(t, dt.AppID, dt.CommandCode, false)
+			if a2.HbHID != m.HbHID || a2.EtEID != m.EtEID {
+				t.Fatalf("answer ID is %d/%d, want %d/%d",
+					a2.HbHID, a2.EtEID, m.HbHID, m.EtEID)
+			}
+			want := dia.ResultCode(dia.DiameterUnableToComply)
+			if tt.replay {
+				want = dia.ResultCode(dia.DiameterSuccess)
+			}
+			dt.AssertResult(t, a2, want)
+
+			n := uint64(0)
+			if tt.replay || tt.marked {
+				n = 1
+			}
+			if d := c.Stats().Duplicate; d != n {
+				t.Fatalf("duplicate count is %d, want %d", d, n)
+			}
+		})
+	}
+}
+
+func TestDuplicateWait(t *testing.T) {
+	dt.Setup(t)
+	mode := dia.DuplicateCheck
+	dia.DuplicateCheck = dia.DuplicateReplay
+	t.Cleanup(func() { dia.DuplicateCheck = mode })
+	c, p := dt.Accept(t)
+
+	m := p.Request()
+	m.EtEID = 0x3999
+	p.Write(m)
+	r, f, e := c.Recieve()
+	if e != nil {
+		t.Fatal(e)
+	}
+	dup := m
+	dup.FlgT = true
+	dup.HbHID = 0x3999
+	p.Write(dup)
+	for deadline := time.Now().Add(dt.Timeout); c.Stats().Duplicate != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("duplicate request is not detected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// duplicate request waits answer of original request
+	f(r.Failed(dia.ResultCode(dia.DiameterSuccess)))
+	ids := map[uint32]bool{}
+	for i := 0; i < 2; i++ {
+		a := p.Expect(t, dt.AppID, dt.CommandCode, false)
+		dt.AssertResult(t, a, dia.ResultCode(dia.DiameterSuccess))
+		ids[a.HbHID] = true
+	}
+	if !ids[dup.HbHID] || len(ids) != 2 {
+		t.Fatalf("answers are %v", ids)
+	}
+	if n := c.RxQueue(); n != 0 {
+		t.Fatalf("queued requests are %d", n)
+	}
+}
@@ -16,27 +16,58 @@ func (e InvalidMessage) Error() string {
 	switch uint32(e) {
 	case DiameterUnsupportedVersion:
 		return "unsupported verion"
-	case DiameterInvalidHdrBits:
+	case DiameterInvalidHdrBits, DiameterInvalidBitInHeader:
 		return "invalid header bit"
+	case DiameterInvalidMessageLength:
+		return "invalid message length"
+	case DiameterCommandUnspported:
+		return "unsupported command"
+	case DiameterApplicationUnsupported:
+		return "unsupported application"
+	case DiameterTooBusy:
+		return "receive queue is full"
 	}
 	return "invalid message"
 }
 
-// InvalidAVP is error of invalid AVP value
-type InvalidAVP uint32
+// InvalidAVP is error of invalid AVP value.
+// AVP is the offending AVP that is set to Failed-AVP of error answer.
+type InvalidAVP struct {
+	Code uint32
+	AVP  RawAVP
+}
 
 func (e InvalidAVP) Error() string {
-	switch uint32(e) {
+	switch e.Code {
 	case DiameterInvalidAvpBits:
-		return "invalid AVP Bits"
+		return fmt.Sprintf("invalid AVP Bits: code=%d", e.AVP.Code)
 	case DiameterInvalidAvpValue:
-		return "invalid AVP Value"
+		return fmt.Sprintf("invalid AVP Value: code=%d", e.AVP.Code)
+	case DiameterInvalidAvpLength:
+		return fmt.Sprintf("invalid AVP Length: code=%d", e.AVP.Code)
 	case DiameterMissingAvp:
-		return "missing mandatory AVP"
+		return fmt.Sprintf("missing mandatory AVP: code=%d", e.AVP.Code)
 	}
 	return "invalid AVP"
 }
 
+// MissingAVP returns InvalidAVP of missing AVP with vendor and code.
+// The AVP has zero value of minimum length for the type in dictionary.
+func MissingAVP(vendor, code uint32) InvalidAVP {
+	a := RawAVP{Code: code, VenID: vendor, FlgV: vendor != 0, FlgM: true}
+	if d, ok := LookupAVP(vendor, code); ok {
+		switch d.Type {
+		case Integer32, Unsigned32, Float32, Time, EnumeratedType:
+			a.data = make([]byte, 4)
+		case Integer64, Unsigned64, Float64:
+			a.data = make([]byte, 8)
+		case Address:
+			a.data = []byte{0, 1, 0, 0, 0, 0}
+		}
+	}
+	return InvalidAVP{Code: DiameterMissingAvp, AVP: a}
+}
+
 // UnknownIDAnswer is error
 type UnknownIDAnswer struct {
 	RawMsg
@@ -52,12 +83,7 @@ type FailureAnswer struct {
 }
 
 func (e FailureAnswer) Error() string {
-	r := e.Answer.Result()
-	if r > 10000 {
-		return fmt.Sprintf("Answer message with failure: code=%d (vendor=%d)",
-			r%10000, r/10000)
-	}
-	return fmt.Sprintf("Answer message with failure: code=%d", r)
+	return fmt.Sprintf("Answer message with failure: %s", e.Answer.Result())
 }
 
 // NotAcceptableEvent is error
@@ -84,3 +110,53 @@ type ConnectionRefused struct{}
 func (e ConnectionRefused) Error() string {
 	return "connection is refused"
 }
+
+// ElectionLost is error of connection that is closed by election
+type ElectionLost struct{}
+
+func (e ElectionLost) Error() string {
+	return "connection is closed by election"
+}
+
+// TimeoutExpired is error
+type TimeoutExpired struct{}
+
+func (e TimeoutExpired) Error() string {
+	return "timeout is expired"
+}
+
+// AdmissionDenied is error of CER that is rejected by admission policy.
+// Code is Result-Code of CEA.
+type AdmissionDenied struct {
+	Code   uint32
+	Reason string
+}
+
+func (e AdmissionDenied) Error() string {
+	return fmt.Sprintf("admission denied: %s", e.Reason)
+}
+
+// DiscoveryFailed is error of peer discovery that has no candidate
+type DiscoveryFailed struct {
+	Realm Identity
+}
+
+func (e DiscoveryFailed) Error() string {
+	return fmt.Sprintf("no peer is discovered for realm %s", e.Realm)
+}
+
+// WriteQueueFull is error of message that is not sent
+// because write queue of Conn is full
+type WriteQueueFull struct{}
+
+func (e WriteQueueFull) Error() string {
+	return "write queue is full"
+}
+
+// DuplicateRequest is error of request that has same Origin-Host and
+// End-to-End ID with other request
+type DuplicateRequest struct{}
+
+func (e DuplicateRequest) Error() string {
+	return "duplicate request"
+}
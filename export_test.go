@@ -0,0 +1,29 @@
+package diameter
+
+import "net"
+
+type addrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.addr }
+
+// AdmitFrom calls p.Admit with CER r that is received from address a
+func AdmitFrom(p Policy, r CER, a net.Addr) error {
+	fsmLock.Lock()
+	defer fsmLock.Unlock()
+	return p.Admit(r, &Conn{con: addrConn{addr: a}})
+}
+
+var (
+	ParseNAPTR = parseNAPTR
+	DNSName    = dnsName
+)
+
+func (h *TopologyHiding) HideRequest(m RawMsg) RawMsg    { return h.hideRequest(m) }
+func (h *TopologyHiding) RevealAnswer(m RawMsg) RawMsg   { return h.revealAnswer(m) }
+func (h *TopologyHiding) RevealRequest(m RawMsg) RawMsg  { return h.revealRequest(m) }
+func (h *TopologyHiding) HideAnswer(m RawMsg) RawMsg     { return h.hideAnswer(m) }
+func (h *TopologyHiding) Seal(v Identity) []byte         { h.init(); return h.seal(v) }
+func (h *TopologyHiding) Open(s []byte) (Identity, bool) { h.init(); return h.open(s) }
@@ -0,0 +1,294 @@
+package diameter
+
+import "sync"
+
+type state int
+
+func (s state) String() string {
+	switch s {
+	case shutdown:
+		return "Shutdown"
+	case closed:
+		return "Closed"
+	case waitConnAck:
+		return "Wait-Conn-Ack"
+	case waitICEA:
+		return "Wait-I-CEA"
+	case waitConnAckElect:
+		return "Wait-Conn-Ack/Elect"
+	case waitReturns:
+		return "Wait-Returns"
+	case rOpen:
+		return "R-Open"
+	case iOpen:
+		return "I-Open"
+	case closing:
+		return "Closing"
+	}
+	return "<nil>"
+}
+
+func (s state) isOpen() bool {
+	return s == rOpen || s == iOpen
+}
+
+const (
+	shutdown state = iota
+	closed
+	waitConnAck
+	waitICEA
+	waitConnAckElect
+	waitReturns
+	rOpen
+	iOpen
+	closing
+)
+
+type transition struct {
+	action []string
+	next   state
+}
+
+// stateTable is peer state machine of RFC 6733 section 5.6
+var stateTable = map[state]map[string]transition{
+	closed: {
+		"Start":      {[]string{"I-Snd-Conn-Req"}, waitConnAck},
+		"R-Conn-CER": {[]string{"R-Accept", "Process-CER", "R-Snd-CEA"}, rOpen}},
+	waitConnAck: {
+		"I-Rcv-Conn-Ack":  {[]string{"I-Snd-CER"}, waitICEA},
+		"I-Rcv-Conn-Nack": {[]string{"Cleanup"}, closed},
+		"R-Conn-CER":      {[]string{"R-Accept", "Process-CER"}, waitConnAckElect},
+		"Timeout":         {[]string{"Error"}, closed}},
+	waitICEA: {
+		"I-Rcv-CEA":     {[]string{"Process-CEA"}, iOpen},
+		"R-Conn-CER":    {[]string{"R-Accept", "Process-CER", "Elect"}, waitReturns},
+		"I-Peer-Disc":   {[]string{"I-Disc"}, closed},
+		"I-Rcv-Non-CEA": {[]string{"Error"}, closed},
+		"Timeout":       {[]string{"Error"}, closed}},
+	waitConnAckElect: {
+		"I-Rcv-Conn-Ack":  {[]string{"I-Snd-CER", "Elect"}, waitReturns},
+		"I-Rcv-Conn-Nack": {[]string{"R-Snd-CEA"}, rOpen},
+		"R-Peer-Disc":     {[]string{"R-Disc"}, waitConnAck},
+		"R-Conn-CER":      {[]string{"R-Reject"}, waitConnAckElect},
+		"Timeout":         {[]string{"Error"}, closed}},
+	waitReturns: {
+		"Win-Election": {[]string{"I-Disc", "R-Snd-CEA"}, rOpen},
+		"I-Peer-Disc":  {[]string{"I-Disc", "R-Snd-CEA"}, rOpen},
+		"I-Rcv-CEA":    {[]string{"R-Disc"}, iOpen},
+		"R-Peer-Disc":  {[]string{"R-Disc"}, waitICEA},
+		"R-Conn-CER":   {[]string{"R-Reject"}, waitReturns},
+		"Timeout":      {[]string{"Error"}, closed}},
+	rOpen: {
+		"Send-Message":  {[]string{"R-Snd-Message"}, rOpen},
+		"R-Rcv-Message": {[]string{"Process"}, rOpen},
+		"R-Rcv-DWR":     {[]string{"Process-DWR", "R-Snd-DWA"}, rOpen},
+		"R-Rcv-DWA":     {[]string{"Process-DWA"}, rOpen},
+		"R-Conn-CER":    {[]string{"R-Reject"}, rOpen},
+		"Stop":          {[]string{"R-Snd-DPR"}, closing},
+		"R-Rcv-DPR":     {[]string{"R-Snd-DPA"}, closed},
+		"R-Peer-Disc":   {[]string{"R-Disc"}, closed}},
+	iOpen: {
+		"Send-Message":  {[]string{"I-Snd-Message"}, iOpen},
+		"I-Rcv-Message": {[]string{"Process"}, iOpen},
+		"I-Rcv-DWR":     {[]string{"Process-DWR", "I-Snd-DWA"}, iOpen},
+		"I-Rcv-DWA":     {[]string{"Process-DWA"}, iOpen},
+		"R-Conn-CER":    {[]string{"R-Reject"}, iOpen},
+		"Stop":          {[]string{"I-Snd-DPR"}, closing},
+		"I-Rcv-DPR":     {[]string{"I-Snd-DPA"}, closed},
+		"I-Peer-Disc":   {[]string{"I-Disc"}, closed}},
+	closing: {
+		"I-Rcv-DPA":   {[]string{"I-Disc"}, closed},
+		"R-Rcv-DPA":   {[]string{"R-Disc"}, closed},
+		"Timeout":     {[]string{"Error"}, closed},
+		"I-Peer-Disc": {[]string{"I-Disc"}, closed},
+		"R-Peer-Disc": {[]string{"R-Disc"}, closed}},
+}
+
+// PeerTransition returns actions and next state of event ev in state s
+// by peer state machine of RFC 6733 section 5.6.
+// ok is false if the event is not defined in the state.
+func PeerTransition(s, ev string) (action []string, next string, ok bool) {
+	for k, v := range stateTable {
+		if k.String() != s {
+			continue
+		}
+		if t, ok := v[ev]; ok {
+			return append([]string{}, t.action...), t.next.String(), true
+		}
+	}
+	return nil, "", false
+}
+
+// fsmLock protects state transition of Conn that is not open.
+// Initiator and responder Conn of same peer are changed together
+// in election, so they are guarded by this lock.
+var fsmLock sync.Mutex
+
+// fsmPeers is Conns that is not closed, by peer host
+var fsmPeers = make(map[Identity][]*Conn)
+
+func (c *Conn) getState() state {
+	return state(c.st.Load())
+}
+
+// setState change state of c, fsmLock must be held
+func (c *Conn) setState(s state) {
+	c.st.Store(int32(s))
+	if c.Peer == nil {
+		return
+	}
+	l := fsmPeers[c.Peer.Host]
+	for i, v := range l {
+		if v == c {
+			l = append(l[:i], l[i+1:]...)
+			break
+		}
+	}
+	if s != closed && s != shutdown {
+		l = append(l, c)
+	}
+	if len(l) == 0 {
+		delete(fsmPeers, c.Peer.Host)
+	} else {
+		fsmPeers[c.Peer.Host] = l
+	}
+}
+
+// findPeer returns other Conn with host, fsmLock must be held.
+// Open Conn is ignored if MultiConn or MultiConn of the Peer is true.
+func findPeer(host Identity, c *Conn) *Conn {
+	multi := MultiConn || (c.Peer != nil && c.Peer.MultiConn)
+	for _, v := range fsmPeers[host] {
+		if v == c {
+			continue
+		}
+		if (multi || v.Peer.MultiConn) &&
+			(v.getState().isOpen() || v.getState() == closing) {
+			continue
+		}
+		return v
+	}
+	return nil
+}
+
+// transit returns transition of event name in current state
+func (c *Conn) transit(ev stateEvent, name string) (transition, error) {
+	s := c.getState()
+	if t, ok := stateTable[s][name]; ok {
+		return t, nil
+	}
+	return transition{}, NotAcceptableEvent{stateEvent: ev, state: s}
+}
+
+// role returns prefix of event and action
+func (c *Conn) role() string {
+	if c.initiator {
+		return "I-"
+	}
+	return "R-"
+}
+
+// handshake notify result of capability exchange to Dial or Accept
+func (c *Conn) handshake(e error) {
+	select {
+	case c.ready <- e:
+	default:
+	}
+}
+
+// raise sends event to event handler of c if it is running
+func (c *Conn) raise(ev stateEvent) {
+	select {
+	case c.notify <- ev:
+	case <-c.done:
+	}
+}
+
+// opened starts watchdog of open Conn
+func (c *Conn) opened(s state) {
+	c.setState(s)
+	c.wdTimer = c.clock.AfterFunc(c.Peer.WDInterval, c.watchdog)
+	c.Since = c.clock.Now()
+	c.handshake(nil)
+}
+
+// disc close transport connection of c, fsmLock must be held
+func (c *Conn) disc(e error) {
+	c.flush(TransportTimeout)
+	c.con.Close()
+	c.setState(closed)
+	c.unpair()
+	c.handshake(e)
+}
+
+func (c *Conn) unpair() {
+	if c.pair != nil {
+		c.pair.pair = nil
+		c.pair = nil
+	}
+}
+
+// elect compares Origin-Host and raise Win-Election if local host win.
+// It runs on responder Conn with received CER, fsmLock must be held.
+func (c *Conn) elect() {
+	if string(Host) <= string(c.Peer.Host) {
+		return
+	}
+	old := c.getState()
+	if i := c.pair; i != nil {
+		iold := i.getState()
+		i.disc(ElectionLost{})
+		publish(newStateUpdate(iold, eventWinElection{}, i, ElectionLost{}))
+	}
+	c.sndCEA()
+	publish(newStateUpdate(old, eventWinElection{}, c, nil))
+}
+
+// sndCEA sends CEA that is made in Process-CER, fsmLock must be held
+func (c *Conn) sndCEA() {
+	c.unpair()
+	e := c.write(c.cea)
+	publish(CapabilityExchangeEvent{newMessageInfo(c, Tx, c.cea, e)})
+	if e != nil {
+		c.disc(e)
+	} else {
+		c.opened(rOpen)
+	}
+}
+
+// rDisc sends CEA with DIAMETER_ELECTION_LOST and disconnect
+// responder Conn, fsmLock must be held
+func (c *Conn) rDisc(ev stateEvent) {
+	old := c.getState()
+	m := c.cea.Clone()
+	for i, a := range m.AVP {
+		if a.VenID == 0 && a.Code == 268 {
+			m.AVP[i] = SetResultCode(DiameterElectionLost)
+		}
+	}
+	e := c.write(m)
+	publish(CapabilityExchangeEvent{newMessageInfo(c, Tx, m, e)})
+	c.disc(ElectionLost{})
+	publish(newStateUpdate(old, ev, c, ElectionLost{}))
+}
+
+// Win-Election
+type eventWinElection struct{}
+
+func (eventWinElection) String() string {
+	return "Win-Election"
+}
+
+func (v eventWinElection) exec(c *Conn) error {
+	return NotAcceptableEvent{stateEvent: v, state: c.getState()}
+}
+
+// I-Rcv-Non-CEA
+func (c *Conn) rcvNonCEA(ev stateEvent) error {
+	e := NotAcceptableEvent{stateEvent: ev, state: c.getState()}
+	if _, e2 := c.transit(ev, "I-Rcv-Non-CEA"); e2 != nil {
+		return e2
+	}
+	c.disc(e)
+	return e
+}
@@ -7,6 +7,8 @@ import (
 
 // GenericReq is generic format of diameter request
 type GenericReq struct {
+	DRMP DRMP
+
 	FlgP     bool   // Proxiable
 	FlgT     bool   // Potentially re-transmitted message
 	Code     uint32 // Command-Code (24bit)
@@ -45,6 +47,9 @@ func (v GenericReq) ToRaw(s string) RawMsg {
 		AVP: make([]RawAVP, 0, len(v.AVP)+6)}
 
 	m.AVP = append(m.AVP, SetSessionID(s))
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetVendorSpecAppID(v.VenID, v.AppID))
 	m.AVP = append(m.AVP, SetAuthSessionState(v.Stateful))
 
@@ -77,6 +82,8 @@ func (GenericReq) FromRaw(m RawMsg) (Request, string, error) {
 		AVP: make([]RawAVP, 0, len(m.AVP))}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 263:
 			s, e = GetSessionID(a)
 		case 260:
@@ -108,26 +115,29 @@ func (GenericReq) FromRaw(m RawMsg) (Request, string, error) {
 }
 
 // Failed make error message for timeout
-func (v GenericReq) Failed(c uint32) Answer {
+func (v GenericReq) Failed(r Result) Answer {
 	return GenericAns{
+		DRMP:        v.DRMP,
 		FlgP:        v.FlgP,
 		Code:        v.Code,
 		AppID:       v.AppID,
 		Stateful:    v.Stateful,
-		ResultCode:  c,
+		ResultCode:  r,
 		OriginHost:  Host,
 		OriginRealm: Realm}
 }
 
 // GenericAns is generic format of diameter request
 type GenericAns struct {
+	DRMP DRMP
+
 	FlgP     bool   // Proxiable
 	Code     uint32 // Command-Code (24bit)
 	VenID    uint32
 	AppID    uint32 // Application-ID
 	Stateful bool
 
-	ResultCode  uint32
+	ResultCode  Result
 	OriginHost  Identity
 	OriginRealm Identity
 
@@ -137,7 +147,7 @@ type GenericAns struct {
 func (v GenericAns) String() string {
 	w := new(bytes.Buffer)
 
-	fmt.Fprintf(w, "%sResult-Code     =%d\n", Indent, v.ResultCode)
+	fmt.Fprintf(w, "%sResult-Code     =%s\n", Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host     =%s\n", Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm    =%s\n", Indent, v.OriginRealm)
 	for i, avp := range v.AVP {
@@ -155,8 +165,11 @@ func (v GenericAns) ToRaw(s string) RawMsg {
 		Code: v.Code, AppID: v.AppID,
 		AVP: make([]RawAVP, 0, len(v.AVP)+6)}
 
-	m.AVP = append(m.AVP, SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, SetSessionID(s))
+	if v.DRMP != NoDRMP {
+		m.AVP = append(m.AVP, SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, SetVendorSpecAppID(v.VenID, v.AppID))
 	m.AVP = append(m.AVP, SetAuthSessionState(v.Stateful))
 
@@ -184,8 +197,10 @@ func (GenericAns) FromRaw(m RawMsg) (Answer, string, error) {
 		AVP: make([]RawAVP, 0, len(m.AVP))}
 	for _, a := range m.AVP {
 		switch a.Code {
+		case 301:
+			v.DRMP, e = GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = GetResultCode(a)
+			v.ResultCode, e = GetResult(a)
 		case 263:
 			s, e = GetSessionID(a)
 		case 260:
@@ -213,6 +228,246 @@ func (GenericAns) FromRaw(m RawMsg) (Answer, string, error) {
 }
 
 // Result returns result-code
-func (v GenericAns) Result() uint32 {
+func (v GenericAns) Result() Result {
 	return v.ResultCode
 }
+
+/*
+ErrorAns is answer-message for error, E-bit is set if protocol error
+ <answer-message> ::= < Diameter Header: code, ERR [, PXY] >
+			0*1< Session-Id >
+			   { Origin-Host }
+			   { Origin-Realm }
+			   { Result-Code }
+			   [ Origin-State-Id ]
+			   [ Error-Message ]
+			   [ Error-Reporting-Host ]
+			   [ Failed-AVP ]
+			   [ Experimental-Result ] // not supported
+			 * [ Proxy-Info ] // not supported
+			 * [ AVP ]
+*/
+type ErrorAns struct {
+	FlgP  bool   // Proxiable
+	Code  uint32 // Command-Code (24bit)
+	AppID uint32 // Application-ID
+
+	ResultCode         uint32
+	OriginHost         Identity
+	OriginRealm        Identity
+	OriginStateID      uint32
+	ErrorMessage       string
+	ErrorReportingHost Identity
+	FailedAVP          []RawAVP
+}
+
+func (v ErrorAns) String() string {
+	w := new(bytes.Buffer)
+
+	fmt.Fprintf(w, "%sResult-Code     =%d\n", Indent, v.ResultCode)
+	fmt.Fprintf(w, "%sOrigin-Host     =%s\n", Indent, v.OriginHost)
+	fmt.Fprintf(w, "%sOrigin-Realm    =%s\n", Indent, v.OriginRealm)
+	fmt.Fprintf(w, "%sOrigin-State-ID =%d\n", Indent, v.OriginStateID)
+	fmt.Fprintf(w, "%sError-Message   =%s\n", Indent, v.ErrorMessage)
+	fmt.Fprintf(w, "%sError-Reporting-Host =%s\n", Indent, v.ErrorReportingHost)
+	for _, avp := range v.FailedAVP {
+		fmt.Fprintf(w, "%sFailed-AVP      =\n%s", Indent, avp)
+	}
+
+	return w.String()
+}
+
+// ToRaw return RawMsg struct of this value
+func (v ErrorAns) ToRaw(s string) RawMsg {
+	m := RawMsg{
+		Ver:  DiaVer,
+		FlgR: false, FlgP: v.FlgP, FlgE: v.Result().Class() == ProtocolError,
+		FlgT: false, Code: v.Code, AppID: v.AppID,
+		AVP: make([]RawAVP, 0, 8)}
+
+	if len(s) != 0 {
+		m.AVP = append(m.AVP, SetSessionID(s))
+	}
+	m.AVP = append(m.AVP, SetOriginHost(v.OriginHost))
+	m.AVP = append(m.AVP, SetOriginRealm(v.OriginRealm))
+	m.AVP = append(m.AVP, SetResultCode(v.ResultCode))
+	if v.OriginStateID != 0 {
+		m.AVP = append(m.AVP, setOriginStateID(v.OriginStateID))
+	}
+	if len(v.ErrorMessage) != 0 {
+		m.AVP = append(m.AVP, setErrorMessage(v.ErrorMessage))
+	}
+	if len(v.ErrorReportingHost) != 0 {
+		m.AVP = append(m.AVP, setErrorReportingHost(v.ErrorReportingHost))
+	}
+	if len(v.FailedAVP) != 0 {
+		m.AVP = append(m.AVP, setFailedAVP(v.FailedAVP))
+	}
+	return m
+}
+
+// FromRaw make this value from RawMsg struct
+func (ErrorAns) FromRaw(m RawMsg) (Answer, string, error) {
+	e := m.Validate(false, m.FlgP, true, false)
+	if e != nil {
+		return nil, "", e
+	}
+
+	var s string
+	v := ErrorAns{
+		FlgP: m.FlgP, Code: m.Code, AppID: m.AppID}
+	for _, a := range m.AVP {
+		switch a.Code {
+		case 263:
+			s, e = GetSessionID(a)
+		case 264:
+			v.OriginHost, e = GetOriginHost(a)
+		case 296:
+			v.OriginRealm, e = GetOriginRealm(a)
+		case 268:
+			v.ResultCode, e = GetResultCode(a)
+		case 278:
+			v.OriginStateID, e = getOriginStateID(a)
+		case 281:
+			v.ErrorMessage, e = getErrorMessage(a)
+		case 294:
+			v.ErrorReportingHost, e = getErrorReportingHost(a)
+		case 279:
+			v.FailedAVP, e = getFailedAVP(a)
+		}
+		if e != nil {
+			return nil, "", e
+		}
+	}
+	if v.ResultCode == 0 {
+		e = MissingAVP(0, 268)
+	} else if len(v.OriginHost) == 0 {
+		e = MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = MissingAVP(0, 296)
+	}
+	return v, s, e
+}
+
+// Result returns result-code
+func (v ErrorAns) Result() Result {
+	return ResultCode(v.ResultCode)
+}
+
+// MakeErrorAns returns answer-message for request m that failed by error e
+func MakeErrorAns(m RawMsg, e error) ErrorAns {
+	v := ErrorAns{
+		FlgP:          m.FlgP,
+		Code:          m.Code,
+		AppID:         m.AppID,
+		ResultCode:    DiameterUnableToComply,
+		OriginHost:    Host,
+		OriginRealm:   Realm,
+		OriginStateID: StateID}
+
+	switch err := e.(type) {
+	case InvalidMessage:
+		v.ResultCode = uint32(err)
+	case InvalidAVP:
+		v.ResultCode = err.Code
+		if err.AVP.Code != 0 {
+			v.FailedAVP = []RawAVP{err.AVP}
+		}
+	}
+	if e != nil {
+		v.ErrorMessage = e.Error()
+	}
+	return v
+}
+
+func errorAnswer(m RawMsg, e error) RawMsg {
+	a := MakeErrorAns(m, e).ToRaw(sessionIDOf(m))
+	a.HbHID = m.HbHID
+	a.EtEID = m.EtEID
+	return a
+}
+
+// RawReq is Request that is sent as RawMsg template.
+// Session-Id, Origin-Host and Origin-Realm are replaced by local value.
+type RawReq struct {
+	RawMsg
+}
+
+// ToRaw return RawMsg struct of this value
+func (v RawReq) ToRaw(s string) RawMsg {
+	m := v.RawMsg.Clone()
+	m.Ver = DiaVer
+	m.FlgR = true
+	sid := false
+	for i, a := range m.AVP {
+		if a.VenID != 0 {
+			continue
+		}
+		switch a.Code {
+		case 263:
+			m.AVP[i] = SetSessionID(s)
+			sid = true
+		case 264:
+			m.AVP[i] = SetOriginHost(Host)
+		case 296:
+			m.AVP[i] = SetOriginRealm(Realm)
+		}
+	}
+	if !sid && len(s) != 0 {
+		m.AVP = append([]RawAVP{SetSessionID(s)}, m.AVP...)
+	}
+	return m
+}
+
+// FromRaw make this value from RawMsg struct
+func (RawReq) FromRaw(m RawMsg) (Request, string, error) {
+	return RawReq{m}, sessionIDOf(m), nil
+}
+
+// Failed make error message for timeout
+func (v RawReq) Failed(r Result) Answer {
+	return RawAns{RawMsg{
+		Ver: DiaVer, FlgP: v.FlgP, Code: v.Code, AppID: v.AppID,
+		AVP: []RawAVP{
+			SetResult(r),
+			SetOriginHost(Host),
+			SetOriginRealm(Realm)}}}
+}
+
+// RawAns is Answer that hold RawMsg as is
+type RawAns struct {
+	RawMsg
+}
+
+// ToRaw return RawMsg struct of this value
+func (v RawAns) ToRaw(s string) RawMsg {
+	m := v.RawMsg.Clone()
+	m.Ver = DiaVer
+	m.FlgR = false
+	for i, a := range m.AVP {
+		if a.Code == 263 && a.VenID == 0 {
+			m.AVP[i] = SetSessionID(s)
+		}
+	}
+	return m
+}
+
+// FromRaw make this value from RawMsg struct
+func (RawAns) FromRaw(m RawMsg) (Answer, string, error) {
+	return RawAns{m}, sessionIDOf(m), nil
+}
+
+// Result returns result-code
+func (v RawAns) Result() Result {
+	return resultOf(v.RawMsg)
+}
+
+func sessionIDOf(m RawMsg) string {
+	for _, a := range m.AVP {
+		if a.Code == 263 && a.VenID == 0 {
+			s, _ := GetSessionID(a)
+			return s
+		}
+	}
+	return ""
+}
@@ -9,17 +9,10 @@ import (
 var MakeCER = defaultMakeCER
 
 func defaultMakeCER(c *Conn) CER {
-	ips := make([]net.IP, 0, 2)
-	s := c.con.LocalAddr().String()
-	s, _, _ = net.SplitHostPort(s)
-	for _, i := range strings.Split(s, "/") {
-		ips = append(ips, net.ParseIP(i))
-	}
-
 	return CER{
 		OriginHost:       Host,
 		OriginRealm:      Realm,
-		HostIPAddress:    ips,
+		HostIPAddress:    localIPs(c),
 		VendorID:         VendorID,
 		ProductName:      ProductName,
 		OriginStateID:    StateID,
@@ -31,13 +24,6 @@ func defaultMakeCER(c *Conn) CER {
 var HandleCER = defaultHandleCER
 
 func defaultHandleCER(r CER, c *Conn) CEA {
-	ips := make([]net.IP, 0, 2)
-	s := c.con.LocalAddr().String()
-	s, _, _ = net.SplitHostPort(s)
-	for _, i := range strings.Split(s, "/") {
-		ips = append(ips, net.ParseIP(i))
-	}
-
 	result := DiameterSuccess
 	if c.Peer == nil {
 		c.Peer = &Peer{Host: r.OriginHost, Realm: r.OriginRealm}
@@ -46,7 +32,7 @@ func defaultHandleCER(r CER, c *Conn) CEA {
 	}
 
 	if result == DiameterSuccess {
-		if _, ok := supportedApps[0xffffffff]; ok && c.Peer.AuthApps == nil {
+		if _, ok := supportedApps()[0xffffffff]; ok && c.Peer.AuthApps == nil {
 			c.Peer.AuthApps = r.ApplicationID
 		} else {
 			apps := c.Peer.AuthApps
@@ -86,7 +72,7 @@ func defaultHandleCER(r CER, c *Conn) CEA {
 		ResultCode:       result,
 		OriginHost:       Host,
 		OriginRealm:      Realm,
-		HostIPAddress:    ips,
+		HostIPAddress:    localIPs(c),
 		VendorID:         VendorID,
 		ProductName:      ProductName,
 		OriginStateID:    StateID,
@@ -94,6 +80,45 @@ func defaultHandleCER(r CER, c *Conn) CEA {
 		FirmwareRevision: FirmwareRevision}
 }
 
+// rejectCEA returns CEA with failure result code r
+func rejectCEA(r uint32, c *Conn) CEA {
+	return CEA{
+		ResultCode:       r,
+		OriginHost:       Host,
+		OriginRealm:      Realm,
+		HostIPAddress:    localIPs(c),
+		VendorID:         VendorID,
+		ProductName:      ProductName,
+		OriginStateID:    StateID,
+		ApplicationID:    getSupportedApps(),
+		FirmwareRevision: FirmwareRevision}
+}
+
+// localIPs returns local address of c.
+// Loopback address is used for non IP transport like net.Pipe.
+func localIPs(c *Conn) []net.IP {
+	ips := addrIPs(c.con.LocalAddr())
+	if len(ips) == 0 {
+		ips = append(ips, net.IPv4(127, 0, 0, 1))
+	}
+	return ips
+}
+
+// addrIPs returns IP addresses of TCP or SCTP address a
+func addrIPs(a net.Addr) []net.IP {
+	ips := make([]net.IP, 0, 2)
+	if a == nil {
+		return ips
+	}
+	s, _, _ := net.SplitHostPort(a.String())
+	for _, i := range strings.Split(s, "/") {
+		if ip := net.ParseIP(i); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
 func match(a, b []uint32) []uint32 {
 	r := make([]uint32, 0, len(a))
 	for _, va := range a {
@@ -36,7 +36,7 @@ func CompareIdentity(id1, id2 Identity) int {
 	s1 := strings.ToLower(string(id1))
 	s2 := strings.ToLower(string(id2))
 
-	l := 0
+	l := len(s1)
 	r := 0
 	if len(s1) > len(s2) {
 		l = len(s2)
@@ -0,0 +1,263 @@
+package diameter
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+	"unicode/utf8"
+)
+
+type jsonMsg struct {
+	Version    uint8    `json:"version,omitempty"`
+	Request    bool     `json:"request"`
+	Proxiable  bool     `json:"proxiable,omitempty"`
+	Error      bool     `json:"error,omitempty"`
+	Retransmit bool     `json:"retransmit,omitempty"`
+	Command    uint32   `json:"command"`
+	AppID      uint32   `json:"application"`
+	HbHID      uint32   `json:"hop-by-hop,omitempty"`
+	EtEID      uint32   `json:"end-to-end,omitempty"`
+	AVP        []RawAVP `json:"avp"`
+}
+
+// MarshalJSON returns JSON text of this message
+func (m RawMsg) MarshalJSON() ([]byte, error) {
+	v := jsonMsg{
+		Version: m.Ver, Request: m.FlgR, Proxiable: m.FlgP,
+		Error: m.FlgE, Retransmit: m.FlgT,
+		Command: m.Code, AppID: m.AppID,
+		HbHID: m.HbHID, EtEID: m.EtEID,
+		AVP: m.AVP}
+	if v.AVP == nil {
+		v.AVP = []RawAVP{}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON make this message from JSON text
+func (m *RawMsg) UnmarshalJSON(b []byte) error {
+	var v jsonMsg
+	if e := json.Unmarshal(b, &v); e != nil {
+		return e
+	}
+	if v.Version == 0 {
+		v.Version = DiaVer
+	}
+	*m = RawMsg{
+		Ver: v.Version, FlgR: v.Request, FlgP: v.Proxiable,
+		FlgE: v.Error, FlgT: v.Retransmit,
+		Code: v.Command, AppID: v.AppID,
+		HbHID: v.HbHID, EtEID: v.EtEID,
+		AVP: v.AVP}
+	return nil
+}
+
+// jsonAVP is JSON form of AVP.
+// AVP in dictionary has Name and typed Value,
+// and other AVP has Code, Vendor and hex encoded Data.
+// AVP in dictionary that has invalid value, like UTF8String
+// that is not valid UTF-8, also has hex encoded Data.
+type jsonAVP struct {
+	Name      string          `json:"name,omitempty"`
+	Code      *uint32         `json:"code,omitempty"`
+	VenID     uint32          `json:"vendor,omitempty"`
+	Mandatory bool            `json:"mandatory,omitempty"`
+	Protected bool            `json:"protected,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Data      *string         `json:"data,omitempty"`
+}
+
+// MarshalJSON returns JSON text of this AVP
+func (a RawAVP) MarshalJSON() ([]byte, error) {
+	v := jsonAVP{VenID: a.VenID, Mandatory: a.FlgM, Protected: a.FlgP}
+	if d, ok := LookupAVP(a.VenID, a.Code); ok {
+		if b, e := a.jsonValue(d.Type); e == nil {
+			v.Name = d.Name
+			v.VenID = 0
+			v.Value = b
+			return json.Marshal(v)
+		}
+	}
+	c := a.Code
+	s := hex.EncodeToString(a.data)
+	v.Code = &c
+	v.Data = &s
+	return json.Marshal(v)
+}
+
+func (a RawAVP) jsonValue(t AVPType) (b []byte, e error) {
+	var v interface{}
+	switch t {
+	case OctetString:
+		v = hex.EncodeToString(a.data)
+	case Integer32:
+		var d int32
+		e = a.Decode(&d)
+		v = d
+	case Integer64:
+		var d int64
+		e = a.Decode(&d)
+		v = d
+	case Unsigned32:
+		var d uint32
+		e = a.Decode(&d)
+		v = d
+	case Unsigned64:
+		var d uint64
+		e = a.Decode(&d)
+		v = d
+	case Float32:
+		var d float32
+		e = a.Decode(&d)
+		v = d
+	case Float64:
+		var d float64
+		e = a.Decode(&d)
+		v = d
+	case Grouped:
+		var d []RawAVP
+		e = a.Decode(&d)
+		v = d
+	case Address:
+		var d net.IP
+		e = a.Decode(&d)
+		v = d.String()
+	case Time:
+		if len(a.data) != 4 {
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
+		} else {
+			s := int64(binary.BigEndian.Uint32(a.data))
+			v = time.Unix(s-2208988800, 0).UTC().Format(time.RFC3339)
+		}
+	case UTF8String, DiameterIdentity, DiameterURI:
+		if !utf8.Valid(a.data) {
+			e = InvalidAVP{Code: DiameterInvalidAvpValue, AVP: a}
+		} else {
+			v = string(a.data)
+		}
+	case EnumeratedType:
+		var d Enumerated
+		e = a.Decode(&d)
+		v = d
+	default:
+		e = &UnknownAVPType{}
+	}
+	if e == nil {
+		b, e = json.Marshal(v)
+	}
+	return
+}
+
+// UnmarshalJSON make this AVP from JSON text
+func (a *RawAVP) UnmarshalJSON(b []byte) (e error) {
+	var v jsonAVP
+	if e = json.Unmarshal(b, &v); e != nil {
+		return
+	}
+	*a = RawAVP{VenID: v.VenID, FlgM: v.Mandatory, FlgP: v.Protected}
+
+	var d AVPDefinition
+	var ok bool
+	if len(v.Name) != 0 {
+		if d, ok = LookupAVPByName(v.Name); !ok {
+			return fmt.Errorf("unknown AVP name %s", v.Name)
+		}
+		a.Code, a.VenID = d.Code, d.VenID
+	} else if v.Code != nil {
+		a.Code = *v.Code
+		d, ok = LookupAVP(a.VenID, a.Code)
+	} else {
+		return fmt.Errorf("AVP name or code is required")
+	}
+	a.FlgV = a.VenID != 0
+
+	switch {
+	case v.Data != nil:
+		a.data, e = hex.DecodeString(*v.Data)
+	case len(v.Value) == 0:
+		a.data = []byte{}
+	case !ok:
+		e = fmt.Errorf("AVP %d(vendor=%d) is not in dictionary, data is required",
+			a.Code, a.VenID)
+	default:
+		e = a.setJSONValue(d.Type, v.Value)
+	}
+	return
+}
+
+func (a *RawAVP) setJSONValue(t AVPType, b json.RawMessage) (e error) {
+	switch t {
+	case OctetString:
+		var s string
+		if e = json.Unmarshal(b, &s); e == nil {
+			a.data, e = hex.DecodeString(s)
+		}
+	case Integer32:
+		var d int32
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Integer64:
+		var d int64
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Unsigned32:
+		var d uint32
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Unsigned64:
+		var d uint64
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Float32:
+		var d float32
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Float64:
+		var d float64
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Grouped:
+		var d []RawAVP
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(d)
+		}
+	case Address:
+		var s string
+		if e = json.Unmarshal(b, &s); e != nil {
+		} else if ip := net.ParseIP(s); ip == nil {
+			e = fmt.Errorf("invalid IP address %s", s)
+		} else {
+			e = a.Encode(ip)
+		}
+	case Time:
+		var s string
+		var d time.Time
+		if e = json.Unmarshal(b, &s); e != nil {
+		} else if d, e = time.Parse(time.RFC3339, s); e == nil {
+			a.data = make([]byte, 4)
+			binary.BigEndian.PutUint32(a.data, uint32(d.Unix()+2208988800))
+		}
+	case UTF8String, DiameterIdentity, DiameterURI:
+		var s string
+		if e = json.Unmarshal(b, &s); e == nil {
+			a.data = []byte(s)
+		}
+	case EnumeratedType:
+		var d int32
+		if e = json.Unmarshal(b, &d); e == nil {
+			e = a.Encode(Enumerated(d))
+		}
+	default:
+		e = &UnknownAVPType{}
+	}
+	return
+}
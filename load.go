@@ -0,0 +1,176 @@
+package diameter
+
+import (
+	"strings"
+	"sync"
+)
+
+// Load information (RFC 8583) configuration
+var (
+	// LoadReport enables Load AVP in sent answers and DWA.
+	// Answer has PEER type Load and also HOST type Load if its Origin-Host
+	// is local host, and DWA has PEER type Load.
+	LoadReport = false
+	// LocalLoad returns load value of local host that is reported to Conn c.
+	// nil uses ratio of RxQueue and TxQueue of c to the buffer size.
+	LocalLoad func(c *Conn) uint64
+)
+
+// MaxLoad is maximum value of Load-Value AVP
+const MaxLoad uint64 = 65535
+
+// LoadType is value of Load-Type AVP
+type LoadType Enumerated
+
+const (
+	// HostLoad is HOST
+	HostLoad LoadType = 0
+	// PeerLoad is PEER
+	PeerLoad LoadType = 1
+)
+
+// Load is load report of Load AVP
+type Load struct {
+	Type     LoadType
+	Value    uint64
+	SourceID Identity
+}
+
+// SetLoad make Load AVP
+func SetLoad(v Load) (a RawAVP) {
+	a = RawAVP{Code: 650, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	lt := RawAVP{Code: 651, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	lt.Encode(Enumerated(v.Type))
+	lv := RawAVP{Code: 652, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	lv.Encode(v.Value)
+	si := RawAVP{Code: 649, VenID: 0, FlgV: false, FlgM: false, FlgP: false}
+	si.Encode(v.SourceID)
+	a.Encode([]RawAVP{lt, lv, si})
+	return
+}
+
+// GetLoad read Load AVP.
+// Load-Value that exceeds MaxLoad is MaxLoad.
+func GetLoad(a RawAVP) (v Load, e error) {
+	o := []RawAVP{}
+	if a.FlgV || a.FlgP {
+		e = InvalidAVP{Code: DiameterInvalidAvpBits, AVP: a}
+	} else {
+		e = a.Decode(&o)
+	}
+	lv := false
+	for _, a := range o {
+		if a.VenID != 0 || e != nil {
+			continue
+		}
+		switch a.Code {
+		case 651:
+			var t Enumerated
+			e = a.Decode(&t)
+			v.Type = LoadType(t)
+		case 652:
+			e = a.Decode(&v.Value)
+			lv = true
+		case 649:
+			e = a.Decode(&v.SourceID)
+		}
+	}
+	if e == nil && (!lv || len(v.SourceID) == 0) {
+		e = InvalidAVP{Code: DiameterMissingAvp, AVP: a}
+	}
+	if v.Value > MaxLoad {
+		v.Value = MaxLoad
+	}
+	return
+}
+
+// HOST type load reports that is received
+var hostLoads = struct {
+	sync.RWMutex
+	m map[string]uint64
+}{m: make(map[string]uint64)}
+
+// HostLoadOf returns load of host that is received in HOST type Load
+func HostLoadOf(host Identity) (uint64, bool) {
+	hostLoads.RLock()
+	defer hostLoads.RUnlock()
+	v, ok := hostLoads.m[strings.ToLower(string(host))]
+	return v, ok
+}
+
+// Load returns load score of the peer.
+// It is larger value of PEER type Load from the peer
+// and HOST type Load of the peer host.
+func (c *Conn) Load() uint64 {
+	v := c.load.Load()
+	if c.Peer == nil {
+		return v
+	}
+	if h, ok := HostLoadOf(c.Peer.Host); ok && h > v {
+		v = h
+	}
+	return v
+}
+
+// rcvLoad stores Load AVPs in received answer m
+func (c *Conn) rcvLoad(m RawMsg) {
+	for _, a := range m.AVP {
+		if a.VenID != 0 || a.Code != 650 {
+			continue
+		}
+		l, e := GetLoad(a)
+		if e != nil {
+			continue
+		}
+		switch l.Type {
+		case PeerLoad:
+			c.load.Store(l.Value)
+		case HostLoad:
+			hostLoads.Lock()
+			hostLoads.m[strings.ToLower(string(l.SourceID))] = l.Value
+			hostLoads.Unlock()
+		}
+	}
+}
+
+// localLoad returns load value of local host for Conn c
+func localLoad(c *Conn) uint64 {
+	if LocalLoad != nil {
+		if v := LocalLoad(c); v < MaxLoad {
+			return v
+		}
+		return MaxLoad
+	}
+	if RxBuffer+TxBuffer <= 0 {
+		return 0
+	}
+	v := uint64(c.RxQueue()+c.TxQueue()) * MaxLoad / uint64(RxBuffer+TxBuffer)
+	if v > MaxLoad {
+		v = MaxLoad
+	}
+	return v
+}
+
+// isLocalOrigin returns true if Origin-Host of m is local host
+func isLocalOrigin(m RawMsg) bool {
+	for _, a := range m.AVP {
+		if a.VenID == 0 && a.Code == 264 {
+			var v Identity
+			return a.Decode(&v) == nil && strings.EqualFold(string(v), string(Host))
+		}
+	}
+	return false
+}
+
+// sndLoad adds Load AVPs to answer a that is sent to c.
+// HOST type Load is added if host is true.
+func sndLoad(c *Conn, a *RawMsg, host bool) {
+	if !LoadReport {
+		return
+	}
+	v := localLoad(c)
+	if host {
+		a.AVP = append(a.AVP, SetLoad(Load{Type: HostLoad, Value: v, SourceID: Host}))
+	}
+	a.AVP = append(a.AVP, SetLoad(Load{Type: PeerLoad, Value: v, SourceID: Host}))
+}
@@ -0,0 +1,53 @@
+package diameter_test
+
+import (
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+)
+
+func TestGetLoad(t *testing.T) {
+	lt := uintAVP(651, dia.Enumerated(dia.PeerLoad))
+	si := dia.RawAVP{Code: 649}
+	si.Encode(dia.Identity("peer.example"))
+
+	tests := []struct {
+		name string
+		avp  dia.RawAVP
+		want dia.Load
+		err  bool
+	}{
+		{"round trip", dia.SetLoad(dia.Load{
+			Type: dia.HostLoad, Value: 100, SourceID: "host.example"}),
+			dia.Load{Type: dia.HostLoad, Value: 100, SourceID: "host.example"}, false},
+		{"default type", groupedAVP(650, uintAVP(652, uint64(10)), si),
+			dia.Load{Type: dia.HostLoad, Value: 10, SourceID: "peer.example"}, false},
+		{"max value", groupedAVP(650, lt, uintAVP(652, uint64(100000)), si),
+			dia.Load{Type: dia.PeerLoad, Value: dia.MaxLoad, SourceID: "peer.example"}, false},
+		{"no value", groupedAVP(650, lt, si), dia.Load{}, true},
+		{"no source", groupedAVP(650, lt, uintAVP(652, uint64(10))), dia.Load{}, true},
+		{"invalid value", groupedAVP(650, lt, uintAVP(652, uint32(10)), si), dia.Load{}, true},
+		{"P flag", func() dia.RawAVP {
+			a := groupedAVP(650, lt, uintAVP(652, uint64(10)), si)
+			a.FlgP = true
+			return a
+		}(), dia.Load{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, e := dia.GetLoad(tt.avp)
+			if tt.err {
+				if e == nil {
+					t.Fatalf("no error, got %+v", v)
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if v != tt.want {
+				t.Fatalf("Load is %+v, want %+v", v, tt.want)
+			}
+		})
+	}
+}
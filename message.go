@@ -21,7 +21,7 @@ var (
 type Request interface {
 	ToRaw(string) RawMsg                     // generate RawMsg with session-id
 	FromRaw(RawMsg) (Request, string, error) // decode RawMsg and return session-id
-	Failed(uint32) Answer
+	Failed(Result) Answer
 	fmt.Stringer
 }
 
@@ -29,7 +29,7 @@ type Request interface {
 type Answer interface {
 	ToRaw(string) RawMsg
 	FromRaw(RawMsg) (Answer, string, error)
-	Result() uint32
+	Result() Result
 	fmt.Stringer
 }
 
@@ -170,6 +170,9 @@ func (m *RawMsg) ReadFrom(r io.Reader) (n int64, e error) {
 	var lng uint32
 	binary.Read(bytes.NewBuffer(buf[0:4]), binary.BigEndian, &lng)
 	// l := m.leng - 20 + (4 - m.leng % 4) % 4
+	if lng < 20 || lng%4 != 0 {
+		e = InvalidMessage(DiameterInvalidMessageLength)
+	}
 
 	flgs := btobo(buf[4:5])
 	m.FlgR = flgs[0]
@@ -183,6 +186,9 @@ func (m *RawMsg) ReadFrom(r io.Reader) (n int64, e error) {
 	binary.Read(bytes.NewBuffer(buf[8:12]), binary.BigEndian, &m.AppID)
 	binary.Read(bytes.NewBuffer(buf[12:16]), binary.BigEndian, &m.HbHID)
 	binary.Read(bytes.NewBuffer(buf[16:20]), binary.BigEndian, &m.EtEID)
+	if e != nil {
+		return
+	}
 
 	buf, i, e = subread(r, int(lng)-20)
 	n += int64(i)
@@ -195,6 +201,7 @@ func (m *RawMsg) ReadFrom(r io.Reader) (n int64, e error) {
 	for rdr.Len() != 0 {
 		a := RawAVP{}
 		if _, e = a.ReadFrom(rdr); e != nil {
+			e = InvalidAVP{Code: DiameterInvalidAvpLength, AVP: a}
 			return
 		}
 		m.AVP = append(m.AVP, a)
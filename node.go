@@ -1,8 +1,9 @@
 package diameter
 
 import (
-	"fmt"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +14,10 @@ var (
 	WDInterval = time.Second * time.Duration(30)
 	// WDExpired is watchdog expired count
 	WDExpired = 3
+	// MultiConn allows more than one open Conn with same peer.
+	// R-Conn-CER in I-Open or R-Open state is rejected if false
+	// and MultiConn of the Peer is also false.
+	MultiConn = false
 
 	// Host name for local host
 	Host Identity
@@ -22,8 +27,10 @@ var (
 	StateID uint32
 
 	// Used for Vendor-Specific-Application-Id, Auth-Application-Id
-	// and Supported-Vendor-Id AVP
-	supportedApps = make(map[uint32]appSet)
+	// and Supported-Vendor-Id AVP.
+	// The map is replaced on write, so it is read without lock.
+	apps   atomic.Pointer[map[uint32]appSet]
+	appsMu sync.Mutex
 
 	hbHID     = make(chan uint32, 1)
 	etEID     = make(chan uint32, 1)
@@ -36,9 +43,30 @@ type appSet struct {
 	ans map[uint32]Answer
 }
 
+// supportedApps returns current supported application messages
+func supportedApps() map[uint32]appSet {
+	if m := apps.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// updateApps replaces supported application messages by copy
+// that is modified by f
+func updateApps(f func(map[uint32]appSet)) {
+	appsMu.Lock()
+	defer appsMu.Unlock()
+	m := make(map[uint32]appSet)
+	for k, v := range supportedApps() {
+		m[k] = v
+	}
+	f(m)
+	apps.Store(&m)
+}
+
 func getSupportedApps() map[uint32][]uint32 {
 	r := make(map[uint32][]uint32)
-	for id, set := range supportedApps {
+	for id, set := range supportedApps() {
 		if id == 0xffffffff {
 			continue
 		}
@@ -67,23 +95,34 @@ func init() {
 
 // AddSupportedMessage add supported application message
 func AddSupportedMessage(v, a, c uint32, req Request, ans Answer) {
-
-	if _, ok := supportedApps[a]; !ok {
-		supportedApps[a] = appSet{
+	updateApps(func(m map[uint32]appSet) {
+		set := appSet{
 			id:  v,
 			req: make(map[uint32]Request),
 			ans: make(map[uint32]Answer)}
-	}
-	supportedApps[a].req[c] = req
-	supportedApps[a].ans[c] = ans
+		if old, ok := m[a]; ok {
+			set.id = old.id
+			for k, r := range old.req {
+				set.req[k] = r
+			}
+			for k, r := range old.ans {
+				set.ans[k] = r
+			}
+		}
+		set.req[c] = req
+		set.ans[c] = ans
+		m[a] = set
+	})
 }
 
 // EnableRelaySupport add supported application message
 func EnableRelaySupport() {
-	supportedApps[0xffffffff] = appSet{
-		id:  0,
-		req: map[uint32]Request{0: GenericReq{}},
-		ans: map[uint32]Answer{0: GenericAns{}}}
+	updateApps(func(m map[uint32]appSet) {
+		m[0xffffffff] = appSet{
+			id:  0,
+			req: map[uint32]Request{0: GenericReq{}},
+			ans: map[uint32]Answer{0: GenericAns{}}}
+	})
 }
 
 func nextHbH() uint32 {
@@ -99,10 +138,7 @@ func nextEtE() uint32 {
 }
 
 func nextSession() string {
-	ret := <-sessionID
-	sessionID <- ret + 1
-	return fmt.Sprintf("%s;%d;%d;0",
-		Host, time.Now().Unix()+2208988800, ret)
+	return SessionIDs.NextSessionID()
 }
 
 // Peer is peer node of Diameter
@@ -112,6 +148,9 @@ type Peer struct {
 	WDInterval time.Duration
 	WDExpired  int
 	AuthApps   map[uint32][]uint32
+	// MultiConn allows more than one open Conn with this peer
+	// even if package MultiConn is false
+	MultiConn bool
 }
 
 func (p *Peer) String() string {
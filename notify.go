@@ -4,34 +4,243 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Notify is called when error or trace event are occured
-var Notify = func(n Notice) {
-	log.Println(n)
-}
-
 // Notice is notification information from connection
 type Notice interface {
 	fmt.Stringer
+	Type() NoticeType
+	PeerHost() Identity
+}
+
+// NoticeType is type of Notice
+type NoticeType int
+
+const (
+	// StateUpdateNotice is type of StateUpdate
+	StateUpdateNotice NoticeType = iota
+	// CapabilityExchangeNotice is type of CapabilityExchangeEvent
+	CapabilityExchangeNotice
+	// WatchdogNotice is type of WatchdogEvent
+	WatchdogNotice
+	// MessageNotice is type of MessageEvent
+	MessageNotice
+	// PurgeNotice is type of PurgeEvent
+	PurgeNotice
+	// AdmissionNotice is type of AdmissionEvent
+	AdmissionNotice
+	// RestartNotice is type of PeerRestarted
+	RestartNotice
+)
+
+func (t NoticeType) String() string {
+	switch t {
+	case StateUpdateNotice:
+		return "state-update"
+	case CapabilityExchangeNotice:
+		return "capability-exchange"
+	case WatchdogNotice:
+		return "watchdog"
+	case MessageNotice:
+		return "message"
+	case PurgeNotice:
+		return "purge"
+	case AdmissionNotice:
+		return "admission"
+	case RestartNotice:
+		return "restart"
+	}
+	return "unknown"
+}
+
+// Filter select notices that are sent to observer.
+// Empty Types or Peers match any type or peer.
+type Filter struct {
+	Types []NoticeType
+	Peers []Identity
+}
+
+func (f Filter) match(n Notice) bool {
+	if len(f.Types) != 0 {
+		ok := false
+		for _, t := range f.Types {
+			if t == n.Type() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(f.Peers) != 0 {
+		ok := false
+		for _, p := range f.Peers {
+			if CompareIdentity(p, n.PeerHost()) == 0 {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type observer struct {
+	f   func(Notice)
+	flt Filter
+}
+
+var observers = struct {
+	sync.RWMutex
+	list  []*observer
+	count atomic.Int32
+}{}
+
+// Subscribe add observer function f that is called with notices
+// selected by filter, and returns function for unsubscribe.
+// f is called from connection goroutine, so it should not block.
+func Subscribe(f func(Notice), flt Filter) func() {
+	o := &observer{f: f, flt: flt}
+	observers.Lock()
+	observers.list = append(observers.list, o)
+	observers.count.Store(int32(len(observers.list)))
+	observers.Unlock()
+
+	return func() {
+		observers.Lock()
+		for i, v := range observers.list {
+			if v == o {
+				observers.list = append(observers.list[:i], observers.list[i+1:]...)
+				break
+			}
+		}
+		observers.count.Store(int32(len(observers.list)))
+		observers.Unlock()
+	}
+}
+
+func publish(n Notice) {
+	observers.RLock()
+	l := make([]*observer, 0, len(observers.list))
+	for _, o := range observers.list {
+		if o.flt.match(n) {
+			l = append(l, o)
+		}
+	}
+	observers.RUnlock()
+
+	for _, o := range l {
+		o.f(n)
+	}
+}
+
+// LogObserver write notice to standard logger
+func LogObserver(n Notice) {
+	log.Println(n)
+}
+
+// UnsubscribeLog unsubscribe default LogObserver.
+// LogObserver is subscribed for all notices at start.
+var UnsubscribeLog = Subscribe(LogObserver, Filter{})
+
+// SlogObserver returns observer that write notice to slog.Logger l
+func SlogObserver(l *slog.Logger) func(Notice) {
+	return func(n Notice) {
+		attrs := []any{
+			slog.String("type", n.Type().String()),
+			slog.String("peer", string(n.PeerHost()))}
+		var e error
+		switch v := n.(type) {
+		case StateUpdate:
+			attrs = append(attrs,
+				slog.String("event", v.Event),
+				slog.String("old_state", v.OldState),
+				slog.String("new_state", v.NewState))
+			e = v.Err
+		case CapabilityExchangeEvent:
+			attrs = append(attrs, v.MessageInfo.attrs()...)
+			e = v.Err
+		case WatchdogEvent:
+			attrs = append(attrs, v.MessageInfo.attrs()...)
+			e = v.Err
+		case MessageEvent:
+			attrs = append(attrs, v.MessageInfo.attrs()...)
+			e = v.Err
+		case PurgeEvent:
+			attrs = append(attrs, v.MessageInfo.attrs()...)
+			e = v.Err
+		case FailoverEvent:
+			attrs = append(attrs, v.MessageInfo.attrs()...)
+			attrs = append(attrs, slog.Int("retry", v.Retry))
+			e = v.Err
+		case AdmissionEvent:
+			attrs = append(attrs,
+				slog.String("realm", string(v.Realm)),
+				slog.Bool("allowed", v.Allowed))
+			if v.Addr != nil {
+				attrs = append(attrs, slog.String("address", v.Addr.String()))
+			}
+			e = v.Err
+		case PeerRestarted:
+			attrs = append(attrs,
+				slog.Uint64("old_state_id", uint64(v.OldStateID)),
+				slog.Uint64("new_state_id", uint64(v.NewStateID)))
+		}
+		if e != nil {
+			l.Warn(n.String(), append(attrs, slog.Any("error", e))...)
+		} else {
+			l.Info(n.String(), attrs...)
+		}
+	}
 }
 
 // StateUpdate notify event
 type StateUpdate struct {
-	oldStat state
-	newStat state
-	stateEvent
-	conn *Conn
-	Err  error
+	OldState string
+	NewState string
+	Event    string
+	Peer     Identity
+	Time     time.Time
+	Err      error
+}
+
+func newStateUpdate(old state, ev stateEvent, c *Conn, e error) StateUpdate {
+	n := StateUpdate{
+		OldState: old.String(),
+		NewState: c.getState().String(),
+		Event:    ev.String(),
+		Time:     c.clock.Now(),
+		Err:      e}
+	if c.Peer != nil {
+		n.Peer = c.Peer.Host
+	}
+	return n
+}
+
+// Type returns StateUpdateNotice
+func (e StateUpdate) Type() NoticeType {
+	return StateUpdateNotice
+}
+
+// PeerHost returns Host of the peer
+func (e StateUpdate) PeerHost() Identity {
+	return e.Peer
 }
 
 func (e StateUpdate) String() string {
 	w := new(bytes.Buffer)
-	fmt.Fprintf(w, "Event %s: Peer %s", e.stateEvent, e.conn.Peer)
-	if e.oldStat != e.newStat {
-		fmt.Fprintf(w, ": State %s -> %s", e.oldStat, e.newStat)
+	fmt.Fprintf(w, "Event %s: Peer %s", e.Event, e.Peer)
+	if e.OldState != e.NewState {
+		fmt.Fprintf(w, ": State %s -> %s", e.OldState, e.NewState)
 	} else {
-		fmt.Fprintf(w, ": State %s", e.oldStat)
+		fmt.Fprintf(w, ": State %s", e.OldState)
 	}
 	if e.Err != nil {
 		fmt.Fprintf(w, ": Failed: %s", e.Err)
@@ -39,69 +248,142 @@ func (e StateUpdate) String() string {
 	return w.String()
 }
 
-func msgHandleLog(x, r bool, c *Conn, e error, req, ans string) string {
+// Direction is direction of message
+type Direction bool
+
+const (
+	// Rx is received message
+	Rx Direction = false
+	// Tx is sent message
+	Tx Direction = true
+)
+
+func (d Direction) String() string {
+	if d {
+		return "tx"
+	}
+	return "rx"
+}
+
+// MessageInfo is information of message that is sent or received
+type MessageInfo struct {
+	Direction
+	Request   bool
+	Peer      Identity
+	SessionID string
+	HbHID     uint32
+	EtEID     uint32
+	Time      time.Time
+	Msg       RawMsg // copy of the message
+	Err       error
+}
+
+func newMessageInfo(c *Conn, d Direction, m RawMsg, e error) MessageInfo {
+	n := MessageInfo{
+		Direction: d,
+		Request:   m.FlgR,
+		HbHID:     m.HbHID,
+		EtEID:     m.EtEID,
+		Time:      c.clock.Now(),
+		Err:       e}
+	if c.Peer != nil {
+		n.Peer = c.Peer.Host
+	}
+	if observers.count.Load() == 0 {
+		return n
+	}
+	n.SessionID = sessionIDOf(m)
+	n.Msg = m.Clone()
+	return n
+}
+
+// PeerHost returns Host of the peer
+func (i MessageInfo) PeerHost() Identity {
+	return i.Peer
+}
+
+func (i MessageInfo) attrs() []any {
+	return []any{
+		slog.String("direction", i.Direction.String()),
+		slog.Bool("request", i.Request),
+		slog.String("session_id", i.SessionID),
+		slog.Uint64("hbh_id", uint64(i.HbHID)),
+		slog.Uint64("ete_id", uint64(i.EtEID)),
+		slog.Uint64("command", uint64(i.Msg.Code)),
+		slog.Uint64("application", uint64(i.Msg.AppID))}
+}
+
+func (i MessageInfo) log(req, ans string) string {
 	w := new(bytes.Buffer)
-	if x {
+	if i.Direction == Tx {
 		fmt.Fprintf(w, "-> ")
 	} else {
 		fmt.Fprintf(w, "<- ")
 	}
-	if r {
-		fmt.Fprintf(w, req)
+	if i.Request {
+		fmt.Fprint(w, req)
 	} else {
-		fmt.Fprintf(w, ans)
+		fmt.Fprint(w, ans)
 	}
-	fmt.Fprintf(w, " (%s)", c.Peer)
-	if e != nil {
-		fmt.Fprintf(w, ": Failed: %s", e)
+	fmt.Fprintf(w, " (%s)", i.Peer)
+	if i.Err != nil {
+		fmt.Fprintf(w, ": Failed: %s", i.Err)
 	}
 	return w.String()
 }
 
 // CapabilityExchangeEvent notify capability exchange related event
 type CapabilityExchangeEvent struct {
-	tx   bool
-	req  bool
-	conn *Conn
-	Err  error
+	MessageInfo
+}
+
+// Type returns CapabilityExchangeNotice
+func (e CapabilityExchangeEvent) Type() NoticeType {
+	return CapabilityExchangeNotice
 }
 
 func (e CapabilityExchangeEvent) String() string {
-	return msgHandleLog(e.tx, e.req, e.conn, e.Err, "CER", "CEA")
+	return e.log("CER", "CEA")
 }
 
 // WatchdogEvent notify watchdog related event
 type WatchdogEvent struct {
-	tx   bool
-	req  bool
-	conn *Conn
-	Err  error
+	MessageInfo
+}
+
+// Type returns WatchdogNotice
+func (e WatchdogEvent) Type() NoticeType {
+	return WatchdogNotice
 }
 
 func (e WatchdogEvent) String() string {
-	return msgHandleLog(e.tx, e.req, e.conn, e.Err, "DWR", "DWA")
+	return e.log("DWR", "DWA")
 }
 
 // MessageEvent notify diameter message related event
 type MessageEvent struct {
-	tx   bool
-	req  bool
-	conn *Conn
-	Err  error
+	MessageInfo
+}
+
+// Type returns MessageNotice
+func (e MessageEvent) Type() NoticeType {
+	return MessageNotice
 }
 
 func (e MessageEvent) String() string {
-	return msgHandleLog(e.tx, e.req, e.conn, e.Err, "REQ", "ANS")
+	return e.log("REQ", "ANS")
 }
 
 // PurgeEvent notify diameter purge related event
 type PurgeEvent struct {
-	tx   bool
-	req  bool
-	conn *Conn
-	Err  error
+	MessageInfo
+}
+
+// Type returns PurgeNotice
+func (e PurgeEvent) Type() NoticeType {
+	return PurgeNotice
 }
 
 func (e PurgeEvent) String() string {
-	return msgHandleLog(e.tx, e.req, e.conn, e.Err, "DPR", "DPA")
+	return e.log("DPR", "DPA")
 }
@@ -0,0 +1,281 @@
+package diameter
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balance is Conn selection method of PeerPool
+type Balance int
+
+const (
+	// RoundRobin selects open Conn in turn
+	RoundRobin Balance = iota
+	// LeastOutstanding selects open Conn that has
+	// the least requests that wait answer
+	LeastOutstanding
+)
+
+// PeerPool is set of Conns to one peer.
+// It sends request by one of the Conns and re-dials failed Conn.
+// The peer must allow more than one Conn from same host.
+// Conns are dialed with MultiConn of the Peer, so CER from the peer
+// is accepted while the pool has open Conn.
+type PeerPool struct {
+	Peer Peer
+	// Size is number of Conns, zero is 1
+	Size int
+	// Balance is Conn selection method
+	Balance Balance
+	// Connect returns new transport connection to the peer
+	Connect func() (net.Conn, error)
+	// RetryInterval is interval of re-dial of failed Conn, zero is 1 second
+	RetryInterval time.Duration
+	// Clock is time source of re-dial interval and deadline of Send,
+	// nil is DefaultClock
+	Clock Clock
+
+	mu      sync.RWMutex
+	members []*poolMember
+	retired ConnStats // stats of replaced Conns
+	next    atomic.Uint32
+	done    chan struct{}
+}
+
+type poolMember struct {
+	c   *Conn
+	out atomic.Int64 // outstanding requests
+}
+
+// Open dials Size Conns with CER timeout d.
+// It returns error if no Conn is opened.
+// Failed Conns are re-dialed by background until Close.
+func (p *PeerPool) Open(d time.Duration) error {
+	n := p.Size
+	if n <= 0 {
+		n = 1
+	}
+	p.mu.Lock()
+	p.members = make([]*poolMember, n)
+	p.done = make(chan struct{})
+	p.mu.Unlock()
+
+	var err error
+	ok := false
+	for i := 0; i < n; i++ {
+		c, e := p.dial(d)
+		if e != nil {
+			err = e
+		} else {
+			ok = true
+		}
+		p.mu.Lock()
+		p.members[i] = &poolMember{c: c}
+		p.mu.Unlock()
+		go p.keep(i, c, d)
+	}
+	if !ok {
+		p.Close(d)
+		return err
+	}
+	return nil
+}
+
+func (p *PeerPool) dial(d time.Duration) (*Conn, error) {
+	if p.Connect == nil {
+		return nil, ConnectionRefused{}
+	}
+	con, e := p.Connect()
+	if e != nil {
+		return nil, e
+	}
+	peer := p.Peer
+	peer.MultiConn = true
+	c, e := Dial(peer, con, d)
+	if e != nil {
+		con.Close()
+	}
+	return c, e
+}
+
+func (p *PeerPool) clock() Clock {
+	if p.Clock == nil {
+		return DefaultClock
+	}
+	return p.Clock
+}
+
+// keep re-dials Conn i when c is closed
+func (p *PeerPool) keep(i int, c *Conn, d time.Duration) {
+	for {
+		if c != nil {
+			select {
+			case <-c.done:
+			case <-p.done:
+				return
+			}
+			p.mu.Lock()
+			p.retired = addStats(p.retired, c.Stats())
+			p.members[i] = &poolMember{}
+			p.mu.Unlock()
+		}
+
+		t := p.RetryInterval
+		if t <= 0 {
+			t = time.Second
+		}
+		ch := make(chan struct{})
+		tm := p.clock().AfterFunc(t, func() { close(ch) })
+		select {
+		case <-ch:
+		case <-p.done:
+			tm.Stop()
+			return
+		}
+
+		var e error
+		if c, e = p.dial(d); e != nil {
+			c = nil
+			continue
+		}
+		p.mu.Lock()
+		select {
+		case <-p.done:
+			p.mu.Unlock()
+			c.Close(d)
+			return
+		default:
+		}
+		p.members[i] = &poolMember{c: c}
+		p.mu.Unlock()
+	}
+}
+
+// Close closes all Conns in the pool with DPR timeout d
+func (p *PeerPool) Close(d time.Duration) {
+	p.mu.Lock()
+	if p.done != nil {
+		select {
+		case <-p.done:
+		default:
+			close(p.done)
+		}
+	}
+	l := append([]*poolMember{}, p.members...)
+	p.mu.Unlock()
+
+	for _, m := range l {
+		if m.c != nil {
+			m.c.Close(d)
+		}
+	}
+}
+
+// Conns returns open Conns in the pool
+func (p *PeerPool) Conns() []*Conn {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var r []*Conn
+	for _, m := range p.members {
+		if m.c != nil && m.c.getState().isOpen() {
+			r = append(r, m.c)
+		}
+	}
+	return r
+}
+
+// pick returns member for request except ex
+func (p *PeerPool) pick(ex []*Conn) *poolMember {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var l []*poolMember
+	for _, m := range p.members {
+		if m.c != nil && m.c.getState().isOpen() && !contains(ex, m.c) {
+			l = append(l, m)
+		}
+	}
+	if len(l) == 0 {
+		return nil
+	}
+
+	if p.Balance == LeastOutstanding {
+		r := l[0]
+		for _, m := range l[1:] {
+			if m.out.Load() < r.out.Load() {
+				r = m
+			}
+		}
+		return r
+	}
+	return l[p.next.Add(1)%uint32(len(l))]
+}
+
+// Send sends request m by one of Conns in the pool and returns answer.
+// The request is retransmitted by other Conn in the pool
+// if the Conn is closed before answer.
+// Answer has DIAMETER_UNABLE_TO_DELIVER if no Conn is available.
+func (p *PeerPool) Send(m Request, d time.Duration) Answer {
+	sid := nextSession()
+	req := m.ToRaw(sid)
+	req.EtEID = nextEtE()
+
+	clk := p.clock()
+	deadline := clk.Now().Add(d)
+	var tried []*Conn
+	for {
+		pm := p.pick(tried)
+		if pm == nil {
+			return m.Failed(ResultCode(DiameterUnableToDeliver))
+		}
+		pm.out.Add(1)
+		a, e := pm.c.sendRaw(m, sid, req, deadline.Sub(clk.Now()))
+		pm.out.Add(-1)
+		if !failover(e, clk, deadline) {
+			return answerOf(m, a, e)
+		}
+
+		tried = append(tried, pm.c)
+		req.FlgT = true
+		publish(FailoverEvent{
+			MessageInfo: newMessageInfo(pm.c, Tx, req, nil),
+			Retry:       len(tried)})
+	}
+}
+
+// Stats returns sum of message counters of Conns in the pool,
+// including Conns that are already replaced
+func (p *PeerPool) Stats() ConnStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	s := p.retired
+	for _, m := range p.members {
+		if m.c != nil {
+			s = addStats(s, m.c.Stats())
+		}
+	}
+	return s
+}
+
+func addStats(a, b ConnStats) ConnStats {
+	a.RxReq += b.RxReq
+	a.Reject += b.Reject
+	a.Duplicate += b.Duplicate
+	a.Tx1xxx += b.Tx1xxx
+	a.Tx2xxx += b.Tx2xxx
+	a.Tx3xxx += b.Tx3xxx
+	a.Tx4xxx += b.Tx4xxx
+	a.Tx5xxx += b.Tx5xxx
+	a.TxEtc += b.TxEtc
+	a.TxReq += b.TxReq
+	a.TxReqFail += b.TxReqFail
+	a.TxReqTimeout += b.TxReqTimeout
+	a.Rx1xxx += b.Rx1xxx
+	a.Rx2xxx += b.Rx2xxx
+	a.Rx3xxx += b.Rx3xxx
+	a.Rx4xxx += b.Rx4xxx
+	a.Rx5xxx += b.Rx5xxx
+	a.RxEtc += b.RxEtc
+	return a
+}
@@ -0,0 +1,107 @@
+package diameter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HandleRestart is called when Origin-State-Id of a host increases.
+// Application can clear sessions and states that is tied to the host.
+// It is called in new goroutine after PeerRestarted is notified to
+// observers, so the message from the host may be handled before it.
+// Router and ts29338.MWD remove sessions and states that is tied
+// to the host by observing PeerRestarted.
+var HandleRestart = defaultHandleRestart
+
+func defaultHandleRestart(e PeerRestarted, c *Conn) {}
+
+// last Origin-State-Id of each Origin-Host
+var peerStates = struct {
+	sync.Mutex
+	m map[string]uint32
+}{m: make(map[string]uint32)}
+
+// LastStateID returns last Origin-State-Id that is received from host
+func LastStateID(host Identity) (uint32, bool) {
+	peerStates.Lock()
+	defer peerStates.Unlock()
+	v, ok := peerStates.m[strings.ToLower(string(host))]
+	return v, ok
+}
+
+// PeerStateID returns last Origin-State-Id that is received
+// in CER, CEA, DWR, DWA, DPR or DPA from the peer
+func (c *Conn) PeerStateID() uint32 {
+	return c.peerState.Load()
+}
+
+// rcvStateID checks Origin-State-Id of received message m
+func (c *Conn) rcvStateID(m RawMsg) {
+	var host Identity
+	var id uint32
+	for _, a := range m.AVP {
+		if a.VenID != 0 {
+			continue
+		}
+		switch a.Code {
+		case 264:
+			host, _ = GetOriginHost(a)
+		case 278:
+			id, _ = getOriginStateID(a)
+		}
+	}
+	if id == 0 || len(host) == 0 {
+		return
+	}
+	if m.AppID == 0 && (m.Code == 257 || m.Code == 280 || m.Code == 282) {
+		c.peerState.Store(id)
+	}
+
+	k := strings.ToLower(string(host))
+	peerStates.Lock()
+	old, ok := peerStates.m[k]
+	if ok && old >= id {
+		peerStates.Unlock()
+		return
+	}
+	peerStates.m[k] = id
+	peerStates.Unlock()
+	if !ok {
+		return
+	}
+
+	e := PeerRestarted{
+		Peer:       host,
+		OldStateID: old,
+		NewStateID: id,
+		Time:       c.clock.Now()}
+	go func() {
+		publish(e)
+		HandleRestart(e, c)
+	}()
+}
+
+// PeerRestarted notify increase of Origin-State-Id of the peer
+type PeerRestarted struct {
+	Peer       Identity
+	OldStateID uint32
+	NewStateID uint32
+	Time       time.Time
+}
+
+// Type returns RestartNotice
+func (e PeerRestarted) Type() NoticeType {
+	return RestartNotice
+}
+
+// PeerHost returns Host of the peer
+func (e PeerRestarted) PeerHost() Identity {
+	return e.Peer
+}
+
+func (e PeerRestarted) String() string {
+	return fmt.Sprintf("Restart: Peer %s: Origin-State-Id %d -> %d",
+		e.Peer, e.OldStateID, e.NewStateID)
+}
@@ -1,5 +1,10 @@
 package diameter
 
+import (
+	"fmt"
+	"sync"
+)
+
 const (
 	// DiameterMultiRoundAuth is Result-Code 1001
 	DiameterMultiRoundAuth uint32 = 1001
@@ -72,3 +77,146 @@ const (
 	// DiameterNoCommonSecurity is Result-Code 5017
 	DiameterNoCommonSecurity uint32 = 5017
 )
+
+// Result is result of Diameter answer.
+// It is Result-Code AVP value when IsExperimental is false,
+// or Vendor-Id and Experimental-Result-Code of Experimental-Result AVP.
+type Result struct {
+	VendorID       uint32
+	Code           uint32
+	IsExperimental bool
+}
+
+// ResultCode returns Result of Result-Code AVP value
+func ResultCode(c uint32) Result {
+	return Result{Code: c}
+}
+
+// ExperimentalResult returns Result of Experimental-Result AVP value
+func ExperimentalResult(v, c uint32) Result {
+	return Result{VendorID: v, Code: c, IsExperimental: true}
+}
+
+func (r Result) String() string {
+	resultNamesMu.RLock()
+	n, ok := resultNames[resultKey{r.VendorID, r.Code}]
+	resultNamesMu.RUnlock()
+	if !ok {
+		n = "UNKNOWN"
+	}
+	if r.IsExperimental {
+		return fmt.Sprintf("%s(%d:%d)", n, r.VendorID, r.Code)
+	}
+	return fmt.Sprintf("%s(%d)", n, r.Code)
+}
+
+// ResultClass is class of result code
+type ResultClass int
+
+const (
+	// UnknownClass is result code out of defined range
+	UnknownClass ResultClass = iota
+	// Informational is 1xxx result code
+	Informational
+	// Success is 2xxx result code
+	Success
+	// ProtocolError is 3xxx result code
+	ProtocolError
+	// TransientFailure is 4xxx result code
+	TransientFailure
+	// PermanentFailure is 5xxx result code
+	PermanentFailure
+)
+
+func (c ResultClass) String() string {
+	switch c {
+	case Informational:
+		return "informational"
+	case Success:
+		return "success"
+	case ProtocolError:
+		return "protocol error"
+	case TransientFailure:
+		return "transient failure"
+	case PermanentFailure:
+		return "permanent failure"
+	}
+	return "unknown"
+}
+
+// Class returns class of the result
+func (r Result) Class() ResultClass {
+	switch r.Code / 1000 {
+	case 1:
+		return Informational
+	case 2:
+		return Success
+	case 3:
+		return ProtocolError
+	case 4:
+		return TransientFailure
+	case 5:
+		return PermanentFailure
+	}
+	return UnknownClass
+}
+
+type resultKey struct {
+	vendor uint32
+	code   uint32
+}
+
+var resultNamesMu sync.RWMutex
+
+var resultNames = map[resultKey]string{
+	{0, DiameterMultiRoundAuth}:         "DIAMETER_MULTI_ROUND_AUTH",
+	{0, DiameterSuccess}:                "DIAMETER_SUCCESS",
+	{0, DiameterLimitedSuccess}:         "DIAMETER_LIMITED_SUCCESS",
+	{0, DiameterCommandUnspported}:      "DIAMETER_COMMAND_UNSUPPORTED",
+	{0, DiameterUnableToDeliver}:        "DIAMETER_UNABLE_TO_DELIVER",
+	{0, DiameterRealmNotServed}:         "DIAMETER_REALM_NOT_SERVED",
+	{0, DiameterTooBusy}:                "DIAMETER_TOO_BUSY",
+	{0, DiameterLoopDetected}:           "DIAMETER_LOOP_DETECTED",
+	{0, DiameterRedirectIndication}:     "DIAMETER_REDIRECT_INDICATION",
+	{0, DiameterApplicationUnsupported}: "DIAMETER_APPLICATION_UNSUPPORTED",
+	{0, DiameterInvalidHdrBits}:         "DIAMETER_INVALID_HDR_BITS",
+	{0, DiameterInvalidAvpBits}:         "DIAMETER_INVALID_AVP_BITS",
+	{0, DiameterUnknownPeer}:            "DIAMETER_UNKNOWN_PEER",
+	{0, DiameterAuthenticationRejected}: "DIAMETER_AUTHENTICATION_REJECTED",
+	{0, DiameterOutOfSpace}:             "DIAMETER_OUT_OF_SPACE",
+	{0, DiameterElectionLost}:           "ELECTION_LOST",
+	{0, DiameterAvpUnsupported}:         "DIAMETER_AVP_UNSUPPORTED",
+	{0, DiameterUnknownSessionID}:       "DIAMETER_UNKNOWN_SESSION_ID",
+	{0, DiameterAuthorizationRejected}:  "DIAMETER_AUTHORIZATION_REJECTED",
+	{0, DiameterInvalidAvpValue}:        "DIAMETER_INVALID_AVP_VALUE",
+	{0, DiameterMissingAvp}:             "DIAMETER_MISSING_AVP",
+	{0, DiameterResourcesExceeded}:      "DIAMETER_RESOURCES_EXCEEDED",
+	{0, DiameterContradictingAvps}:      "DIAMETER_CONTRADICTING_AVPS",
+	{0, DiameterAvpNotAllowed}:          "DIAMETER_AVP_NOT_ALLOWED",
+	{0, DiameterAvpOccursTooManyTimes}:  "DIAMETER_AVP_OCCURS_TOO_MANY_TIMES",
+	{0, DiameterNoCommonApplication}:    "DIAMETER_NO_COMMON_APPLICATION",
+	{0, DiameterUnsupportedVersion}:     "DIAMETER_UNSUPPORTED_VERSION",
+	{0, DiameterUnableToComply}:         "DIAMETER_UNABLE_TO_COMPLY",
+	{0, DiameterInvalidBitInHeader}:     "DIAMETER_INVALID_BIT_IN_HEADER",
+	{0, DiameterInvalidAvpLength}:       "DIAMETER_INVALID_AVP_LENGTH",
+	{0, DiameterInvalidMessageLength}:   "DIAMETER_INVALID_MESSAGE_LENGTH",
+	{0, DiameterInvalidAvpBitCombo}:     "DIAMETER_INVALID_AVP_BIT_COMBO",
+	{0, DiameterNoCommonSecurity}:       "DIAMETER_NO_COMMON_SECURITY",
+}
+
+// RegisterResultName add name of result code for vendor v and code c
+func RegisterResultName(v, c uint32, name string) {
+	resultNamesMu.Lock()
+	resultNames[resultKey{v, c}] = name
+	resultNamesMu.Unlock()
+}
+
+func resultOf(m RawMsg) (r Result) {
+	for _, a := range m.AVP {
+		if a.VenID == 0 && (a.Code == 268 || a.Code == 297) {
+			r, _ = GetResult(a)
+			return
+		}
+	}
+	return
+}
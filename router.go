@@ -0,0 +1,289 @@
+package diameter
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSessionTTL is TTL of session binding of Router
+// that has zero SessionTTL
+var DefaultSessionTTL = time.Minute * 5
+
+// Router selects Conn for request by Destination-Host, Destination-Realm
+// and Application-ID, and sends the request.
+// Zero value is empty Router without failover.
+type Router struct {
+	// Retry is number of retransmission of a request to alternate Conn
+	// when the Conn is closed before answer. Zero disables failover.
+	// Retransmitted request has T flag and same End-to-End ID.
+	Retry int
+	// Clock is time source of deadline of Send, nil is DefaultClock
+	Clock Clock
+	// Sessions binds Session-Id of request that is sent by SendSession
+	// to the selected Conn. Following requests of the session are sent
+	// to the same Conn, and alternate Conn is selected only when the Conn
+	// is down. Binding expires after SessionTTL, and it is removed when
+	// the peer of the Conn restarts. Nil disables session affinity.
+	Sessions SessionStore[*Conn]
+	// SessionTTL is TTL of binding in Sessions, zero is DefaultSessionTTL
+	SessionTTL time.Duration
+
+	mu    sync.RWMutex
+	conns []*Conn
+	next  atomic.Uint32
+	watch sync.Once
+}
+
+// Add adds Conn to the Router
+func (r *Router) Add(c *Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.conns {
+		if v == c {
+			return
+		}
+	}
+	r.conns = append(r.conns, c)
+}
+
+// Remove removes Conn from the Router
+func (r *Router) Remove(c *Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.conns {
+		if v == c {
+			r.conns = append(r.conns[:i], r.conns[i+1:]...)
+			return
+		}
+	}
+}
+
+// Conns returns Conns in the Router
+func (r *Router) Conns() []*Conn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Conn{}, r.conns...)
+}
+
+// Select returns open Conn for request m except Conns in ex.
+// Conn that is bound to Session-Id of m is used if it is open and
+// its peer is Destination-Host of m and supports Application-ID of m,
+// or Conn of Destination-Host peer is used if it exists,
+// or Conn of Destination-Realm peer is used.
+// Conn is selected by Load of the peers if there are some Conns.
+// The peer must support Application-ID of m.
+// It returns nil if no Conn is available.
+func (r *Router) Select(m RawMsg, ex ...*Conn) *Conn {
+	var sid string
+	var host, realm Identity
+	for _, a := range m.AVP {
+		if a.VenID != 0 {
+			continue
+		}
+		switch a.Code {
+		case 263:
+			sid, _ = GetSessionID(a)
+		case 293:
+			host, _ = GetDestinationHost(a)
+		case 283:
+			realm, _ = GetDestinationRealm(a)
+		}
+	}
+
+	if c := r.bound(sid); c != nil && !contains(ex, c) && c.supportApp(m.AppID) &&
+		(len(host) == 0 || strings.EqualFold(string(c.Peer.Host), string(host))) {
+		return c
+	}
+
+	var hosts, realms []*Conn
+	r.mu.RLock()
+	for _, c := range r.conns {
+		if !c.getState().isOpen() || !c.supportApp(m.AppID) || contains(ex, c) {
+			continue
+		}
+		if len(host) != 0 && CompareIdentity(c.Peer.Host, host) == 0 {
+			hosts = append(hosts, c)
+		} else if len(realm) == 0 || CompareIdentity(c.Peer.Realm, realm) == 0 {
+			realms = append(realms, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(hosts) == 0 {
+		hosts = realms
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return r.pick(hosts)
+}
+
+// bound returns open Conn that is bound to Session-Id sid
+func (r *Router) bound(sid string) *Conn {
+	if r.Sessions == nil || len(sid) == 0 {
+		return nil
+	}
+	c, ok := r.Sessions.Get(sid)
+	if !ok || c == nil || !c.getState().isOpen() {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !contains(r.conns, c) {
+		return nil
+	}
+	return c
+}
+
+// pick returns Conn in l by weighted random selection with load of the peers.
+// Weight of the peer is MaxLoad+1-Load, so the peer with lower load is
+// selected more frequently. Round robin is used if all loads are same.
+func (r *Router) pick(l []*Conn) *Conn {
+	w := make([]uint64, len(l))
+	sum := uint64(0)
+	same := true
+	for i, c := range l {
+		ld := c.Load()
+		if ld > MaxLoad {
+			ld = MaxLoad
+		}
+		w[i] = MaxLoad + 1 - ld
+		sum += w[i]
+		same = same && w[i] == w[0]
+	}
+	if same {
+		return l[r.next.Add(1)%uint32(len(l))]
+	}
+
+	n := uint64(rand.Int63n(int64(sum)))
+	for i, v := range w {
+		if n < v {
+			return l[i]
+		}
+		n -= v
+	}
+	return l[len(l)-1]
+}
+
+// restarted removes sessions that is bound to Conn of the restarted peer
+func (r *Router) restarted(n Notice) {
+	var l []string
+	r.Sessions.Range(func(id string, c *Conn) bool {
+		if c != nil && strings.EqualFold(string(c.Peer.Host), string(n.PeerHost())) {
+			l = append(l, id)
+		}
+		return true
+	})
+	for _, id := range l {
+		r.Sessions.Delete(id)
+	}
+}
+
+func contains(l []*Conn, c *Conn) bool {
+	for _, v := range l {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// supportApp returns true if application a is negotiated in CER/CEA
+func (c *Conn) supportApp(a uint32) bool {
+	if a == 0 || c.Peer.AuthApps == nil {
+		return true
+	}
+	for _, ids := range c.Peer.AuthApps {
+		for _, id := range ids {
+			if id == a || id == 0xffffffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Send sends request m of new session to selected Conn and returns answer.
+// The request is retransmitted to alternate Conn by Retry
+// if the Conn is closed before answer.
+// Answer has DIAMETER_UNABLE_TO_DELIVER if no Conn is available.
+// The session is not bound to the Conn.
+func (r *Router) Send(m Request, d time.Duration) Answer {
+	return r.send(nextSession(), false, m, d)
+}
+
+// SendSession sends request m of session sid to selected Conn
+// and returns answer. The request is sent to the Conn that is bound
+// to sid if Sessions is set, and sid is bound to the selected Conn.
+func (r *Router) SendSession(sid string, m Request, d time.Duration) Answer {
+	return r.send(sid, true, m, d)
+}
+
+// send sends request m of session sid, and binds sid to the Conn if bind
+func (r *Router) send(sid string, bind bool, m Request, d time.Duration) Answer {
+	req := m.ToRaw(sid)
+	req.EtEID = nextEtE()
+
+	clk := r.Clock
+	if clk == nil {
+		clk = DefaultClock
+	}
+	deadline := clk.Now().Add(d)
+	var tried []*Conn
+	for {
+		c := r.Select(req, tried...)
+		if c == nil {
+			return m.Failed(ResultCode(DiameterUnableToDeliver))
+		}
+		if bind && r.Sessions != nil {
+			r.watch.Do(func() {
+				Subscribe(r.restarted, Filter{Types: []NoticeType{RestartNotice}})
+			})
+			ttl := r.SessionTTL
+			if ttl == 0 {
+				ttl = DefaultSessionTTL
+			}
+			r.Sessions.Set(sid, c, ttl)
+		}
+		a, e := c.sendRaw(m, sid, req, deadline.Sub(clk.Now()))
+		if !failover(e, clk, deadline) || len(tried) >= r.Retry {
+			return answerOf(m, a, e)
+		}
+
+		// Conn is closed before answer, failover to alternate Conn
+		tried = append(tried, c)
+		req.FlgT = true
+		publish(FailoverEvent{
+			MessageInfo: newMessageInfo(c, Tx, req, nil),
+			Retry:       len(tried)})
+	}
+}
+
+// failover returns true if the request of error e should be retransmitted.
+// Only closed Conn is failed over, and answer of the alternate Conn
+// should be received before deadline of clock clk.
+func failover(e error, clk Clock, deadline time.Time) bool {
+	if _, ok := e.(ConnectionRefused); !ok {
+		return false
+	}
+	return clk.Now().Before(deadline)
+}
+
+// FailoverEvent notify retransmission of pending request
+// to alternate Conn. Peer is the closed peer.
+type FailoverEvent struct {
+	MessageInfo
+	Retry int
+}
+
+// Type returns MessageNotice
+func (e FailoverEvent) Type() NoticeType {
+	return MessageNotice
+}
+
+func (e FailoverEvent) String() string {
+	return e.log("REQ failover", "ANS failover")
+}
@@ -0,0 +1,189 @@
+package diameter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionIDGenerator makes Session-Id of new session
+type SessionIDGenerator interface {
+	NextSessionID() string
+}
+
+// SessionIDs is Session-Id generator of sent request
+var SessionIDs SessionIDGenerator = &SessionIDFormat{Optional: "0"}
+
+// SessionIDFormat makes Session-Id of RFC 6733 format,
+// <DiameterIdentity>;<high 32 bits>;<low 32 bits>[;<optional value>].
+// Low 32 bits is counter that starts from random value.
+type SessionIDFormat struct {
+	// Host is DiameterIdentity part, empty is Host
+	Host Identity
+	// RestartSafe uses StateID as high 32 bits, so Session-Id is
+	// unique after restart. Current NTP time in seconds is used if false.
+	RestartSafe bool
+	// Optional is optional value part, it is omitted if empty
+	Optional string
+}
+
+// NextSessionID returns new Session-Id
+func (f *SessionIDFormat) NextSessionID() string {
+	host := f.Host
+	if len(host) == 0 {
+		host = Host
+	}
+	high := StateID
+	if !f.RestartSafe {
+		high = uint32(time.Now().Unix() + 2208988800)
+	}
+
+	low := <-sessionID
+	sessionID <- low + 1
+	if len(f.Optional) == 0 {
+		return fmt.Sprintf("%s;%d;%d", host, high, low)
+	}
+	return fmt.Sprintf("%s;%d;%d;%s", host, high, low, f.Optional)
+}
+
+// SessionStore is store of application data T that is tied to Session-Id
+type SessionStore[T any] interface {
+	// Get returns data of the session
+	Get(id string) (T, bool)
+	// Set stores data of the session that expires after ttl.
+	// Zero ttl is default TTL of the store, and the session does not
+	// expire if both are zero.
+	Set(id string, v T, ttl time.Duration)
+	// Delete removes data of the session
+	Delete(id string)
+	// Range calls f for each session until f returns false
+	Range(f func(id string, v T) bool)
+	// Stats returns metrics of the store
+	Stats() SessionStats
+}
+
+// SessionStats is snapshot of metrics of SessionStore
+type SessionStats struct {
+	Size    int
+	Added   uint64
+	Deleted uint64
+	Expired uint64
+}
+
+// MemorySessionStore is in-memory SessionStore.
+// Expired sessions are removed on access of the store.
+type MemorySessionStore[T any] struct {
+	// TTL is default TTL of session, zero is no expiry
+	TTL time.Duration
+	// Clock is clock of TTL, nil is DefaultClock
+	Clock Clock
+
+	mu    sync.Mutex
+	m     map[string]sessionEntry[T]
+	stats SessionStats
+	sweep time.Time
+}
+
+type sessionEntry[T any] struct {
+	v      T
+	expire time.Time // zero is no expiry
+}
+
+func (e sessionEntry[T]) alive(now time.Time) bool {
+	return e.expire.IsZero() || now.Before(e.expire)
+}
+
+func (s *MemorySessionStore[T]) now() time.Time {
+	if s.Clock == nil {
+		return DefaultClock.Now()
+	}
+	return s.Clock.Now()
+}
+
+// NewSessionStore returns MemorySessionStore with default ttl
+func NewSessionStore[T any](ttl time.Duration) *MemorySessionStore[T] {
+	return &MemorySessionStore[T]{TTL: ttl, m: make(map[string]sessionEntry[T])}
+}
+
+// expire removes expired sessions, mu must be held
+func (s *MemorySessionStore[T]) expire(now time.Time) {
+	if s.m == nil {
+		s.m = make(map[string]sessionEntry[T])
+	}
+	if now.Sub(s.sweep) < time.Second {
+		return
+	}
+	s.sweep = now
+	for k, v := range s.m {
+		if !v.alive(now) {
+			delete(s.m, k)
+			s.stats.Expired++
+		}
+	}
+}
+
+// Get returns data of the session
+func (s *MemorySessionStore[T]) Get(id string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.expire(now)
+	e, ok := s.m[id]
+	if !ok || !e.alive(now) {
+		var zero T
+		return zero, false
+	}
+	return e.v, true
+}
+
+// Set stores data of the session that expires after ttl
+func (s *MemorySessionStore[T]) Set(id string, v T, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = s.TTL
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.expire(now)
+	if _, ok := s.m[id]; !ok {
+		s.stats.Added++
+	}
+	e := sessionEntry[T]{v: v}
+	if ttl != 0 {
+		e.expire = now.Add(ttl)
+	}
+	s.m[id] = e
+}
+
+// Delete removes data of the session
+func (s *MemorySessionStore[T]) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[id]; ok {
+		delete(s.m, id)
+		s.stats.Deleted++
+	}
+}
+
+// Range calls f for each session until f returns false.
+// f must not access the store.
+func (s *MemorySessionStore[T]) Range(f func(id string, v T) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.expire(now)
+	for k, e := range s.m {
+		if e.alive(now) && !f(k, e.v) {
+			return
+		}
+	}
+}
+
+// Stats returns metrics of the store
+func (s *MemorySessionStore[T]) Stats() SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.stats
+	r.Size = len(s.m)
+	return r
+}
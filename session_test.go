@@ -0,0 +1,81 @@
+package diameter_test
+
+import (
+	"testing"
+	"time"
+
+	dia "github.com/fkgi/diameter"
+	dt "github.com/fkgi/diameter/diametertest"
+)
+
+func TestMemorySessionStoreTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     time.Duration // default TTL of store
+		ttl     time.Duration // TTL of Set
+		advance time.Duration
+		found   bool
+	}{
+		{"no expiry", 0, 0, time.Hour, true},
+		{"before default", time.Minute, 0, time.Second * 59, true},
+		{"at default", time.Minute, 0, time.Minute, false},
+		{"after default", time.Minute, 0, time.Minute * 2, false},
+		{"before ttl", time.Minute, time.Second * 10, time.Second * 9, true},
+		{"after ttl", time.Minute, time.Second * 10, time.Second * 11, false},
+		{"ttl longer than default", time.Second, time.Minute, time.Second * 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := dt.NewClock(time.Unix(0, 0))
+			s := dia.NewSessionStore[int](tt.def)
+			s.Clock = clk
+			s.Set("sess", 1, tt.ttl)
+			clk.Advance(tt.advance)
+
+			v, ok := s.Get("sess")
+			if ok != tt.found {
+				t.Fatalf("found is %v, want %v", ok, tt.found)
+			}
+			if ok && v != 1 {
+				t.Fatalf("value is %d, want 1", v)
+			}
+			n := 0
+			s.Range(func(string, int) bool { n++; return true })
+			if ok != (n == 1) {
+				t.Fatalf("ranged sessions are %d", n)
+			}
+		})
+	}
+}
+
+func TestMemorySessionStoreStats(t *testing.T) {
+	clk := dt.NewClock(time.Unix(0, 0))
+	s := &dia.MemorySessionStore[string]{TTL: time.Minute, Clock: clk}
+
+	s.Set("a", "x", 0)
+	s.Set("b", "y", 0)
+	s.Set("a", "z", 0)
+	s.Set("c", "w", time.Hour)
+	s.Delete("b")
+	s.Delete("b")
+	if v, _ := s.Get("a"); v != "z" {
+		t.Fatalf("value is %s, want z", v)
+	}
+
+	// expiry extends by Set
+	clk.Advance(time.Second * 30)
+	s.Set("a", "z", 0)
+	clk.Advance(time.Second * 45)
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("session is expired before TTL")
+	}
+
+	clk.Advance(time.Minute)
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("session is not expired")
+	}
+	want := dia.SessionStats{Size: 1, Added: 3, Deleted: 1, Expired: 1}
+	if st := s.Stats(); st != want {
+		t.Fatalf("stats is %+v, want %+v", st, want)
+	}
+}
@@ -13,48 +13,90 @@ func (eventRcvCER) String() string {
 	return "Rcv-CER"
 }
 
+func (eventRcvCER) peerLevel() {}
+
 func (v eventRcvCER) exec(c *Conn) error {
-	c.RxReq++
-	if c.state != waitCER {
-		c.Reject++
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	c.stats.RxReq.Add(1)
+	if c.initiator && c.getState() == waitICEA {
+		c.stats.Reject.Add(1)
+		return c.rcvNonCEA(v)
+	}
+	if c.initiator || c.getState() != closed {
+		c.stats.Reject.Add(1)
+		return NotAcceptableEvent{stateEvent: v, state: c.getState()}
 	}
 
 	cer, _, e := CER{}.FromRaw(v.m)
-	Notify(CapabilityExchangeEvent{tx: false, req: true, conn: c, Err: e})
+	publish(CapabilityExchangeEvent{newMessageInfo(c, Rx, v.m, e)})
 
 	if e != nil {
-		// ToDo
-		// make error answer for undecodable CER
-		c.Reject++
-		c.con.Close()
+		c.stats.Reject.Add(1)
+		a := errorAnswer(v.m, e)
+		c.write(a)
+		publish(CapabilityExchangeEvent{newMessageInfo(c, Tx, a, e)})
+		c.disc(e)
+		return e
+	}
+
+	// R-Conn-CER is event of the peer that has other Conn.
+	// CER from local host is loopback connection and it is not elected.
+	s := closed
+	var i *Conn
+	if h := cer.(CER).OriginHost; h != Host {
+		i = findPeer(h, c)
+	}
+	if i != nil {
+		s = i.getState()
+	}
+	t, ok := stateTable[s]["R-Conn-CER"]
+	if !ok {
+		c.stats.Reject.Add(1)
+		e = NotAcceptableEvent{stateEvent: v, state: s}
+		c.disc(e)
 		return e
 	}
+	if t.action[0] == "R-Reject" {
+		c.stats.Reject.Add(1)
+		c.disc(ConnectionRefused{})
+		return ConnectionRefused{}
+	}
 
-	cea := HandleCER(cer.(CER), c)
+	// R-Accept, Process-CER
+	var cea CEA
+	if r, e := admit(cer.(CER), c); e != nil {
+		c.stats.Reject.Add(1)
+		cea = rejectCEA(r, c)
+	} else {
+		cea = HandleCER(cer.(CER), c)
+	}
 	m := cea.ToRaw("")
 	m.HbHID = v.m.HbHID
 	m.EtEID = v.m.EtEID
 	if cea.ResultCode != DiameterSuccess {
-		m.FlgE = true
+		m.FlgE = cea.Result().Class() == ProtocolError
+		e = c.write(m)
+		if e == nil {
+			e = FailureAnswer{cea}
+		}
+		publish(CapabilityExchangeEvent{newMessageInfo(c, Tx, m, e)})
+		c.disc(e)
+		return e
 	}
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e = m.WriteTo(c.con)
+	c.cea = m
 
-	if e == nil && cea.ResultCode != DiameterSuccess {
-		e = FailureAnswer{cea}
-	}
-	if e == nil {
-		c.state = open
-		c.wdTimer = time.AfterFunc(c.Peer.WDInterval, c.watchdog)
-		c.Since = time.Now()
+	if i == nil {
+		c.sndCEA()
+		return nil
 	}
-
-	Notify(CapabilityExchangeEvent{tx: true, req: false, conn: c, Err: e})
-	if e != nil {
-		c.con.Close()
+	c.pair, i.pair = i, c
+	c.setState(t.next)
+	old := i.getState()
+	i.setState(t.next)
+	publish(newStateUpdate(old, v, i, nil))
+	if t.next == waitReturns {
+		c.elect()
 	}
-	return e
+	return nil
 }
 
 // RcvCEA
@@ -66,35 +108,45 @@ func (eventRcvCEA) String() string {
 	return "Rcv-CEA"
 }
 
+func (eventRcvCEA) peerLevel() {}
+
 func (v eventRcvCEA) exec(c *Conn) error {
-	if c.state != waitCEA {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	t, e := c.transit(v, c.role()+"Rcv-CEA")
+	if e != nil {
+		return e
 	}
-	ch, ok := c.sndstack[v.m.HbHID]
-	if !ok {
+	if v.m.HbHID != c.cerID {
 		return UnknownIDAnswer{v.m}
 	}
-	delete(c.sndstack, v.m.HbHID)
 
 	cea, _, e := CEA{}.FromRaw(v.m)
 	if e == nil {
+		c.countRx(cea.Result())
 		HandleCEA(cea.(CEA), c)
-		if cea.Result() == DiameterSuccess {
-			c.state = open
-			c.wdTimer = time.AfterFunc(c.Peer.WDInterval, c.watchdog)
-			c.Since = time.Now()
-		} else {
+		if cea.Result() != ResultCode(DiameterSuccess) {
 			e = FailureAnswer{cea}
 		}
 	}
+	publish(CapabilityExchangeEvent{newMessageInfo(c, Rx, v.m, e)})
 
-	Notify(CapabilityExchangeEvent{tx: false, req: false, conn: c, Err: e})
+	r := c.pair
+	c.unpair()
 	if e != nil {
-		c.con.Close()
-		v.m = RawMsg{}
+		if r != nil {
+			// responder Conn takes over
+			old := r.getState()
+			r.sndCEA()
+			publish(newStateUpdate(old, v, r, nil))
+		}
+		c.disc(e)
+		return e
 	}
-	ch <- v.m
-	return e
+	if r != nil {
+		// R-Disc
+		r.rDisc(v)
+	}
+	c.opened(t.next)
+	return nil
 }
 
 type eventRcvDWR struct {
@@ -106,19 +158,26 @@ func (eventRcvDWR) String() string {
 }
 
 func (v eventRcvDWR) exec(c *Conn) error {
-	c.RxReq++
-	if c.state != open {
-		c.Reject++
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	c.stats.RxReq.Add(1)
+	if c.initiator && c.getState() == waitICEA {
+		c.stats.Reject.Add(1)
+		return c.rcvNonCEA(v)
+	}
+	if _, e := c.transit(v, c.role()+"Rcv-DWR"); e != nil {
+		c.stats.Reject.Add(1)
+		return e
 	}
 
 	dwr, _, e := DWR{}.FromRaw(v.m)
-	Notify(WatchdogEvent{tx: false, req: true, conn: c, Err: e})
+	publish(WatchdogEvent{newMessageInfo(c, Rx, v.m, e)})
 
 	if e != nil {
-		// ToDo
-		// make error answer for undecodable CER
-		c.Reject++
+		c.stats.Reject.Add(1)
+		a := errorAnswer(v.m, e)
+		if e2 := c.write(a); e2 != nil {
+			c.con.Close()
+		}
+		publish(WatchdogEvent{newMessageInfo(c, Tx, a, e)})
 		return e
 	}
 
@@ -126,11 +185,11 @@ func (v eventRcvDWR) exec(c *Conn) error {
 	m := dwa.ToRaw("")
 	m.HbHID = v.m.HbHID
 	m.EtEID = v.m.EtEID
+	sndLoad(c, &m, false)
 	if dwa.ResultCode != DiameterSuccess {
 		m.FlgE = true
 	}
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e = m.WriteTo(c.con)
+	e = c.write(m)
 
 	if e == nil && dwa.ResultCode != DiameterSuccess {
 		e = FailureAnswer{dwa}
@@ -140,7 +199,7 @@ func (v eventRcvDWR) exec(c *Conn) error {
 		c.wdTimer.Reset(c.Peer.WDInterval)
 	}
 
-	Notify(WatchdogEvent{tx: true, req: false, conn: c, Err: e})
+	publish(WatchdogEvent{newMessageInfo(c, Tx, m, e)})
 	if e != nil {
 		c.con.Close()
 	}
@@ -157,19 +216,23 @@ func (eventRcvDWA) String() string {
 }
 
 func (v eventRcvDWA) exec(c *Conn) error {
-	if c.state != open {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	if c.initiator && c.getState() == waitICEA {
+		return c.rcvNonCEA(v)
+	}
+	if _, e := c.transit(v, c.role()+"Rcv-DWA"); e != nil {
+		return e
 	}
-	ch, ok := c.sndstack[v.m.HbHID]
+	ch, ok := c.takePending(v.m.HbHID)
 	if !ok {
 		return UnknownIDAnswer{v.m}
 	}
-	delete(c.sndstack, v.m.HbHID)
 
 	dwa, _, e := DWA{}.FromRaw(v.m)
 	if e == nil {
+		c.rcvLoad(v.m)
+		c.countRx(dwa.Result())
 		HandleDWA(dwa.(DWA), c)
-		if dwa.Result() == uint32(DiameterSuccess) {
+		if dwa.Result() == ResultCode(DiameterSuccess) {
 			c.wdCount = 0
 		} else {
 			e = FailureAnswer{dwa}
@@ -178,11 +241,11 @@ func (v eventRcvDWA) exec(c *Conn) error {
 	c.wdTimer.Stop()
 	c.wdTimer.Reset(c.Peer.WDInterval)
 
-	Notify(WatchdogEvent{tx: false, req: false, conn: c, Err: e})
+	publish(WatchdogEvent{newMessageInfo(c, Rx, v.m, e)})
 	if e != nil {
 		v.m = RawMsg{}
 	}
-	ch <- v.m
+	ch <- pendingAns{m: v.m, e: e}
 	return e
 }
 
@@ -194,20 +257,30 @@ func (eventRcvDPR) String() string {
 	return "Rcv-DPR"
 }
 
+func (eventRcvDPR) peerLevel() {}
+
 func (v eventRcvDPR) exec(c *Conn) error {
-	c.RxReq++
-	if c.state != open {
-		c.Reject++
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	c.stats.RxReq.Add(1)
+	if c.initiator && c.getState() == waitICEA {
+		c.stats.Reject.Add(1)
+		return c.rcvNonCEA(v)
+	}
+	t, e := c.transit(v, c.role()+"Rcv-DPR")
+	if e != nil {
+		c.stats.Reject.Add(1)
+		return e
 	}
 
 	dpr, _, e := DPR{}.FromRaw(v.m)
-	Notify(PurgeEvent{tx: false, req: true, conn: c, Err: e})
+	publish(PurgeEvent{newMessageInfo(c, Rx, v.m, e)})
 
 	if e != nil {
-		// ToDo
-		// make error answer for undecodable CER
-		c.Reject++
+		c.stats.Reject.Add(1)
+		a := errorAnswer(v.m, e)
+		if e2 := c.write(a); e2 != nil {
+			c.con.Close()
+		}
+		publish(PurgeEvent{newMessageInfo(c, Tx, a, e)})
 		return e
 	}
 
@@ -218,17 +291,16 @@ func (v eventRcvDPR) exec(c *Conn) error {
 	if dpa.ResultCode != DiameterSuccess {
 		m.FlgE = true
 	} else {
-		c.state = closing
+		c.setState(t.next)
 		c.wdTimer.Stop()
 		c.Since = time.Time{}
-		c.wdTimer = time.AfterFunc(TransportTimeout, func() {
+		c.wdTimer = c.clock.AfterFunc(TransportTimeout, func() {
 			c.con.Close()
 		})
 	}
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e = m.WriteTo(c.con)
+	e = c.write(m)
 
-	Notify(&PurgeEvent{tx: true, req: false, conn: c, Err: e})
+	publish(PurgeEvent{newMessageInfo(c, Tx, m, e)})
 	if e != nil {
 		c.con.Close()
 	}
@@ -243,30 +315,35 @@ func (eventRcvDPA) String() string {
 	return "Rcv-DPA"
 }
 
+func (eventRcvDPA) peerLevel() {}
+
 func (v eventRcvDPA) exec(c *Conn) error {
-	if c.state != closing {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	if c.initiator && c.getState() == waitICEA {
+		return c.rcvNonCEA(v)
+	}
+	if _, e := c.transit(v, c.role()+"Rcv-DPA"); e != nil {
+		return e
 	}
-	ch, ok := c.sndstack[v.m.HbHID]
+	ch, ok := c.takePending(v.m.HbHID)
 	if !ok {
 		return UnknownIDAnswer{v.m}
 	}
-	delete(c.sndstack, v.m.HbHID)
 
 	dpa, _, e := DPA{}.FromRaw(v.m)
 	if e == nil {
+		c.countRx(dpa.Result())
 		HandleDPA(dpa.(DPA), c)
-		if dpa.Result() != uint32(DiameterSuccess) {
+		if dpa.Result() != ResultCode(DiameterSuccess) {
 			e = FailureAnswer{dpa}
 		}
 	}
 
-	Notify(PurgeEvent{tx: false, req: false, conn: c, Err: e})
-	c.con.Close()
+	publish(PurgeEvent{newMessageInfo(c, Rx, v.m, e)})
+	c.disc(e)
 	if e != nil {
 		v.m = RawMsg{}
 	}
-	ch <- v.m
+	ch <- pendingAns{m: v.m, e: e}
 	return e
 }
 
@@ -280,55 +357,107 @@ func (eventRcvMsg) String() string {
 
 func (v eventRcvMsg) exec(c *Conn) (e error) {
 
+	if c.initiator && c.getState() == waitICEA {
+		if v.m.FlgR {
+			c.stats.RxReq.Add(1)
+			c.stats.Reject.Add(1)
+		}
+		return c.rcvNonCEA(v)
+	}
 	if v.m.FlgR {
-		c.RxReq++
-		if c.state != open {
-			c.Reject++
-			return NotAcceptableEvent{stateEvent: v, state: c.state}
+		c.stats.RxReq.Add(1)
+		if _, e = c.transit(v, c.role()+"Rcv-Message"); e != nil {
+			c.stats.Reject.Add(1)
+			return
 		}
 
 		var cause uint32
 
-		if app, ok := supportedApps[v.m.AppID]; !ok {
+		if app, ok := supportedApps()[v.m.AppID]; !ok {
 			cause = DiameterApplicationUnsupported
 		} else if _, ok = app.req[v.m.Code]; !ok {
 			cause = DiameterCommandUnspported
 		}
 
 		if cause == 0 {
-		} else if app, ok := supportedApps[0xffffffff]; !ok {
+		} else if app, ok := supportedApps()[0xffffffff]; !ok {
 		} else if _, ok = app.req[0]; ok {
 			cause = 0
 		}
 
 		if cause != 0 {
-			req, sid, _ := GenericReq{}.FromRaw(v.m)
-			a := req.Failed(cause).ToRaw(sid)
-			a.HbHID = v.m.HbHID
-			a.EtEID = v.m.EtEID
-			c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-			_, e = a.WriteTo(c.con)
-		} else {
-			c.rcvstack <- v.m
+			c.stats.Reject.Add(1)
+			e = c.write(errorAnswer(v.m, InvalidMessage(cause)))
+		} else if c.rcvDuplicate(v.m) {
+		} else if !c.rcvstack.push(v.m, priorityOf(v.m, NoDRMP.Level())) {
+			// Rx queue is full, the answer is also sent to duplicate request
+			c.stats.Reject.Add(1)
+			a := errorAnswer(v.m, InvalidMessage(DiameterTooBusy))
+			sndDuplicate(v.m, a)
+			e = c.write(a)
 		}
 	} else {
-		if c.state != open {
-			return NotAcceptableEvent{stateEvent: v, state: c.state}
+		if _, e = c.transit(v, c.role()+"Rcv-Message"); e != nil {
+			return
 		}
 
-		ch, ok := c.sndstack[v.m.HbHID]
+		ch, ok := c.takePending(v.m.HbHID)
 		if !ok {
 			return
 		}
-		delete(c.sndstack, v.m.HbHID)
-		ch <- v.m
+		c.countRx(resultOf(v.m))
+		ch <- pendingAns{m: v.m}
 	}
 	c.wdTimer.Stop()
 	c.wdTimer.Reset(c.Peer.WDInterval)
 
-	Notify(MessageEvent{tx: false, req: v.m.FlgR, conn: c, Err: e})
+	publish(MessageEvent{newMessageInfo(c, Rx, v.m, e)})
 	if e != nil {
 		c.con.Close()
 	}
 	return
 }
+
+// RcvErr
+type eventRcvErr struct {
+	m RawMsg
+	e error
+}
+
+func (eventRcvErr) String() string {
+	return "Rcv-Err"
+}
+
+func (v eventRcvErr) exec(c *Conn) (e error) {
+	if c.initiator && c.getState() == waitICEA {
+		if v.m.FlgR {
+			c.stats.RxReq.Add(1)
+			c.stats.Reject.Add(1)
+		}
+		return c.rcvNonCEA(v)
+	}
+	if !v.m.FlgR {
+		if _, e = c.transit(v, c.role()+"Rcv-Message"); e != nil {
+			return
+		}
+		if ch, ok := c.takePending(v.m.HbHID); ok {
+			ch <- pendingAns{e: v.e}
+		}
+		return v.e
+	}
+	c.stats.RxReq.Add(1)
+	c.stats.Reject.Add(1)
+	if _, e = c.transit(v, c.role()+"Rcv-Message"); e != nil {
+		return
+	}
+
+	a := errorAnswer(v.m, v.e)
+	e = c.write(a)
+
+	publish(MessageEvent{newMessageInfo(c, Tx, a, v.e)})
+	if e != nil {
+		c.con.Close()
+		return
+	}
+	return v.e
+}
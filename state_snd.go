@@ -4,40 +4,18 @@ import (
 	"time"
 )
 
-type state int
-
-func (s state) String() string {
-	switch s {
-	case shutdown:
-		return "shutdown"
-	case closed:
-		return "closed"
-	case waitCER:
-		return "waitCER"
-	case waitCEA:
-		return "waitCEA"
-	case open:
-		return "open"
-	case closing:
-		return "closing"
-	}
-	return "<nil>"
-}
-
-const (
-	shutdown state = iota
-	closed
-	waitCER
-	waitCEA
-	open
-	closing
-)
-
 type stateEvent interface {
 	exec(p *Conn) error
 	String() string
 }
 
+// peerEvent is event that changes state of peer.
+// It is executed with fsmLock.
+type peerEvent interface {
+	stateEvent
+	peerLevel()
+}
+
 // Init
 type eventInit struct{}
 
@@ -46,31 +24,90 @@ func (eventInit) String() string {
 }
 
 func (v eventInit) exec(c *Conn) error {
-	return NotAcceptableEvent{stateEvent: v, state: c.state}
+	return NotAcceptableEvent{stateEvent: v, state: c.getState()}
+}
+
+// Start
+type eventStart struct{}
+
+func (eventStart) String() string {
+	return "Start"
+}
+
+func (eventStart) peerLevel() {}
+
+func (v eventStart) exec(c *Conn) error {
+	t, e := c.transit(v, "Start")
+	if e != nil {
+		return e
+	}
+	// I-Snd-Conn-Req is done by caller of Dial
+	c.setState(t.next)
+	return nil
 }
 
-// Connect
-type eventConnect struct {
+// I-Rcv-Conn-Ack
+type eventConnAck struct {
 	m RawMsg
 }
 
-func (eventConnect) String() string {
-	return "Connect"
+func (eventConnAck) String() string {
+	return "I-Rcv-Conn-Ack"
 }
 
-func (v eventConnect) exec(c *Conn) error {
-	if c.state != closed {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+func (eventConnAck) peerLevel() {}
+
+func (v eventConnAck) exec(c *Conn) error {
+	t, e := c.transit(v, "I-Rcv-Conn-Ack")
+	if e != nil {
+		return e
 	}
-	c.state = waitCEA
 
-	c.TxReq++
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e := v.m.WriteTo(c.con)
-	Notify(CapabilityExchangeEvent{tx: true, req: true, conn: c, Err: e})
+	c.cerID = v.m.HbHID
+	e = c.write(v.m)
+	publish(CapabilityExchangeEvent{newMessageInfo(c, Tx, v.m, e)})
 	if e != nil {
-		c.con.Close()
+		if r := c.pair; r != nil {
+			r.disc(e)
+		}
+		c.disc(e)
+		return e
 	}
+
+	c.setState(t.next)
+	if r := c.pair; r != nil && t.next == waitReturns {
+		r.setState(waitReturns)
+		r.elect()
+	}
+	return nil
+}
+
+// Timeout
+type eventTimeout struct {
+	m RawMsg
+}
+
+func (eventTimeout) String() string {
+	return "Timeout"
+}
+
+func (eventTimeout) peerLevel() {}
+
+func (v eventTimeout) exec(c *Conn) error {
+	if ch, ok := c.takePending(v.m.HbHID); ok {
+		ch <- pendingAns{e: TimeoutExpired{}}
+	}
+	if _, e := c.transit(v, "Timeout"); e != nil {
+		return e
+	}
+
+	e := TimeoutExpired{}
+	if r := c.pair; r != nil {
+		old := r.getState()
+		r.disc(e)
+		publish(newStateUpdate(old, v, r, e))
+	}
+	c.disc(e)
 	return e
 }
 
@@ -84,8 +121,8 @@ func (eventWatchdog) String() string {
 }
 
 func (v eventWatchdog) exec(c *Conn) error {
-	if c.state != open {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	if !c.getState().isOpen() {
+		return NotAcceptableEvent{stateEvent: v, state: c.getState()}
 	}
 
 	c.wdCount++
@@ -94,10 +131,8 @@ func (v eventWatchdog) exec(c *Conn) error {
 		return WatchdogExpired{}
 	}
 
-	c.TxReq++
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e := v.m.WriteTo(c.con)
-	Notify(WatchdogEvent{tx: true, req: true, conn: c, Err: e})
+	e := c.write(v.m)
+	publish(WatchdogEvent{newMessageInfo(c, Tx, v.m, e)})
 	if e != nil {
 		c.con.Close()
 	}
@@ -113,19 +148,20 @@ func (eventStop) String() string {
 	return "Stop"
 }
 
+func (eventStop) peerLevel() {}
+
 func (v eventStop) exec(c *Conn) error {
-	if c.state != open {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	t, e := c.transit(v, "Stop")
+	if e != nil {
+		return e
 	}
 
-	c.state = closing
+	c.setState(t.next)
 	c.wdTimer.Stop()
 	c.Since = time.Time{}
 
-	c.TxReq++
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e := v.m.WriteTo(c.con)
-	Notify(PurgeEvent{tx: true, req: true, conn: c, Err: e})
+	e = c.write(v.m)
+	publish(PurgeEvent{newMessageInfo(c, Tx, v.m, e)})
 	if e != nil {
 		c.con.Close()
 	}
@@ -139,39 +175,82 @@ func (eventPeerDisc) String() string {
 	return "Peer-Disc"
 }
 
+func (eventPeerDisc) peerLevel() {}
+
 func (v eventPeerDisc) exec(c *Conn) error {
 	c.con.Close()
-	c.state = closed
+	if c.wdTimer != nil {
+		c.wdTimer.Stop()
+	}
 	c.Since = time.Time{}
 
-	for _, ch := range c.sndstack {
-		ch <- RawMsg{}
+	for _, ch := range c.takeAllPending() {
+		ch <- pendingAns{e: ConnectionRefused{}}
 	}
-	c.rcvstack <- RawMsg{}
+	c.rcvstack.close()
 
+	t, e := c.transit(v, c.role()+"Peer-Disc")
+	if p := c.pair; e == nil && p != nil {
+		old := p.getState()
+		c.unpair()
+		if t.next == rOpen {
+			// I-Disc, R-Snd-CEA
+			p.sndCEA()
+		} else {
+			// R-Disc
+			p.setState(t.next)
+		}
+		publish(newStateUpdate(old, v, p, nil))
+	}
+	c.disc(ConnectionRefused{})
 	return nil
 }
 
-// Snd MSG
-type eventSndMsg struct {
-	m RawMsg
-}
+// Snd MSG, message is taken from sndqueue in priority order
+type eventSndMsg struct{}
 
 func (eventSndMsg) String() string {
 	return "Snd-MSG"
 }
 
 func (v eventSndMsg) exec(c *Conn) error {
-	if c.state != open {
-		return NotAcceptableEvent{stateEvent: v, state: c.state}
+	q, ok := c.sndqueue.take(false)
+	if !ok {
+		return nil
+	}
+	m := q.m
+	if _, e := c.transit(v, "Send-Message"); e != nil {
+		return e
 	}
 
-	c.TxReq++
-	c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
-	_, e := v.m.WriteTo(c.con)
-	Notify(MessageEvent{tx: true, req: v.m.FlgR, conn: c, Err: e})
-	if e != nil {
+	e := c.writeAt(m, q.pri)
+	publish(MessageEvent{newMessageInfo(c, Tx, m, e)})
+	if _, ok := e.(WriteQueueFull); ok && m.FlgR {
+		// backpressure, request is failed without closing the Conn
+		if ch, ok := c.takePending(m.HbHID); ok {
+			ch <- pendingAns{e: e}
+		}
+	} else if e != nil {
 		c.con.Close()
 	}
 	return e
 }
+
+// Snd-Timeout
+type eventSndTimeout struct {
+	m RawMsg
+}
+
+func (eventSndTimeout) String() string {
+	return "Snd-Timeout"
+}
+
+func (v eventSndTimeout) exec(c *Conn) error {
+	ch, ok := c.takePending(v.m.HbHID)
+	if !ok {
+		return nil
+	}
+	c.stats.TxReqTimeout.Add(1)
+	ch <- pendingAns{m: v.m, e: TimeoutExpired{}}
+	return nil
+}
@@ -0,0 +1,266 @@
+package diameter
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets is upper bounds in seconds of Send round trip histogram
+var LatencyBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type msgKey struct {
+	peer  string
+	app   uint32
+	cmd   uint32
+	other bool
+	tx    bool
+	req   bool
+	class ResultClass
+}
+
+type latencyKey struct {
+	peer  string
+	app   uint32
+	cmd   uint32
+	other bool
+}
+
+// commandOf returns application and command of m for labels.
+// Command that is not supported is counted as other, so the number of
+// series is not increased by arbitrary command from the peer.
+func commandOf(m RawMsg) (app, cmd uint32, other bool) {
+	if m.AppID == 0 && (m.Code == 257 || m.Code == 280 || m.Code == 282) {
+		return m.AppID, m.Code, false
+	}
+	if set, ok := supportedApps()[m.AppID]; ok {
+		if _, ok := set.req[m.Code]; ok {
+			return m.AppID, m.Code, false
+		}
+	}
+	return 0, 0, true
+}
+
+func commandLabel(app, cmd uint32, other bool) string {
+	if other {
+		return "application=\"other\",command=\"other\""
+	}
+	return fmt.Sprintf("application=\"%d\",command=\"%d\"", app, cmd)
+}
+
+type histogram struct {
+	sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	h.Lock()
+	defer h.Unlock()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+var stats = struct {
+	sync.RWMutex
+	msg     map[msgKey]*atomic.Uint64
+	latency map[latencyKey]*histogram
+	conns   map[*Conn]uint64 // ID of connection label
+	connID  uint64
+}{
+	msg:     make(map[msgKey]*atomic.Uint64),
+	latency: make(map[latencyKey]*histogram),
+	conns:   make(map[*Conn]uint64)}
+
+func countMsg(c *Conn, m RawMsg, tx bool) {
+	k := msgKey{peer: c.Peer.String(), tx: tx, req: m.FlgR}
+	k.app, k.cmd, k.other = commandOf(m)
+	if !m.FlgR {
+		k.class = resultOf(m).Class()
+	}
+
+	stats.RLock()
+	v, ok := stats.msg[k]
+	stats.RUnlock()
+	if !ok {
+		stats.Lock()
+		if v, ok = stats.msg[k]; !ok {
+			v = new(atomic.Uint64)
+			stats.msg[k] = v
+		}
+		stats.Unlock()
+	}
+	v.Add(1)
+}
+
+func observeLatency(c *Conn, m RawMsg, d time.Duration) {
+	k := latencyKey{peer: c.Peer.String()}
+	k.app, k.cmd, k.other = commandOf(m)
+
+	stats.RLock()
+	h, ok := stats.latency[k]
+	stats.RUnlock()
+	if !ok {
+		stats.Lock()
+		if h, ok = stats.latency[k]; !ok {
+			h = &histogram{
+				bounds: append([]float64{}, LatencyBuckets...),
+				counts: make([]uint64, len(LatencyBuckets))}
+			stats.latency[k] = h
+		}
+		stats.Unlock()
+	}
+	h.observe(d.Seconds())
+}
+
+func addStatsConn(c *Conn) {
+	stats.Lock()
+	stats.connID++
+	stats.conns[c] = stats.connID
+	stats.Unlock()
+}
+
+func delStatsConn(c *Conn) {
+	stats.Lock()
+	delete(stats.conns, c)
+	stats.Unlock()
+}
+
+// StatsHandler returns http.Handler that serves message statistics,
+// Send latency histograms and queue length in Prometheus text format.
+// Message statistics are sum of Conns of the peer, and counters of each
+// Conn has connection label that is unique in the process.
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(promText())
+	})
+}
+
+func promText() []byte {
+	w := new(bytes.Buffer)
+	stats.RLock()
+	defer stats.RUnlock()
+
+	mk := make([]msgKey, 0, len(stats.msg))
+	for k := range stats.msg {
+		mk = append(mk, k)
+	}
+	sort.Slice(mk, func(i, j int) bool {
+		a, b := mk[i], mk[j]
+		if a.peer != b.peer {
+			return a.peer < b.peer
+		}
+		if a.other != b.other {
+			return b.other
+		}
+		if a.app != b.app {
+			return a.app < b.app
+		}
+		if a.cmd != b.cmd {
+			return a.cmd < b.cmd
+		}
+		if a.tx != b.tx {
+			return a.tx
+		}
+		if a.req != b.req {
+			return a.req
+		}
+		return a.class < b.class
+	})
+	fmt.Fprintln(w, "# HELP diameter_messages_total Number of Diameter messages.")
+	fmt.Fprintln(w, "# TYPE diameter_messages_total counter")
+	for _, k := range mk {
+		dir, typ := "rx", "request"
+		if k.tx {
+			dir = "tx"
+		}
+		if !k.req {
+			typ = "answer"
+		}
+		fmt.Fprintf(w,
+			"diameter_messages_total{peer=\"%s\",%s,direction=\"%s\",type=\"%s\"",
+			promEscape(k.peer), commandLabel(k.app, k.cmd, k.other), dir, typ)
+		if !k.req {
+			fmt.Fprintf(w, ",class=\"%s\"", k.class)
+		}
+		fmt.Fprintf(w, "} %d\n", stats.msg[k].Load())
+	}
+
+	lk := make([]latencyKey, 0, len(stats.latency))
+	for k := range stats.latency {
+		lk = append(lk, k)
+	}
+	sort.Slice(lk, func(i, j int) bool {
+		a, b := lk[i], lk[j]
+		if a.peer != b.peer {
+			return a.peer < b.peer
+		}
+		if a.other != b.other {
+			return b.other
+		}
+		if a.app != b.app {
+			return a.app < b.app
+		}
+		return a.cmd < b.cmd
+	})
+	fmt.Fprintln(w, "# HELP diameter_request_duration_seconds Round trip time of sent requests.")
+	fmt.Fprintln(w, "# TYPE diameter_request_duration_seconds histogram")
+	for _, k := range lk {
+		h := stats.latency[k]
+		l := fmt.Sprintf("peer=\"%s\",%s",
+			promEscape(k.peer), commandLabel(k.app, k.cmd, k.other))
+		h.Lock()
+		for i, b := range h.bounds {
+			fmt.Fprintf(w, "diameter_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n",
+				l, b, h.counts[i])
+		}
+		fmt.Fprintf(w, "diameter_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n",
+			l, h.count)
+		fmt.Fprintf(w, "diameter_request_duration_seconds_sum{%s} %g\n", l, h.sum)
+		fmt.Fprintf(w, "diameter_request_duration_seconds_count{%s} %d\n", l, h.count)
+		h.Unlock()
+	}
+
+	cs := make([]*Conn, 0, len(stats.conns))
+	for c := range stats.conns {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		return stats.conns[cs[i]] < stats.conns[cs[j]]
+	})
+	fmt.Fprintln(w, "# HELP diameter_duplicate_requests_total Number of received duplicate requests.")
+	fmt.Fprintln(w, "# TYPE diameter_duplicate_requests_total counter")
+	for _, c := range cs {
+		fmt.Fprintf(w, "diameter_duplicate_requests_total{peer=\"%s\",connection=\"%d\"} %d\n",
+			promEscape(c.Peer.String()), stats.conns[c], c.stats.Duplicate.Load())
+	}
+	fmt.Fprintln(w, "# HELP diameter_queue_length Number of messages waiting in queue.")
+	fmt.Fprintln(w, "# TYPE diameter_queue_length gauge")
+	for _, c := range cs {
+		l := fmt.Sprintf("peer=\"%s\",connection=\"%d\"",
+			promEscape(c.Peer.String()), stats.conns[c])
+		fmt.Fprintf(w, "diameter_queue_length{%s,queue=\"rx\"} %d\n", l, c.RxQueue())
+		fmt.Fprintf(w, "diameter_queue_length{%s,queue=\"tx\"} %d\n", l, c.TxQueue())
+	}
+
+	return w.Bytes()
+}
+
+func promEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,272 @@
+package diameter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// TopologyHiding is topology hiding of Diameter Edge Agent.
+// It is set to Conn of external peer by SetTopologyHiding, and
+// host names of internal network in relayed messages are rewritten.
+//
+// Origin-Host and Route-Record of sent request, and Origin-Host and
+// Error-Reporting-Host of sent answer are replaced by Hosts or opaque token.
+// Host part of Session-Id in sent request, and in sent answer if the host is
+// known internal host, is also replaced.
+// Destination-Host of received request, hidden names in received answer and
+// Session-Id that has hidden name are restored. Sent request has Proxy-Info with encrypted real Origin-Host,
+// so the real host is recovered from Proxy-State if mapping cache is lost.
+// CER, DWR and DPR are not rewritten.
+type TopologyHiding struct {
+	// Hosts maps internal host to external host name
+	Hosts map[Identity]Identity
+	// Realm is realm of token, empty is Realm
+	Realm Identity
+	// Key is secret of token and Proxy-State, that is
+	// 16, 24 or 32 bytes AES key. Empty is random key.
+	Key []byte
+
+	once  sync.Once
+	aead  cipher.AEAD
+	mac   []byte
+	mu    sync.RWMutex
+	cache map[string]Identity // hidden name to real host
+}
+
+// SetTopologyHiding set TopologyHiding of this Conn.
+// nil stop topology hiding.
+func (c *Conn) SetTopologyHiding(h *TopologyHiding) {
+	c.hiding.Store(h)
+}
+
+func (h *TopologyHiding) init() {
+	h.once.Do(func() {
+		k := h.Key
+		if len(k) == 0 {
+			k = make([]byte, 32)
+			rand.Read(k)
+		}
+		if b, e := aes.NewCipher(k); e == nil {
+			h.aead, _ = cipher.NewGCM(b)
+		}
+		m := hmac.New(sha256.New, k)
+		m.Write([]byte("topology-hiding"))
+		h.mac = m.Sum(nil)
+		h.cache = make(map[string]Identity)
+	})
+}
+
+// Hide returns external name of internal host v
+func (h *TopologyHiding) Hide(v Identity) Identity {
+	h.init()
+	for k, x := range h.Hosts {
+		if strings.EqualFold(string(k), string(v)) {
+			return x
+		}
+	}
+
+	t := h.token(v)
+	h.mu.Lock()
+	h.cache[strings.ToLower(string(t))] = v
+	h.mu.Unlock()
+	return t
+}
+
+// token returns opaque name of host v
+func (h *TopologyHiding) token(v Identity) Identity {
+	m := hmac.New(sha256.New, h.mac)
+	m.Write([]byte(strings.ToLower(string(v))))
+	r := h.Realm
+	if len(r) == 0 {
+		r = Realm
+	}
+	return Identity("th" + hex.EncodeToString(m.Sum(nil)[:8]) + "." + string(r))
+}
+
+// known returns true if v is internal host that is already hidden
+func (h *TopologyHiding) known(v Identity) bool {
+	h.init()
+	if strings.EqualFold(string(v), string(Host)) {
+		return true
+	}
+	for k := range h.Hosts {
+		if strings.EqualFold(string(k), string(v)) {
+			return true
+		}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.cache[strings.ToLower(string(h.token(v)))]
+	return ok
+}
+
+// Reveal returns internal host of external name v.
+// It returns false if v is not hidden name.
+func (h *TopologyHiding) Reveal(v Identity) (Identity, bool) {
+	h.init()
+	for k, x := range h.Hosts {
+		if strings.EqualFold(string(x), string(v)) {
+			return k, true
+		}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.cache[strings.ToLower(string(v))]
+	return r, ok
+}
+
+// seal returns Proxy-State of host v
+func (h *TopologyHiding) seal(v Identity) []byte {
+	if h.aead == nil {
+		return nil
+	}
+	n := make([]byte, h.aead.NonceSize())
+	rand.Read(n)
+	return h.aead.Seal(n, n, []byte(v), nil)
+}
+
+// open returns host in Proxy-State s
+func (h *TopologyHiding) open(s []byte) (Identity, bool) {
+	if h.aead == nil || len(s) < h.aead.NonceSize() {
+		return "", false
+	}
+	n := h.aead.NonceSize()
+	v, e := h.aead.Open(nil, s[:n], s[n:], nil)
+	if e != nil {
+		return "", false
+	}
+	return Identity(v), true
+}
+
+// rewrite returns copy of m that AVPs in codes are replaced by f.
+// Source-ID (649) is also replaced in Load AVP.
+func rewrite(m RawMsg, f func(Identity) Identity, codes ...uint32) RawMsg {
+	l := make([]RawAVP, 0, len(m.AVP)+1)
+	for _, a := range m.AVP {
+		if a.VenID == 0 && containsCode(codes, a.Code) {
+			var v Identity
+			if a.Decode(&v) == nil {
+				n := RawAVP{Code: a.Code, FlgV: a.FlgV, FlgM: a.FlgM, FlgP: a.FlgP}
+				n.Encode(f(v))
+				a = n
+			}
+		} else if a.VenID == 0 && a.Code == 650 && containsCode(codes, 649) {
+			var o []RawAVP
+			if a.Decode(&o) == nil {
+				n := RawAVP{Code: a.Code, FlgV: a.FlgV, FlgM: a.FlgM, FlgP: a.FlgP}
+				n.Encode(rewrite(RawMsg{AVP: o}, f, 649).AVP)
+				a = n
+			}
+		}
+		l = append(l, a)
+	}
+	m.AVP = l
+	return m
+}
+
+// rewriteSession returns copy of m that host part of Session-Id
+// is replaced by f
+func rewriteSession(m RawMsg, f func(Identity) Identity) RawMsg {
+	for i, a := range m.AVP {
+		if a.VenID != 0 || a.Code != 263 {
+			continue
+		}
+		s, e := GetSessionID(a)
+		if e != nil {
+			break
+		}
+		host, rest, ok := strings.Cut(s, ";")
+		if !ok {
+			break
+		}
+		n := string(f(Identity(host))) + ";" + rest
+		if n == s {
+			break
+		}
+		l := append([]RawAVP{}, m.AVP...)
+		l[i] = SetSessionID(n)
+		m.AVP = l
+		break
+	}
+	return m
+}
+
+func containsCode(l []uint32, c uint32) bool {
+	for _, v := range l {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *TopologyHiding) reveal(v Identity) Identity {
+	if r, ok := h.Reveal(v); ok {
+		return r
+	}
+	return v
+}
+
+// hideRequest rewrites request m that is sent to external peer
+func (h *TopologyHiding) hideRequest(m RawMsg) RawMsg {
+	h.init()
+	var org Identity
+	for _, a := range m.AVP {
+		if a.VenID == 0 && a.Code == 264 {
+			org, _ = GetOriginHost(a)
+		}
+	}
+	m = rewrite(m, h.Hide, 264, 282)
+	m = rewriteSession(m, h.Hide)
+	if len(org) == 0 {
+		return m
+	}
+	if s := h.seal(org); s != nil {
+		m.AVP = append(m.AVP, SetProxyInfo(h.Hide(Host), s))
+	}
+	return m
+}
+
+// revealAnswer rewrites answer m that is received from external peer
+func (h *TopologyHiding) revealAnswer(m RawMsg) RawMsg {
+	h.init()
+	l := make([]RawAVP, 0, len(m.AVP))
+	for _, a := range m.AVP {
+		if a.VenID == 0 && a.Code == 284 {
+			ph, ps, e := GetProxyInfo(a)
+			if e == nil && strings.EqualFold(string(ph), string(h.Hide(Host))) {
+				if org, ok := h.open(ps); ok {
+					h.Hide(org)
+				}
+				continue
+			}
+		}
+		l = append(l, a)
+	}
+	m.AVP = l
+	m = rewrite(m, h.reveal, 264, 282, 293, 294)
+	return rewriteSession(m, h.reveal)
+}
+
+// revealRequest rewrites request m that is received from external peer
+func (h *TopologyHiding) revealRequest(m RawMsg) RawMsg {
+	m = rewrite(m, h.reveal, 293)
+	return rewriteSession(m, h.reveal)
+}
+
+// hideAnswer rewrites answer m that is sent to external peer
+func (h *TopologyHiding) hideAnswer(m RawMsg) RawMsg {
+	m = rewrite(m, h.Hide, 264, 294, 649)
+	return rewriteSession(m, func(v Identity) Identity {
+		if h.known(v) {
+			return h.Hide(v)
+		}
+		return v
+	})
+}
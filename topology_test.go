@@ -0,0 +1,187 @@
+package diameter_test
+
+import (
+	"strings"
+	"testing"
+
+	dia "github.com/fkgi/diameter"
+	dt "github.com/fkgi/diameter/diametertest"
+)
+
+// identities returns values of Identity AVPs of code in m
+func identities(t *testing.T, m dia.RawMsg, code uint32) []dia.Identity {
+	var l []dia.Identity
+	for _, a := range m.AVP {
+		if a.VenID == 0 && a.Code == code {
+			var v dia.Identity
+			if e := a.Decode(&v); e != nil {
+				t.Fatal(e)
+			}
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+func sessionOf(t *testing.T, m dia.RawMsg) string {
+	a, ok := dt.FindAVP(m, 0, 263)
+	if !ok {
+		t.Fatal("no Session-Id")
+	}
+	s, e := dia.GetSessionID(a)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return s
+}
+
+func TestTopologyHidingRoundTrip(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	const inner dia.Identity = "hss1.internal.example"
+	tests := []struct {
+		name  string
+		hosts map[dia.Identity]dia.Identity
+		lost  bool // mapping cache is lost before answer
+		want  dia.Identity
+	}{
+		{"mapped", map[dia.Identity]dia.Identity{inner: "dea.example"}, false, "dea.example"},
+		{"token", nil, false, ""},
+		{"token without cache", nil, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt.Setup(t)
+			h := &dia.TopologyHiding{Hosts: tt.hosts, Key: key}
+			sid := string(inner) + ";1;2"
+			req := dia.RawMsg{Ver: dia.DiaVer, FlgR: true, Code: dt.CommandCode, AppID: dt.AppID,
+				AVP: []dia.RawAVP{
+					dia.SetSessionID(sid),
+					dia.SetOriginHost(inner),
+					dia.SetOriginRealm(dt.Realm),
+					dia.SetRouteRecord(inner)}}
+
+			hr := h.HideRequest(req)
+			org := identities(t, hr, 264)
+			if len(org) != 1 || strings.Contains(string(org[0]), "internal") {
+				t.Fatalf("Origin-Host is %v", org)
+			}
+			if len(tt.want) != 0 && org[0] != tt.want {
+				t.Fatalf("Origin-Host is %s, want %s", org[0], tt.want)
+			}
+			if rr := identities(t, hr, 282); len(rr) != 1 || rr[0] != org[0] {
+				t.Fatalf("Route-Record is %v", rr)
+			}
+			hs := sessionOf(t, hr)
+			if hs != string(org[0])+";1;2" {
+				t.Fatalf("Session-Id is %s", hs)
+			}
+			pi, ok := dt.FindAVP(hr, 0, 284)
+			if !ok {
+				t.Fatal("no Proxy-Info")
+			}
+			if r, ok := h.Reveal(org[0]); !ok || r != inner {
+				t.Fatalf("revealed host is %s", r)
+			}
+
+			if tt.lost {
+				h = &dia.TopologyHiding{Hosts: tt.hosts, Key: key}
+			}
+			ans := dia.RawMsg{Ver: dia.DiaVer, Code: dt.CommandCode, AppID: dt.AppID,
+				HbHID: hr.HbHID, EtEID: hr.EtEID,
+				AVP: []dia.RawAVP{
+					dia.SetSessionID(hs),
+					dia.SetResultCode(dia.DiameterSuccess),
+					dia.SetOriginHost("server.external.example"),
+					dia.SetOriginRealm("external.example"),
+					pi}}
+			ra := h.RevealAnswer(ans)
+			if s := sessionOf(t, ra); s != sid {
+				t.Fatalf("Session-Id is %s, want %s", s, sid)
+			}
+			dt.AssertNoAVP(t, ra, 0, 284)
+			if o := identities(t, ra, 264); len(o) != 1 || o[0] != "server.external.example" {
+				t.Fatalf("Origin-Host is %v", o)
+			}
+
+			// following request from external peer to the hidden host
+			in := dia.RawMsg{Ver: dia.DiaVer, FlgR: true, Code: dt.CommandCode, AppID: dt.AppID,
+				AVP: []dia.RawAVP{
+					dia.SetSessionID(hs),
+					dia.SetOriginHost("server.external.example"),
+					dia.SetOriginRealm("external.example"),
+					dia.SetDestinationHost(org[0])}}
+			rr := h.RevealRequest(in)
+			if d := identities(t, rr, 293); len(d) != 1 || d[0] != inner {
+				t.Fatalf("Destination-Host is %v", d)
+			}
+			if s := sessionOf(t, rr); s != sid {
+				t.Fatalf("Session-Id is %s, want %s", s, sid)
+			}
+		})
+	}
+}
+
+func TestTopologyHidingAnswer(t *testing.T) {
+	dt.Setup(t)
+	h := &dia.TopologyHiding{Hosts: map[dia.Identity]dia.Identity{dt.Host: "dea.example"}}
+	ans := dia.RawMsg{Ver: dia.DiaVer, Code: dt.CommandCode, AppID: dt.AppID,
+		AVP: []dia.RawAVP{
+			dia.SetSessionID("client.external.example;1;2"),
+			dia.SetResultCode(dia.DiameterSuccess),
+			dia.SetOriginHost(dt.Host),
+			dia.SetOriginRealm(dt.Realm),
+			dia.SetLoad(dia.Load{Type: dia.HostLoad, Value: 10, SourceID: dt.Host})}}
+
+	ha := h.HideAnswer(ans)
+	if o := identities(t, ha, 264); len(o) != 1 || o[0] != "dea.example" {
+		t.Fatalf("Origin-Host is %v", o)
+	}
+	a, ok := dt.FindAVP(ha, 0, 650)
+	if !ok {
+		t.Fatal("no Load")
+	}
+	l, e := dia.GetLoad(a)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if l.SourceID != "dea.example" || l.Value != 10 {
+		t.Fatalf("Load is %+v", l)
+	}
+	// Session-Id of unknown host is not rewritten
+	if s := sessionOf(t, ha); s != "client.external.example;1;2" {
+		t.Fatalf("Session-Id is %s", s)
+	}
+}
+
+func TestTopologyHidingSeal(t *testing.T) {
+	h := &dia.TopologyHiding{Key: []byte("0123456789abcdef")}
+	s := h.Seal("hss1.internal.example")
+
+	tests := []struct {
+		name string
+		h    *dia.TopologyHiding
+		s    []byte
+		ok   bool
+	}{
+		{"same key", &dia.TopologyHiding{Key: []byte("0123456789abcdef")}, s, true},
+		{"other key", &dia.TopologyHiding{Key: []byte("fedcba9876543210")}, s, false},
+		{"random key", &dia.TopologyHiding{}, s, false},
+		{"tampered", h, append(append([]byte{}, s[:len(s)-1]...), s[len(s)-1]^1), false},
+		{"short", h, s[:4], false},
+		{"empty", h, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := tt.h.Open(tt.s)
+			if ok != tt.ok {
+				t.Fatalf("opened is %v, want %v", ok, tt.ok)
+			}
+			if ok && v != "hss1.internal.example" {
+				t.Fatalf("host is %s", v)
+			}
+		})
+	}
+	if string(h.Seal("a")) == string(h.Seal("a")) {
+		t.Fatal("Proxy-State has same nonce")
+	}
+}
@@ -13,7 +13,7 @@ import (
 ALR is AlertServiceCentreRequest message.
  <ALR> ::= < Diameter Header: 8388648, REQ, PXY, 16777312 >
 		   < Session-Id >
-		   [ DRMP ] // not supported
+		   [ DRMP ]
 		   [ Vendor-Specific-Application-Id ]
 		   { Auth-Session-State }
 		   { Origin-Host }
@@ -32,6 +32,8 @@ ALR is AlertServiceCentreRequest message.
 		 * [ Route-Record ]
 */
 type ALR struct {
+	DRMP dia.DRMP
+
 	OriginHost       dia.Identity
 	OriginRealm      dia.Identity
 	DestinationHost  dia.Identity
@@ -76,6 +78,9 @@ func (v ALR) ToRaw(s string) dia.RawMsg {
 		AVP: make([]dia.RawAVP, 0, 15)}
 
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 
@@ -113,6 +118,8 @@ func (ALR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -137,19 +144,25 @@ func (ALR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 ||
-		len(v.DestinationRealm) == 0 || v.SCAddress.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = dia.MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = dia.MissingAVP(0, 296)
+	} else if len(v.DestinationRealm) == 0 {
+		e = dia.MissingAVP(0, 283)
+	} else if v.SCAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 3300)
 	} else if v.MSISDN.Length() == 0 && v.IMSI.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.MissingAVP(10415, 3102)
 	}
 	return v, s, e
 }
 
 // Failed make error message for timeout
-func (v ALR) Failed(c uint32) dia.Answer {
+func (v ALR) Failed(r dia.Result) dia.Answer {
 	return ALA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r,
 		OriginHost:  dia.Host,
 		OriginRealm: dia.Realm}
 }
@@ -158,7 +171,7 @@ func (v ALR) Failed(c uint32) dia.Answer {
 ALA is AlertServiceCentreAnswer message.
  <ALA> ::= < Diameter Header: 8388648, PXY, 16777312 >
 		   < Session-Id >
-		   [ DRMP ] // not supported
+		   [ DRMP ]
 		   [ Vendor-Specific-Application-Id ]
 		   [ Result-Code ]
 		   [ Experimental-Result ]
@@ -172,7 +185,9 @@ ALA is AlertServiceCentreAnswer message.
 		 * [ Route-Record ]
 */
 type ALA struct {
-	ResultCode  uint32
+	DRMP dia.DRMP
+
+	ResultCode  dia.Result
 	OriginHost  dia.Identity
 	OriginRealm dia.Identity
 
@@ -182,11 +197,7 @@ type ALA struct {
 func (v ALA) String() string {
 	w := new(bytes.Buffer)
 
-	if v.ResultCode > 10000 {
-		fmt.Fprintf(w, "%sExp-Result-Code   =%d:%d\n", dia.Indent, v.ResultCode/10000, v.ResultCode%10000)
-	} else {
-		fmt.Fprintf(w, "%sResult-Code       =%d\n", dia.Indent, v.ResultCode)
-	}
+	fmt.Fprintf(w, "%sResult            =%s\n", dia.Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host       =%s\n", dia.Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm      =%s\n", dia.Indent, v.OriginRealm)
 
@@ -201,14 +212,17 @@ func (v ALA) ToRaw(s string) dia.RawMsg {
 		Code: 8388648, AppID: 16777312,
 		AVP: make([]dia.RawAVP, 0, 10)}
 
-	m.AVP = append(m.AVP, dia.SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, dia.SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 	m.AVP = append(m.AVP, dia.SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, dia.SetOriginRealm(v.OriginRealm))
 
-	if v.ResultCode != dia.DiameterSuccess && len(v.FailedAVP) != 0 {
+	if v.ResultCode != dia.ResultCode(dia.DiameterSuccess) && len(v.FailedAVP) != 0 {
 		m.AVP = append(m.AVP, dia.SetFailedAVP(v.FailedAVP))
 	}
 	return m
@@ -227,8 +241,10 @@ func (ALA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = dia.GetResultCode(a)
+			v.ResultCode, e = dia.GetResult(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -239,14 +255,14 @@ func (ALA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		}
 	}
 
-	if v.ResultCode == 0 ||
+	if v.ResultCode.Code == 0 ||
 		len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 	}
 	return v, s, e
 }
 
 // Result returns result-code
-func (v ALA) Result() uint32 {
+func (v ALA) Result() dia.Result {
 	return v.ResultCode
 }
@@ -13,7 +13,7 @@ import (
 RDR is Report-SM-Delivery-Status-Request message.
  <RDR> ::= < Diameter Header: 8388649, REQ, PXY, 16777312 >
 		   < Session-Id >
-		   [ DRMP ] // not supported
+		   [ DRMP ]
 		   [ Vendor-Specific-Application-Id ]
 		   { Auth-Session-State }
 		   { Origin-Host }
@@ -31,6 +31,8 @@ RDR is Report-SM-Delivery-Status-Request message.
 		 * [ Route-Record ]
 */
 type RDR struct {
+	DRMP dia.DRMP
+
 	OriginHost       dia.Identity
 	OriginRealm      dia.Identity
 	DestinationHost  dia.Identity
@@ -58,7 +60,6 @@ type RDR struct {
 		SingleAttempt bool
 	}
 
-	// DRMP
 	// SMSMICorrelationID
 	// []SupportedFeatures
 	// []ProxyInfo
@@ -121,6 +122,9 @@ func (v RDR) ToRaw(s string) dia.RawMsg {
 		AVP: make([]dia.RawAVP, 0, 15)}
 
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 
@@ -163,6 +167,8 @@ func (RDR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -193,23 +199,29 @@ func (RDR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 ||
-		len(v.DestinationRealm) == 0 || v.SCAddress.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = dia.MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = dia.MissingAVP(0, 296)
+	} else if len(v.DestinationRealm) == 0 {
+		e = dia.MissingAVP(0, 283)
+	} else if v.SCAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 3300)
 	} else if v.MSISDN.Length() == 0 && v.IMSI.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.MissingAVP(10415, 3102)
 	} else if v.DeliveryOutcome.MME.SMDeliveryCause == NoOutcome &&
 		v.DeliveryOutcome.MSC.SMDeliveryCause == NoOutcome &&
 		v.DeliveryOutcome.SGSN.SMDeliveryCause == NoOutcome {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.MissingAVP(10415, 3316)
 	}
 	return v, s, e
 }
 
 // Failed make error message for timeout
-func (v RDR) Failed(c uint32) dia.Answer {
+func (v RDR) Failed(r dia.Result) dia.Answer {
 	return SRA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r,
 		OriginHost:  dia.Host,
 		OriginRealm: dia.Realm}
 }
@@ -218,7 +230,7 @@ func (v RDR) Failed(c uint32) dia.Answer {
 RDA is ReportSMDeliveryStatusAnswer message.
  <RDA> ::= < Diameter Header: 8388649, PXY, 16777312 >
 		   < Session-Id >
-		   [ DRMP ] // not supported
+		   [ DRMP ]
 		   [ Vendor-Specific-Application-Id ]
 		   [ Result-Code ]
 		   [ Experimental-Result ]
@@ -233,8 +245,9 @@ RDA is ReportSMDeliveryStatusAnswer message.
 		 * [ Route-Record ]
 */
 type RDA struct {
-	// DRMP
-	ResultCode  uint32
+	DRMP dia.DRMP
+
+	ResultCode  dia.Result
 	OriginHost  dia.Identity
 	OriginRealm dia.Identity
 
@@ -246,11 +259,7 @@ type RDA struct {
 func (v RDA) String() string {
 	w := new(bytes.Buffer)
 
-	if v.ResultCode > 10000 {
-		fmt.Fprintf(w, "%sExp-Result-Code   =%d:%d\n", dia.Indent, v.ResultCode/10000, v.ResultCode%10000)
-	} else {
-		fmt.Fprintf(w, "%sResult-Code       =%d\n", dia.Indent, v.ResultCode)
-	}
+	fmt.Fprintf(w, "%sResult            =%s\n", dia.Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host       =%s\n", dia.Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm      =%s\n", dia.Indent, v.OriginRealm)
 
@@ -269,14 +278,17 @@ func (v RDA) ToRaw(s string) dia.RawMsg {
 		Code: 8388649, AppID: 16777312,
 		AVP: make([]dia.RawAVP, 0, 20)}
 
-	m.AVP = append(m.AVP, dia.SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, dia.SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 	m.AVP = append(m.AVP, dia.SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, dia.SetOriginRealm(v.OriginRealm))
 
-	if v.ResultCode != dia.DiameterSuccess {
+	if v.ResultCode != dia.ResultCode(dia.DiameterSuccess) {
 		if len(v.FailedAVP) != 0 {
 			m.AVP = append(m.AVP, dia.SetFailedAVP(v.FailedAVP))
 		}
@@ -301,8 +313,10 @@ func (RDA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = dia.GetResultCode(a)
+			v.ResultCode, e = dia.GetResult(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -316,14 +330,14 @@ func (RDA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		}
 	}
 
-	if v.ResultCode == 0 ||
+	if v.ResultCode.Code == 0 ||
 		len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 	}
 	return v, s, e
 }
 
 // Result returns result-code
-func (v RDA) Result() uint32 {
+func (v RDA) Result() dia.Result {
 	return v.ResultCode
 }
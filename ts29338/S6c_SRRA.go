@@ -13,7 +13,7 @@ import (
 SRR is Send-Routing-Info-For-SM-Request message.
  <SRR> ::= < Diameter Header: 8388647, REQ, PXY, 16777312 >
            < Session-Id >
-		   [ DRMP ]  // not supported
+		   [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            { Auth-Session-State }
            { Origin-Host }
@@ -35,7 +35,8 @@ SRR is Send-Routing-Info-For-SM-Request message.
 IP-SM-GW and MSISDN-less SMS are not supported.
 */
 type SRR struct {
-	// DRMP
+	DRMP dia.DRMP
+
 	OriginHost       dia.Identity
 	OriginRealm      dia.Identity
 	DestinationHost  dia.Identity
@@ -111,6 +112,9 @@ func (v SRR) ToRaw(s string) dia.RawMsg {
 		AVP: make([]dia.RawAVP, 0, 15)}
 
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 
@@ -161,6 +165,8 @@ func (SRR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -191,19 +197,25 @@ func (SRR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 ||
-		len(v.DestinationRealm) == 0 || v.SCAddress.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = dia.MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = dia.MissingAVP(0, 296)
+	} else if len(v.DestinationRealm) == 0 {
+		e = dia.MissingAVP(0, 283)
+	} else if v.SCAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 3300)
 	} else if v.MSISDN.Length() == 0 && v.IMSI.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.MissingAVP(0, 1)
 	}
 	return v, s, e
 }
 
 // Failed make error message for timeout
-func (v SRR) Failed(c uint32) dia.Answer {
+func (v SRR) Failed(r dia.Result) dia.Answer {
 	return SRA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r,
 		OriginHost:  dia.Host,
 		OriginRealm: dia.Realm}
 }
@@ -212,7 +224,7 @@ func (v SRR) Failed(c uint32) dia.Answer {
 SRA is SendRoutingInfoForSMAnswer message.
  <SRA> ::= < Diameter Header: 8388647, PXY, 16777312 >
            < Session-Id >
-		   [ DRMP ] // not supported
+		   [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            [ Result-Code ]
            [ Experimental-Result ]
@@ -236,8 +248,9 @@ SRA is SendRoutingInfoForSMAnswer message.
 IP-SM-GW and MSISDN-less SMS are not supported.
 */
 type SRA struct {
-	// DRMP
-	ResultCode  uint32
+	DRMP dia.DRMP
+
+	ResultCode  dia.Result
 	OriginHost  dia.Identity
 	OriginRealm dia.Identity
 
@@ -272,11 +285,7 @@ type SRA struct {
 func (v SRA) String() string {
 	w := new(bytes.Buffer)
 
-	if v.ResultCode > 10000 {
-		fmt.Fprintf(w, "%sExp-Result-Code   =%d:%d\n", dia.Indent, v.ResultCode/10000, v.ResultCode%10000)
-	} else {
-		fmt.Fprintf(w, "%sResult-Code       =%d\n", dia.Indent, v.ResultCode)
-	}
+	fmt.Fprintf(w, "%sResult            =%s\n", dia.Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host       =%s\n", dia.Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm      =%s\n", dia.Indent, v.OriginRealm)
 
@@ -325,14 +334,17 @@ func (v SRA) ToRaw(s string) dia.RawMsg {
 		Code: 8388647, AppID: 16777312,
 		AVP: make([]dia.RawAVP, 0, 20)}
 
-	m.AVP = append(m.AVP, dia.SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, dia.SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 	m.AVP = append(m.AVP, dia.SetOriginHost(v.OriginHost))
 	m.AVP = append(m.AVP, dia.SetOriginRealm(v.OriginRealm))
 
-	if v.ResultCode != dia.DiameterSuccess {
+	if v.ResultCode != dia.ResultCode(dia.DiameterSuccess) {
 		if len(v.FailedAVP) != 0 {
 			m.AVP = append(m.AVP, dia.SetFailedAVP(v.FailedAVP))
 		}
@@ -396,8 +408,10 @@ func (SRA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = dia.GetResultCode(a)
+			v.ResultCode, e = dia.GetResult(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -439,24 +453,24 @@ func (SRA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		}
 	}
 
-	if v.ResultCode == 0 ||
+	if v.ResultCode.Code == 0 ||
 		len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 	}
-	if v.ResultCode == dia.DiameterSuccess {
+	if v.ResultCode == dia.ResultCode(dia.DiameterSuccess) {
 		if v.IMSI.Length() == 0 {
-			e = dia.InvalidAVP(dia.DiameterMissingAvp)
+			e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 		} else if v.ServingNode[0].Address.Length() == 0 {
-			e = dia.InvalidAVP(dia.DiameterMissingAvp)
+			e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 		} else if v.ServingNode[1].Address.Length() != 0 &&
 			v.ServingNode[0].NodeType == v.ServingNode[1].NodeType {
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue}
 		}
 	}
 	return v, s, e
 }
 
 // Result returns result-code
-func (v SRA) Result() uint32 {
+func (v SRA) Result() dia.Result {
 	return v.ResultCode
 }
@@ -19,7 +19,7 @@ func setUserName(v teldata.IMSI) (a dia.RawAVP) {
 func getUserName(a dia.RawAVP) (v teldata.IMSI, e error) {
 	s := new(string)
 	if a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.ParseIMSI(*s)
 	}
@@ -35,7 +35,7 @@ func setMSISDN(v teldata.E164) (a dia.RawAVP) {
 func getMSISDN(a dia.RawAVP) (v teldata.E164, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.B2E164(*s)
 	}
@@ -69,14 +69,14 @@ func setSMRPMTI(v MTType) (a dia.RawAVP) {
 func getSMRPMTI(a dia.RawAVP) (v MTType, e error) {
 	s := new(dia.Enumerated)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e != nil {
 	} else if *s == 0 {
 		v = DeliverMT
 	} else if *s == 1 {
 		v = StatusReportMT
 	} else {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 	}
 	return
 }
@@ -95,7 +95,7 @@ func setSMRPSMEA(v sms.Address) (a dia.RawAVP) {
 func getSMRPSMEA(a dia.RawAVP) (v sms.Address, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v.Decode(byte(len(*s)-3)*2, *s)
 	}
@@ -129,7 +129,7 @@ func setSRRFlags(g, p, s bool) (a dia.RawAVP) {
 func getSRRFlags(a dia.RawAVP) (g, p, s bool, e error) {
 	v := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(v); e == nil {
 		g = (*v)&0x00000001 == 0x00000001
 		p = (*v)&0x00000002 == 0x00000002
@@ -166,14 +166,14 @@ func setSMDeliveryNotIntended(v RequiredInfo) (a dia.RawAVP) {
 func getSMDeliveryNotIntended(a dia.RawAVP) (v RequiredInfo, e error) {
 	s := new(dia.Enumerated)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e != nil {
 	} else if *s == 0 {
 		v = OnlyImsiRequested
 	} else if *s == 1 {
 		v = OnlyMccMncRequested
 	} else {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 	}
 	return
 }
@@ -264,7 +264,7 @@ func getAdditionalServingNode(a dia.RawAVP) (t NodeType, d teldata.E164, n, r di
 func getSN(a dia.RawAVP) (t NodeType, d teldata.E164, n, r dia.Identity, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&o)
 	}
@@ -273,21 +273,21 @@ func getSN(a dia.RawAVP) (t NodeType, d teldata.E164, n, r dia.Identity, e error
 		switch a.Code {
 		case 1489:
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(b); e == nil {
 				d, e = teldata.B2E164(*b)
 				t = NodeSGSN
 			}
 		case 1645:
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(b); e == nil {
 				d, e = teldata.B2E164(*b)
 				t = NodeMME
 			}
 		case 2403:
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(b); e == nil {
 				d, e = teldata.B2E164(*b)
 				t = NodeMSC
@@ -300,13 +300,13 @@ func getSN(a dia.RawAVP) (t NodeType, d teldata.E164, n, r dia.Identity, e error
 			switch a.Code {
 			case 2409:
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else {
 					e = a.Decode(&n)
 				}
 			case 2410:
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else {
 					e = a.Decode(&r)
 				}
@@ -317,13 +317,13 @@ func getSN(a dia.RawAVP) (t NodeType, d teldata.E164, n, r dia.Identity, e error
 			switch a.Code {
 			case 2402:
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else {
 					e = a.Decode(&n)
 				}
 			case 2408:
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else {
 					e = a.Decode(&r)
 				}
@@ -344,7 +344,7 @@ func setLMSI(v uint32) dia.RawAVP {
 func getLMSI(a dia.RawAVP) (v uint32, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil && len(*s) == 4 {
 		binary.Read(bytes.NewBuffer(*s), binary.BigEndian, &v)
 	}
@@ -368,7 +368,7 @@ func setUserIdentifier(i teldata.IMSI, m teldata.E164) dia.RawAVP {
 func getUserIdentifier(a dia.RawAVP) (i teldata.IMSI, m teldata.E164, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(&o); e == nil {
 		for _, a := range o {
 			switch a.Code {
@@ -413,7 +413,7 @@ func setMWDStatus(sca, mnrf, mcef, mnrg bool) (a dia.RawAVP) {
 func getMWDStatus(a dia.RawAVP) (sca, mnrf, mcef, mnrg bool, e error) {
 	s := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		sca = (*s)&0x00000001 == 0x00000001
 		mnrf = (*s)&0x00000002 == 0x00000002
@@ -449,7 +449,7 @@ func setMMEAbsentUserDiagnosticSM(v sms.AbsentDiag) (a dia.RawAVP) {
 func getMMEAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 	s := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		switch *s {
 		case 0:
@@ -467,7 +467,7 @@ func getMMEAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 		case 13:
 			v = sms.TempUnavailable
 		default:
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -499,7 +499,7 @@ func setMSCAbsentUserDiagnosticSM(v sms.AbsentDiag) (a dia.RawAVP) {
 func getMSCAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 	s := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		switch *s {
 		case 0:
@@ -517,7 +517,7 @@ func getMSCAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 		case 13:
 			v = sms.TempUnavailable
 		default:
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -551,7 +551,7 @@ func setSGSNAbsentUserDiagnosticSM(v sms.AbsentDiag) (a dia.RawAVP) {
 func getSGSNAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 	s := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		switch *s {
 		case 1:
@@ -571,7 +571,7 @@ func getSGSNAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 		case 13:
 			v = sms.TempUnavailable
 		default:
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -616,7 +616,7 @@ func setAbsentUserDiagnosticSM(v sms.AbsentDiag) (a dia.RawAVP) {
 func getAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 	s := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		switch *s {
 		case 0:
@@ -648,7 +648,7 @@ func getAbsentUserDiagnosticSM(a dia.RawAVP) (v sms.AbsentDiag, e error) {
 		case 13:
 			v = sms.TempUnavailable
 		default:
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -707,7 +707,7 @@ func getSMDeliveryOutcome(a dia.RawAVP) (
 	ec, cc, nc SMDeliveryCause, ed, cd, nd sms.AbsentDiag, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(&o); e == nil {
 		for _, a := range o {
 			switch a.Code {
@@ -723,7 +723,7 @@ func getSMDeliveryOutcome(a dia.RawAVP) (
 			}
 		}
 		if ec != NoOutcome && cc != NoOutcome {
-			e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+			e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 		}
 	}
 	return
@@ -732,14 +732,14 @@ func getSMDeliveryOutcome(a dia.RawAVP) (
 func getNodeOutcome(a dia.RawAVP) (c SMDeliveryCause, d sms.AbsentDiag, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(&o); e == nil {
 		for _, a := range o {
 			switch a.Code {
 			case 3321:
 				var n dia.Enumerated
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else if e = a.Decode(&n); e != nil {
 				} else if n == 0 {
 					c = UeMemoryCapacityExceeded
@@ -751,7 +751,7 @@ func getNodeOutcome(a dia.RawAVP) (c SMDeliveryCause, d sms.AbsentDiag, e error)
 			case 3322:
 				var n uint32
 				if !a.FlgV || !a.FlgM || a.FlgP {
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 				} else if e = a.Decode(&n); e == nil {
 					d = sms.AbsentDiag(n)
 				}
@@ -780,7 +780,7 @@ func setRDRFlags(s bool) (a dia.RawAVP) {
 func getRDRFlags(a dia.RawAVP) (s bool, e error) {
 	v := new(uint32)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(v); e == nil {
 		s = (*v)&0x00000001 == 0x00000001
 	}
@@ -798,7 +798,7 @@ func setMaximumUEAvailabilityTime(v time.Time) (a dia.RawAVP) {
 
 func getMaximumUEAvailabilityTime(a dia.RawAVP) (v time.Time, e error) {
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	}
 	e = a.Decode(&v)
 	return
@@ -824,7 +824,7 @@ func setSMSGMSCAlertEvent(av, nn bool) (a dia.RawAVP) {
 func getSMSGMSCAlertEvent(a dia.RawAVP) (av, nn bool, e error) {
 	s := new(uint32)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		av = (*s)&0x00000001 == 0x00000001
 		nn = (*s)&0x00000002 == 0x00000002
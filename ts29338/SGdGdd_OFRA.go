@@ -13,7 +13,7 @@ import (
 OFR is MO-Forward-ShortMessage-Request message.
  <OFR> ::= < Diameter Header: 8388645, REQ, PXY, 16777313 >
            < Session-Id >
-           [ DRMP ]  // not supported
+           [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            { Auth-Session-State }
            { Origin-Host }
@@ -32,6 +32,8 @@ OFR is MO-Forward-ShortMessage-Request message.
          * [ Route-Record ]
 */
 type OFR struct {
+	DRMP dia.DRMP
+
 	OriginHost       dia.Identity
 	OriginRealm      dia.Identity
 	DestinationHost  dia.Identity
@@ -83,6 +85,9 @@ func (v OFR) ToRaw(s string) dia.RawMsg {
 		AVP: make([]dia.RawAVP, 0, 12)}
 
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 
@@ -118,6 +123,8 @@ func (OFR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -142,22 +149,27 @@ func (OFR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 ||
-		len(v.DestinationRealm) == 0 || v.SCAddress.Length() == 0 ||
-		v.SMSPDU.DA.Addr == nil {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
-	}
-
-	if v.IMSI.Length() == 0 && v.MSISDN.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = dia.MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = dia.MissingAVP(0, 296)
+	} else if len(v.DestinationRealm) == 0 {
+		e = dia.MissingAVP(0, 283)
+	} else if v.SCAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 3300)
+	} else if v.SMSPDU.DA.Addr == nil {
+		e = dia.MissingAVP(10415, 3301)
+	} else if v.IMSI.Length() == 0 && v.MSISDN.Length() == 0 {
+		e = dia.MissingAVP(10415, 3102)
 	}
 	return v, s, e
 }
 
 // Failed make error message for timeout
-func (v OFR) Failed(c uint32) dia.Answer {
+func (v OFR) Failed(r dia.Result) dia.Answer {
 	return OFA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r,
 		OriginHost:  dia.Host,
 		OriginRealm: dia.Realm}
 }
@@ -166,7 +178,7 @@ func (v OFR) Failed(c uint32) dia.Answer {
 OFA is MO-Forward-Short-Message-Answer message.
  <OFA> ::= < Diameter Header: 8388645, PXY, 16777313 >
            < Session-Id >
-           [ DRMP ] // not supported
+           [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            [ Result-Code ]
            [ Experimental-Result ]
@@ -183,7 +195,9 @@ OFA is MO-Forward-Short-Message-Answer message.
          * [ Route-Record ]
 */
 type OFA struct {
-	ResultCode  uint32
+	DRMP dia.DRMP
+
+	ResultCode  dia.Result
 	OriginHost  dia.Identity
 	OriginRealm dia.Identity
 
@@ -200,15 +214,11 @@ type OFA struct {
 func (v OFA) String() string {
 	w := new(bytes.Buffer)
 
-	if v.ResultCode > 10000 {
-		fmt.Fprintf(w, "%sExp-Result-Code   =%d:%d\n", dia.Indent, v.ResultCode/10000, v.ResultCode%10000)
-	} else {
-		fmt.Fprintf(w, "%sResult-Code       =%d\n", dia.Indent, v.ResultCode)
-	}
+	fmt.Fprintf(w, "%sResult            =%s\n", dia.Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host       =%s\n", dia.Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm      =%s\n", dia.Indent, v.OriginRealm)
 
-	if v.ResultCode == dia.DiameterSuccess {
+	if v.ResultCode == dia.ResultCode(dia.DiameterSuccess) {
 		fmt.Fprintf(w, "%sSMS Data Unit     =%s\n", dia.Indent, v.SMSPDU.String())
 	}
 
@@ -242,8 +252,11 @@ func (v OFA) ToRaw(s string) dia.RawMsg {
 		Code: 8388645, AppID: 16777313,
 		AVP: make([]dia.RawAVP, 0, 20)}
 
-	m.AVP = append(m.AVP, dia.SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, dia.SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
@@ -251,7 +264,7 @@ func (v OFA) ToRaw(s string) dia.RawMsg {
 	m.AVP = append(m.AVP, dia.SetOriginRealm(v.OriginRealm))
 
 	switch v.ResultCode {
-	case dia.DiameterSuccess:
+	case dia.ResultCode(dia.DiameterSuccess):
 		m.AVP = append(m.AVP, setSMRPUI(&v.SMSPDU))
 	case DiameterErrorSmDeliveryFailure:
 		m.AVP = append(m.AVP, setSMSubmissionFailureCause(
@@ -273,8 +286,10 @@ func (OFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = dia.GetResultCode(a)
+			v.ResultCode, e = dia.GetResult(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -285,7 +300,7 @@ func (OFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		}
 	}
 	switch v.ResultCode {
-	case dia.DiameterSuccess:
+	case dia.ResultCode(dia.DiameterSuccess):
 		for _, a := range m.AVP {
 			switch a.Code {
 			case 3301:
@@ -306,14 +321,14 @@ func (OFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 			}
 		}
 	}
-	if v.ResultCode == 0 ||
+	if v.ResultCode.Code == 0 ||
 		len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 	}
 	return v, s, e
 }
 
 // Result returns result-code
-func (v OFA) Result() uint32 {
+func (v OFA) Result() dia.Result {
 	return v.ResultCode
 }
@@ -14,7 +14,7 @@ import (
 TFR is MT-Forward-Short-Message-Request message.
  <TFR> ::= < Diameter Header: 8388646, REQ, PXY, 16777313 >
            < Session-Id >
-           [ DRMP ]  // not supported
+           [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            { Auth-Session-State }
            { Origin-Host }
@@ -38,6 +38,8 @@ TFR is MT-Forward-Short-Message-Request message.
          * [ Route-Record ]
 */
 type TFR struct {
+	DRMP dia.DRMP
+
 	OriginHost       dia.Identity
 	OriginRealm      dia.Identity
 	DestinationHost  dia.Identity
@@ -100,6 +102,9 @@ func (v TFR) ToRaw(s string) dia.RawMsg {
 		AVP: make([]dia.RawAVP, 0, 15)}
 
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
 
@@ -153,6 +158,8 @@ func (TFR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -189,23 +196,31 @@ func (TFR) FromRaw(m dia.RawMsg) (dia.Request, string, error) {
 		}
 	}
 
-	if len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 ||
-		len(v.DestinationHost) == 0 || len(v.DestinationRealm) == 0 ||
-		v.IMSI.Length() == 0 || v.SCAddress.Length() == 0 ||
-		v.SMSPDU.OA.Addr == nil {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
-	}
-
-	if v.MMEAddress.Length() == 0 && v.SGSNAddress.Length() == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+	if len(v.OriginHost) == 0 {
+		e = dia.MissingAVP(0, 264)
+	} else if len(v.OriginRealm) == 0 {
+		e = dia.MissingAVP(0, 296)
+	} else if len(v.DestinationHost) == 0 {
+		e = dia.MissingAVP(0, 293)
+	} else if len(v.DestinationRealm) == 0 {
+		e = dia.MissingAVP(0, 283)
+	} else if v.IMSI.Length() == 0 {
+		e = dia.MissingAVP(0, 1)
+	} else if v.SCAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 3300)
+	} else if v.SMSPDU.OA.Addr == nil {
+		e = dia.MissingAVP(10415, 3301)
+	} else if v.MMEAddress.Length() == 0 && v.SGSNAddress.Length() == 0 {
+		e = dia.MissingAVP(10415, 1645)
 	}
 	return v, s, e
 }
 
 // Failed make error message for timeout
-func (v TFR) Failed(c uint32) dia.Answer {
+func (v TFR) Failed(r dia.Result) dia.Answer {
 	return TFA{
-		ResultCode:  c,
+		DRMP:        v.DRMP,
+		ResultCode:  r,
 		OriginHost:  dia.Host,
 		OriginRealm: dia.Realm}
 }
@@ -214,7 +229,7 @@ func (v TFR) Failed(c uint32) dia.Answer {
 TFA is MT-Forward-Short-Message-Answer message.
  <TFA> ::= < Diameter Header: 8388646, PXY, 16777313 >
            < Session-Id >
-           [ DRMP ] // not supported
+           [ DRMP ]
            [ Vendor-Specific-Application-Id ]
            [ Result-Code ]
            [ Experimental-Result ]
@@ -233,7 +248,9 @@ TFA is MT-Forward-Short-Message-Answer message.
          * [ Route-Record ]
 */
 type TFA struct {
-	ResultCode  uint32
+	DRMP dia.DRMP
+
+	ResultCode  dia.Result
 	OriginHost  dia.Identity
 	OriginRealm dia.Identity
 
@@ -249,15 +266,11 @@ type TFA struct {
 func (v TFA) String() string {
 	w := new(bytes.Buffer)
 
-	if v.ResultCode > 10000 {
-		fmt.Fprintf(w, "%sExp-Result-Code   =%d:%d\n", dia.Indent, v.ResultCode/10000, v.ResultCode%10000)
-	} else {
-		fmt.Fprintf(w, "%sResult-Code       =%d\n", dia.Indent, v.ResultCode)
-	}
+	fmt.Fprintf(w, "%sResult            =%s\n", dia.Indent, v.ResultCode)
 	fmt.Fprintf(w, "%sOrigin-Host       =%s\n", dia.Indent, v.OriginHost)
 	fmt.Fprintf(w, "%sOrigin-Realm      =%s\n", dia.Indent, v.OriginRealm)
 
-	if v.ResultCode == dia.DiameterSuccess {
+	if v.ResultCode == dia.ResultCode(dia.DiameterSuccess) {
 		fmt.Fprintf(w, "%sSMS Data Unit     =%s\n", dia.Indent, v.SMSPDU.String())
 	}
 
@@ -297,8 +310,11 @@ func (v TFA) ToRaw(s string) dia.RawMsg {
 		Code: 8388646, AppID: 16777313,
 		AVP: make([]dia.RawAVP, 0, 20)}
 
-	m.AVP = append(m.AVP, dia.SetResultCode(v.ResultCode))
+	m.AVP = append(m.AVP, dia.SetResult(v.ResultCode))
 	m.AVP = append(m.AVP, dia.SetSessionID(s))
+	if v.DRMP != dia.NoDRMP {
+		m.AVP = append(m.AVP, dia.SetDRMP(v.DRMP))
+	}
 	m.AVP = append(m.AVP, dia.SetVendorSpecAppID(10415, m.AppID))
 
 	m.AVP = append(m.AVP, dia.SetAuthSessionState(false))
@@ -306,7 +322,7 @@ func (v TFA) ToRaw(s string) dia.RawMsg {
 	m.AVP = append(m.AVP, dia.SetOriginRealm(v.OriginRealm))
 
 	switch v.ResultCode {
-	case dia.DiameterSuccess:
+	case dia.ResultCode(dia.DiameterSuccess):
 		m.AVP = append(m.AVP, setSMRPUI(&v.SMSPDU))
 	case DiameterErrorAbsentUser:
 		m.AVP = append(m.AVP, setAbsentUserDiagnosticSM(v.AbsentDiag))
@@ -333,8 +349,10 @@ func (TFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		switch a.Code {
 		case 263:
 			s, e = dia.GetSessionID(a)
+		case 301:
+			v.DRMP, e = dia.GetDRMP(a)
 		case 268, 297:
-			v.ResultCode, e = dia.GetResultCode(a)
+			v.ResultCode, e = dia.GetResult(a)
 		case 264:
 			v.OriginHost, e = dia.GetOriginHost(a)
 		case 296:
@@ -345,7 +363,7 @@ func (TFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 		}
 	}
 	switch v.ResultCode {
-	case dia.DiameterSuccess:
+	case dia.ResultCode(dia.DiameterSuccess):
 		for _, a := range m.AVP {
 			switch a.Code {
 			case 3301:
@@ -378,14 +396,14 @@ func (TFA) FromRaw(m dia.RawMsg) (dia.Answer, string, error) {
 			}
 		}
 	}
-	if v.ResultCode == 0 ||
+	if v.ResultCode.Code == 0 ||
 		len(v.OriginHost) == 0 || len(v.OriginRealm) == 0 {
-		e = dia.InvalidAVP(dia.DiameterMissingAvp)
+		e = dia.InvalidAVP{Code: dia.DiameterMissingAvp}
 	}
 	return v, s, e
 }
 
 // Result returns result-code
-func (v TFA) Result() uint32 {
+func (v TFA) Result() dia.Result {
 	return v.ResultCode
 }
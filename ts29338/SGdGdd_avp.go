@@ -17,7 +17,7 @@ func setSCAddress(v teldata.E164) (a dia.RawAVP) {
 func getSCAddress(a dia.RawAVP) (v teldata.E164, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.B2E164(*s)
 	}
@@ -34,7 +34,7 @@ func setSMRPUI(v sms.TPDU) (a dia.RawAVP) {
 func getSMRPUIasDeliver(a dia.RawAVP) (v sms.Deliver, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		e = v.Decode(*s)
 	}
@@ -44,7 +44,7 @@ func getSMRPUIasDeliver(a dia.RawAVP) (v sms.Deliver, e error) {
 func getSMRPUIasDeliverReport(a dia.RawAVP) (v sms.DeliverReport, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		e = v.Decode(*s)
 	}
@@ -54,7 +54,7 @@ func getSMRPUIasDeliverReport(a dia.RawAVP) (v sms.DeliverReport, e error) {
 func getSMRPUIasSubmit(a dia.RawAVP) (v sms.Submit, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		e = v.Decode(*s)
 	}
@@ -64,7 +64,7 @@ func getSMRPUIasSubmit(a dia.RawAVP) (v sms.Submit, e error) {
 func getSMRPUIasSubmitReport(a dia.RawAVP) (v sms.SubmitReport, e error) {
 	s := new([]byte)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		e = v.Decode(*s)
 	}
@@ -81,7 +81,7 @@ func setMMENumberForMTSMS(v teldata.E164) (a dia.RawAVP) {
 func getMMENumberForMTSMS(a dia.RawAVP) (v teldata.E164, e error) {
 	s := new([]byte)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.B2E164(*s)
 	}
@@ -98,7 +98,7 @@ func setSGSNNumber(v teldata.E164) (a dia.RawAVP) {
 func getSGSNNumber(a dia.RawAVP) (v teldata.E164, e error) {
 	s := new(string)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.ParseE164(*s)
 	}
@@ -120,7 +120,7 @@ func setTFRFlags(m bool) (a dia.RawAVP) {
 func getTFRFlags(a dia.RawAVP) (m bool, e error) {
 	v := new(uint32)
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(v); e == nil {
 		m = (*v)&0x00000001 == 0x00000001
 	}
@@ -184,7 +184,7 @@ func setSMDeliveryFailureCause(c DeliveryFailureCause, d sms.DeliverReport) (a d
 func getSMDeliveryFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.DeliverReport, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&o)
 	}
@@ -193,7 +193,7 @@ func getSMDeliveryFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.Deli
 		case 3304:
 			s := new(dia.Enumerated)
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(s); e == nil {
 				switch *s {
 				case 0:
@@ -211,13 +211,13 @@ func getSMDeliveryFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.Deli
 				case 6:
 					c = CauseUserNotSCUser
 				default:
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 				}
 			}
 		case 3305:
 			s := new([]byte)
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(s); e == nil {
 				e = d.Decode(*s)
 			}
@@ -263,7 +263,7 @@ func setSMSubmissionFailureCause(c DeliveryFailureCause, d sms.SubmitReport) (a
 func getSMSubmissionFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.SubmitReport, e error) {
 	o := []dia.RawAVP{}
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&o)
 	}
@@ -272,7 +272,7 @@ func getSMSubmissionFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.Su
 		case 3304:
 			s := new(dia.Enumerated)
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(s); e == nil {
 				switch *s {
 				case 0:
@@ -290,13 +290,13 @@ func getSMSubmissionFailureCause(a dia.RawAVP) (c DeliveryFailureCause, d sms.Su
 				case 6:
 					c = CauseUserNotSCUser
 				default:
-					e = dia.InvalidAVP(dia.DiameterInvalidAvpValue)
+					e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpValue, AVP: a}
 				}
 			}
 		case 3305:
 			s := new([]byte)
 			if !a.FlgV || !a.FlgM || a.FlgP {
-				e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+				e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 			} else if e = a.Decode(s); e == nil {
 				e = d.Decode(*s)
 			}
@@ -314,7 +314,7 @@ func setSMDeliveryTimer(v uint32) (a dia.RawAVP) {
 
 func getSMDeliveryTimer(a dia.RawAVP) (v uint32, e error) {
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -331,7 +331,7 @@ func setSMDeliveryStartTime(v time.Time) (a dia.RawAVP) {
 
 func getSMDeliveryStartTime(a dia.RawAVP) (v time.Time, e error) {
 	if !a.FlgV || !a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -354,7 +354,7 @@ func setOFRFlags(s bool) (a dia.RawAVP) {
 func getOFRFlags(a dia.RawAVP) (s bool, e error) {
 	v := new(uint32)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(v); e == nil {
 		s = (*v)&0x00000001 == 0x00000001
 	}
@@ -371,7 +371,7 @@ func setMaximumRetransmissionTime(v time.Time) (a dia.RawAVP) {
 
 func getMaximumRetransmissionTime(a dia.RawAVP) (v time.Time, e error) {
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -388,7 +388,7 @@ func setSMSGMSCAddress(v teldata.E164) (a dia.RawAVP) {
 func getSMSGMSCAddress(a dia.RawAVP) (v teldata.E164, e error) {
 	s := new(string)
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else if e = a.Decode(s); e == nil {
 		v, e = teldata.ParseE164(*s)
 	}
@@ -405,7 +405,7 @@ func setRequestedRetransmissionTime(v time.Time) (a dia.RawAVP) {
 
 func getRequestedRetransmissionTime(a dia.RawAVP) (v time.Time, e error) {
 	if !a.FlgV || a.FlgM || a.FlgP {
-		e = dia.InvalidAVP(dia.DiameterInvalidAvpBits)
+		e = dia.InvalidAVP{Code: dia.DiameterInvalidAvpBits, AVP: a}
 	} else {
 		e = a.Decode(&v)
 	}
@@ -0,0 +1,56 @@
+package ts29338
+
+import dia "github.com/fkgi/diameter"
+
+func init() {
+	dia.RegisterAVP(vid3gpp, 701, "MSISDN", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 628, "Supported-Features", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 629, "Feature-List-ID", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 630, "Feature-List", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 1489, "SGSN-Number", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 1645, "MME-Number-for-MT-SMS", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 2400, "LMSI", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 2401, "Serving-Node", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 2402, "MME-Name", dia.DiameterIdentity)
+	dia.RegisterAVP(vid3gpp, 2403, "MSC-Number", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 2406, "Additional-Serving-Node", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 2408, "MME-Realm", dia.DiameterIdentity)
+	dia.RegisterAVP(vid3gpp, 2409, "SGSN-Name", dia.DiameterIdentity)
+	dia.RegisterAVP(vid3gpp, 2410, "SGSN-Realm", dia.DiameterIdentity)
+	dia.RegisterAVP(vid3gpp, 3102, "User-Identifier", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3111, "External-Identifier", dia.UTF8String)
+	dia.RegisterAVP(vid3gpp, 3300, "SC-Address", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 3301, "SM-RP-UI", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 3302, "TFR-Flags", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3303, "SM-Delivery-Failure-Cause", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3304, "SM-Enumerated-Delivery-Failure-Cause", dia.EnumeratedType)
+	dia.RegisterAVP(vid3gpp, 3305, "SM-Diagnostic-Info", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 3306, "SM-Delivery-Timer", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3307, "SM-Delivery-Start-Time", dia.Time)
+	dia.RegisterAVP(vid3gpp, 3308, "SM-RP-MTI", dia.EnumeratedType)
+	dia.RegisterAVP(vid3gpp, 3309, "SM-RP-SMEA", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 3310, "SRR-Flags", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3311, "SM-Delivery-Not-Intended", dia.EnumeratedType)
+	dia.RegisterAVP(vid3gpp, 3312, "MWD-Status", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3313, "MME-Absent-User-Diagnostic-SM", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3314, "MSC-Absent-User-Diagnostic-SM", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3315, "SGSN-Absent-User-Diagnostic-SM", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3316, "SM-Delivery-Outcome", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3317, "MME-SM-Delivery-Outcome", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3318, "MSC-SM-Delivery-Outcome", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3319, "SGSN-SM-Delivery-Outcome", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3320, "IP-SM-GW-SM-Delivery-Outcome", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3321, "SM-Delivery-Cause", dia.EnumeratedType)
+	dia.RegisterAVP(vid3gpp, 3322, "Absent-User-Diagnostic-SM", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3323, "RDR-Flags", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3324, "SMSMI-Correlation-ID", dia.Grouped)
+	dia.RegisterAVP(vid3gpp, 3325, "HSS-ID", dia.UTF8String)
+	dia.RegisterAVP(vid3gpp, 3326, "Originating-SIP-URI", dia.UTF8String)
+	dia.RegisterAVP(vid3gpp, 3327, "Destination-SIP-URI", dia.UTF8String)
+	dia.RegisterAVP(vid3gpp, 3328, "OFR-Flags", dia.Unsigned32)
+	dia.RegisterAVP(vid3gpp, 3329, "Maximum-UE-Availability-Time", dia.Time)
+	dia.RegisterAVP(vid3gpp, 3330, "Maximum-Retransmission-Time", dia.Time)
+	dia.RegisterAVP(vid3gpp, 3331, "Requested-Retransmission-Time", dia.Time)
+	dia.RegisterAVP(vid3gpp, 3332, "SMS-GMSC-Address", dia.OctetString)
+	dia.RegisterAVP(vid3gpp, 3333, "SMS-GMSC-Alert-Event", dia.Unsigned32)
+}
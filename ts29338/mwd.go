@@ -0,0 +1,100 @@
+package ts29338
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	dia "github.com/fkgi/diameter"
+	"github.com/fkgi/teldata"
+)
+
+// MWD is Message Waiting Data of HSS, that is list of SC-Address
+// waiting alert for each MSISDN. SC-Address is stored by RDR and
+// tied to Origin-Host of the RDR.
+// Data of the host is removed when the host restarts.
+type MWD struct {
+	mu          sync.Mutex
+	m           map[string][]mwdEntry
+	unsubscribe func()
+}
+
+type mwdEntry struct {
+	sc   teldata.E164
+	host dia.Identity
+}
+
+// NewMWD returns empty MWD that removes data of restarted host
+func NewMWD() *MWD {
+	d := &MWD{m: make(map[string][]mwdEntry)}
+	d.unsubscribe = dia.Subscribe(d.restarted,
+		dia.Filter{Types: []dia.NoticeType{dia.RestartNotice}})
+	return d
+}
+
+// Close stops removing data of restarted host
+func (d *MWD) Close() {
+	d.unsubscribe()
+}
+
+// Add stores SC-Address of RDR v to MWD of the MSISDN
+func (d *MWD) Add(v RDR) {
+	if len(v.MSISDN) == 0 || len(v.SCAddress) == 0 {
+		return
+	}
+	k := string(v.MSISDN)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, e := range d.m[k] {
+		if bytes.Equal(e.sc, v.SCAddress) {
+			d.m[k][i].host = v.OriginHost
+			return
+		}
+	}
+	d.m[k] = append(d.m[k], mwdEntry{sc: v.SCAddress, host: v.OriginHost})
+}
+
+// Take removes MWD of the MSISDN and returns ALR for each SC-Address.
+// Destination-Host of the ALR is Origin-Host of the RDR.
+func (d *MWD) Take(msisdn teldata.E164) []ALR {
+	d.mu.Lock()
+	l := d.m[string(msisdn)]
+	delete(d.m, string(msisdn))
+	d.mu.Unlock()
+
+	r := make([]ALR, 0, len(l))
+	for _, e := range l {
+		r = append(r, ALR{
+			DestinationHost: e.host,
+			MSISDN:          msisdn,
+			SCAddress:       e.sc})
+	}
+	return r
+}
+
+// Len returns number of MSISDN in MWD
+func (d *MWD) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.m)
+}
+
+// restarted removes SC-Address that is tied to the restarted host
+func (d *MWD) restarted(n dia.Notice) {
+	host := string(n.PeerHost())
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for k, l := range d.m {
+		r := l[:0]
+		for _, e := range l {
+			if !strings.EqualFold(string(e.host), host) {
+				r = append(r, e)
+			}
+		}
+		if len(r) == 0 {
+			delete(d.m, k)
+		} else {
+			d.m[k] = r
+		}
+	}
+}
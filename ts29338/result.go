@@ -1,25 +1,41 @@
 package ts29338
 
-const (
-	vid3gpp uint32 = 10415 * 10000
-	// DiameterErrorUserUnknown is Result-Code 5001
-	DiameterErrorUserUnknown uint32 = vid3gpp + 5001
-	// DiameterErrorAbsentUser is Result-Code 5550
-	DiameterErrorAbsentUser uint32 = vid3gpp + 5550
-	// DiameterErrorUserBusyForMtSms is Result-Code 5551
-	DiameterErrorUserBusyForMtSms uint32 = vid3gpp + 5551
-	// DiameterErrorFacilityNotSupported is Result-Code 5552
-	DiameterErrorFacilityNotSupported uint32 = vid3gpp + 5552
-	// DiameterErrorIlleagalUser is Result-Code 5553
-	DiameterErrorIlleagalUser uint32 = vid3gpp + 5553
-	// DiameterErrorIlleagalEquipment is Result-Code 5554
-	DiameterErrorIlleagalEquipment uint32 = vid3gpp + 5554
-	// DiameterErrorSmDeliveryFailure is Result-Code 5555
-	DiameterErrorSmDeliveryFailure uint32 = vid3gpp + 5555
-	// DiameterErrorServiceNotSubscribed is Result-Code 5556
-	DiameterErrorServiceNotSubscribed uint32 = vid3gpp + 5556
-	// DiameterErrorServiceBarred is Result-Code 5557
-	DiameterErrorServiceBarred uint32 = vid3gpp + 5557
-	// DiameterErrorMwdListFull is Result-Code 5558
-	DiameterErrorMwdListFull uint32 = vid3gpp + 5558
+import dia "github.com/fkgi/diameter"
+
+const vid3gpp uint32 = 10415
+
+var (
+	// DiameterErrorUserUnknown is Experimental-Result-Code 5001
+	DiameterErrorUserUnknown = dia.ExperimentalResult(vid3gpp, 5001)
+	// DiameterErrorAbsentUser is Experimental-Result-Code 5550
+	DiameterErrorAbsentUser = dia.ExperimentalResult(vid3gpp, 5550)
+	// DiameterErrorUserBusyForMtSms is Experimental-Result-Code 5551
+	DiameterErrorUserBusyForMtSms = dia.ExperimentalResult(vid3gpp, 5551)
+	// DiameterErrorFacilityNotSupported is Experimental-Result-Code 5552
+	DiameterErrorFacilityNotSupported = dia.ExperimentalResult(vid3gpp, 5552)
+	// DiameterErrorIlleagalUser is Experimental-Result-Code 5553
+	DiameterErrorIlleagalUser = dia.ExperimentalResult(vid3gpp, 5553)
+	// DiameterErrorIlleagalEquipment is Experimental-Result-Code 5554
+	DiameterErrorIlleagalEquipment = dia.ExperimentalResult(vid3gpp, 5554)
+	// DiameterErrorSmDeliveryFailure is Experimental-Result-Code 5555
+	DiameterErrorSmDeliveryFailure = dia.ExperimentalResult(vid3gpp, 5555)
+	// DiameterErrorServiceNotSubscribed is Experimental-Result-Code 5556
+	DiameterErrorServiceNotSubscribed = dia.ExperimentalResult(vid3gpp, 5556)
+	// DiameterErrorServiceBarred is Experimental-Result-Code 5557
+	DiameterErrorServiceBarred = dia.ExperimentalResult(vid3gpp, 5557)
+	// DiameterErrorMwdListFull is Experimental-Result-Code 5558
+	DiameterErrorMwdListFull = dia.ExperimentalResult(vid3gpp, 5558)
 )
+
+func init() {
+	dia.RegisterResultName(vid3gpp, 5001, "DIAMETER_ERROR_USER_UNKNOWN")
+	dia.RegisterResultName(vid3gpp, 5550, "DIAMETER_ERROR_ABSENT_USER")
+	dia.RegisterResultName(vid3gpp, 5551, "DIAMETER_ERROR_USER_BUSY_FOR_MT_SMS")
+	dia.RegisterResultName(vid3gpp, 5552, "DIAMETER_ERROR_FACILITY_NOT_SUPPORTED")
+	dia.RegisterResultName(vid3gpp, 5553, "DIAMETER_ERROR_ILLEGAL_USER")
+	dia.RegisterResultName(vid3gpp, 5554, "DIAMETER_ERROR_ILLEGAL_EQUIPMENT")
+	dia.RegisterResultName(vid3gpp, 5555, "DIAMETER_ERROR_SM_DELIVERY_FAILURE")
+	dia.RegisterResultName(vid3gpp, 5556, "DIAMETER_ERROR_SERVICE_NOT_SUBSCRIBED")
+	dia.RegisterResultName(vid3gpp, 5557, "DIAMETER_ERROR_SERVICE_BARRED")
+	dia.RegisterResultName(vid3gpp, 5558, "DIAMETER_ERROR_MWD_LIST_FULL")
+}
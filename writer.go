@@ -0,0 +1,168 @@
+package diameter
+
+import (
+	"bytes"
+	"time"
+)
+
+// WriteBatch is maximum bytes that is merged into one write of transport
+var WriteBatch = 65535
+
+// write puts message m to write queue of the Conn
+// with priority of its DRMP.
+// It returns WriteQueueFull if the queue has TxBuffer messages.
+func (c *Conn) write(m RawMsg) error {
+	return c.writeAt(m, priorityOf(m, NoDRMP.Level()))
+}
+
+// writeAt puts message m to write queue with priority level pri.
+// Higher priority message is written first.
+func (c *Conn) writeAt(m RawMsg, pri int) error {
+	if c.wq.push(m, pri) {
+		return nil
+	}
+	if c.wq.isClosed() {
+		return ConnectionRefused{}
+	}
+	if m.FlgR {
+		c.stats.TxReq.Add(1)
+		c.stats.TxReqFail.Add(1)
+	} else {
+		c.countTx(resultOf(m))
+	}
+	return WriteQueueFull{}
+}
+
+// flush waits until messages in write queue are written or timeout d
+func (c *Conn) flush(d time.Duration) {
+	ch := make(chan struct{})
+	if !c.wq.mark(ch) {
+		return
+	}
+	to := make(chan struct{})
+	t := c.clock.AfterFunc(d, func() { close(to) })
+	defer t.Stop()
+	select {
+	case <-ch:
+	case <-c.wdone:
+	case <-to:
+	}
+}
+
+// writeHandler writes messages in write queue to transport
+// in order of priority. Queued messages are merged into one write
+// up to WriteBatch bytes, message that is larger than WriteBatch
+// is written alone. It stops when the queue is closed.
+func writeHandler(c *Conn) {
+	defer close(c.wdone)
+	buf := new(bytes.Buffer)
+	enc := new(bytes.Buffer)
+	var l []RawMsg
+	var done []chan struct{}
+	var next *queuedMsg // message for next write
+	for {
+		r := next
+		if r == nil {
+			v, ok := c.wq.take(true)
+			if !ok {
+				return
+			}
+			r = &v
+		}
+		next = nil
+		for r != nil {
+			if r.done != nil {
+				done = append(done, r.done)
+			} else {
+				enc.Reset()
+				r.m.WriteTo(enc)
+				if len(l) != 0 && buf.Len()+enc.Len() > WriteBatch {
+					next = r
+					break
+				}
+				buf.Write(enc.Bytes())
+				l = append(l, r.m)
+			}
+			r = nil
+			if v, ok := c.wq.take(false); ok {
+				r = &v
+			}
+		}
+
+		var e error
+		if buf.Len() != 0 {
+			c.con.SetWriteDeadline(time.Now().Add(TransportTimeout))
+			_, e = c.con.Write(buf.Bytes())
+		}
+		for _, m := range l {
+			c.written(m, e)
+		}
+		for _, ch := range done {
+			close(ch)
+		}
+		buf.Reset()
+		l, done = l[:0], done[:0]
+
+		if e != nil {
+			c.con.Close()
+			return
+		}
+	}
+}
+
+// written counts message m that is written with error e
+func (c *Conn) written(m RawMsg, e error) {
+	if m.FlgR {
+		c.stats.TxReq.Add(1)
+	} else {
+		c.countTx(resultOf(m))
+	}
+	if e != nil && m.FlgR {
+		c.stats.TxReqFail.Add(1)
+	} else if e == nil {
+		countMsg(c, m, true)
+		if cp := c.capture.Load(); cp != nil {
+			cp.capture(c, Tx, m)
+		}
+	}
+}
+
+// pendingAns is answer of pending request, or error if
+// the request is failed without answer
+type pendingAns struct {
+	m RawMsg
+	e error
+}
+
+// addPending registers channel of answer for request with hbh.
+// It returns false if the Conn is already closed.
+func (c *Conn) addPending(hbh uint32, ch chan pendingAns) bool {
+	c.sndmu.Lock()
+	defer c.sndmu.Unlock()
+	if c.sndstack == nil {
+		return false
+	}
+	c.sndstack[hbh] = ch
+	return true
+}
+
+// takePending removes and returns channel of answer for request with hbh
+func (c *Conn) takePending(hbh uint32) (chan pendingAns, bool) {
+	c.sndmu.Lock()
+	defer c.sndmu.Unlock()
+	ch, ok := c.sndstack[hbh]
+	if ok {
+		delete(c.sndstack, hbh)
+	}
+	return ch, ok
+}
+
+// takeAllPending removes all channels of answer, and following
+// addPending fails
+func (c *Conn) takeAllPending() map[uint32]chan pendingAns {
+	c.sndmu.Lock()
+	defer c.sndmu.Unlock()
+	m := c.sndstack
+	c.sndstack = nil
+	return m
+}